@@ -0,0 +1,95 @@
+/*
+Package autotls provides automatic HTTPS for velocity apps using certificates obtained
+and renewed from Let's Encrypt via golang.org/x/crypto/acme/autocert.
+
+It lives in its own package so applications that do not use it don't pull in the
+autocert dependency.
+
+Challenge Requirements:
+  - Every domain must resolve to the host running the app
+  - Port 443 must be reachable for TLS-ALPN-01 challenges and serving HTTPS
+  - Port 80 must be reachable for HTTP-01 challenges; other HTTP requests are redirected to HTTPS
+  - The cache directory must be writable and persist across restarts, otherwise
+    certificates are requested again on every start and may hit Let's Encrypt rate limits
+
+Usage:
+
+	app := velocity.New()
+	router := app.Router("/")
+	// ... register routes
+
+	log.Fatal(autotls.Listen(app, []string{"example.com", "www.example.com"}, "/var/cache/certs"))
+*/
+package autotls
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+
+	"github.com/Juanfec4/velocity"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Manager returns an autocert.Manager that accepts the Let's Encrypt terms of service,
+// only issues certificates for domains, and caches them in cacheDir.
+// An empty cacheDir disables caching.
+func Manager(domains []string, cacheDir string) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+	}
+	if cacheDir != "" {
+		m.Cache = autocert.DirCache(cacheDir)
+	}
+	return m
+}
+
+// ServerConfig returns a velocity.ServerConfig whose TLSConfig obtains certificates from m.
+// Fields of an optional base config are preserved; its TLSConfig, if any, is cloned and
+// its GetCertificate replaced.
+func ServerConfig(m *autocert.Manager, cfg ...velocity.ServerConfig) velocity.ServerConfig {
+	config := velocity.ServerConfig{}
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+
+	tlsConfig := m.TLSConfig()
+	if config.TLSConfig != nil {
+		tlsConfig = config.TLSConfig.Clone()
+		tlsConfig.GetCertificate = m.GetCertificate
+		if !slices.Contains(tlsConfig.NextProtos, acme.ALPNProto) {
+			tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+		}
+	} else {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	config.TLSConfig = tlsConfig
+	config.CertFile = ""
+	config.KeyFile = ""
+	return config
+}
+
+// Listen serves app over HTTPS on port 443 using certificates for domains, and runs
+// an HTTP server on port 80 answering ACME challenges and redirecting everything else
+// to HTTPS. It blocks until the HTTPS server stops.
+func Listen(app *velocity.App, domains []string, cacheDir string, cfg ...velocity.ServerConfig) error {
+	return ListenWithManager(app, Manager(domains, cacheDir), cfg...)
+}
+
+// ListenWithManager is like Listen but uses a preconfigured autocert.Manager.
+func ListenWithManager(app *velocity.App, m *autocert.Manager, cfg ...velocity.ServerConfig) error {
+	challenge := &http.Server{
+		Addr:    ":80",
+		Handler: m.HTTPHandler(nil),
+	}
+	go func() {
+		if err := challenge.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			app.Logger().Error("autotls: challenge server stopped", "err", err)
+		}
+	}()
+	defer challenge.Close()
+
+	return app.Listen(443, ServerConfig(m, cfg...))
+}
@@ -0,0 +1,75 @@
+package autotls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/autotls"
+	"golang.org/x/crypto/acme"
+)
+
+func TestManagerHostPolicy(t *testing.T) {
+	m := autotls.Manager([]string{"example.com"}, t.TempDir())
+
+	if err := m.HostPolicy(context.Background(), "example.com"); err != nil {
+		t.Errorf("expected example.com to be allowed, got %v", err)
+	}
+	if err := m.HostPolicy(context.Background(), "evil.com"); err == nil {
+		t.Error("expected evil.com to be rejected")
+	}
+	if m.Cache == nil {
+		t.Error("expected certificate cache to be configured")
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	m := autotls.Manager([]string{"example.com"}, "")
+
+	cfg := autotls.ServerConfig(m, velocity.ServerConfig{
+		ReadTimeout: 5 * time.Second,
+		TLSConfig:   &tls.Config{MinVersion: tls.VersionTLS13},
+	})
+
+	if cfg.ReadTimeout != 5*time.Second {
+		t.Errorf("expected ReadTimeout to be preserved, got %v", cfg.ReadTimeout)
+	}
+	if cfg.TLSConfig == nil || cfg.TLSConfig.GetCertificate == nil {
+		t.Fatal("expected TLSConfig.GetCertificate to be wired to the manager")
+	}
+	if cfg.TLSConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected MinVersion to be preserved, got %x", cfg.TLSConfig.MinVersion)
+	}
+	if !slices.Contains(cfg.TLSConfig.NextProtos, acme.ALPNProto) {
+		t.Errorf("expected NextProtos to include %q, got %v", acme.ALPNProto, cfg.TLSConfig.NextProtos)
+	}
+}
+
+func TestChallengeHandler(t *testing.T) {
+	m := autotls.Manager([]string{"example.com"}, "")
+	handler := m.HTTPHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users?page=2", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Errorf("expected status %d, got %d", http.StatusFound, rec.Code)
+	}
+	if got, exp := rec.Header().Get("Location"), "https://example.com/users?page=2"; got != exp {
+		t.Errorf("expected location %q, got %q", exp, got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/acme-challenge/unknown", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown challenge token, got %d", http.StatusNotFound, rec.Code)
+	}
+}
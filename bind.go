@@ -0,0 +1,278 @@
+package velocity
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindConfig configures Bind.
+type BindConfig struct {
+	// MaxBodySize is the maximum request body size in bytes. Larger bodies fail with
+	// a BindError with status 413. A negative value disables the limit.
+	// Default: 1 MB
+	MaxBodySize int64
+
+	// DisallowUnknownFields makes JSON bodies with fields that do not match the
+	// destination struct fail with a BindError.
+	// Default: false
+	DisallowUnknownFields bool
+}
+
+// BindError is returned by Bind and BindQuery when the request cannot be bound.
+// Status is the response status suited to the failure: 400 Bad Request for
+// malformed input, 413 Request Entity Too Large or 415 Unsupported Media Type.
+type BindError struct {
+	Status int
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	return "velocity: " + e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+const defaultMaxBodySize = 1 << 20
+
+// Bind decodes the request body into v, choosing the decoder by Content-Type:
+// JSON for application/json and +json types, XML for application/xml, text/xml
+// and +xml types, and form fields matched by `form` tags, like BindQuery, for
+// application/x-www-form-urlencoded and multipart/form-data. Requests without a
+// Content-Type are decoded as JSON. Failures are reported as *BindError.
+//
+// Example:
+//
+//	router.Post("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    var dto CreateUser
+//	    if err := velocity.Bind(r, &dto, velocity.BindConfig{DisallowUnknownFields: true}); err != nil {
+//	        var be *velocity.BindError
+//	        errors.As(err, &be)
+//	        http.Error(w, err.Error(), be.Status)
+//	        return
+//	    }
+//	})
+func Bind(r *http.Request, v any, cfg ...BindConfig) error {
+	config := BindConfig{}
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return &BindError{Status: http.StatusUnsupportedMediaType, Err: fmt.Errorf("invalid content type %q", ct)}
+		}
+		mediaType = mt
+	}
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	if config.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, config.MaxBodySize)
+	}
+
+	var err error
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		dec := json.NewDecoder(r.Body)
+		if config.DisallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		err = dec.Decode(v)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		err = xml.NewDecoder(r.Body).Decode(v)
+	case mediaType == "application/x-www-form-urlencoded":
+		if err = r.ParseForm(); err == nil {
+			return bindValues(r.PostForm, "form", v)
+		}
+	case mediaType == "multipart/form-data":
+		if err = r.ParseMultipartForm(32 << 20); err == nil {
+			return bindValues(r.MultipartForm.Value, "form", v)
+		}
+	default:
+		return &BindError{Status: http.StatusUnsupportedMediaType, Err: fmt.Errorf("unsupported content type %q", mediaType)}
+	}
+	if err == nil {
+		return nil
+	}
+
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return &BindError{Status: http.StatusRequestEntityTooLarge, Err: fmt.Errorf("request body exceeds %d bytes", maxErr.Limit)}
+	case errors.Is(err, io.EOF):
+		return &BindError{Status: http.StatusBadRequest, Err: errors.New("empty request body")}
+	}
+	return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("decoding request body: %w", err)}
+}
+
+// BindQuery maps the request's query parameters onto the fields of the struct v
+// points to. Fields are matched by their `query` tag, and a `default` tag supplies
+// the value of a missing parameter. Untagged fields and fields tagged "-" are left
+// alone; embedded structs are bound recursively.
+//
+// Supported field types are string, bool, all int, uint and float types,
+// time.Duration, time.Time (RFC 3339), pointers to these and slices of these, which
+// take every value of a repeated parameter, e.g. "?tag=a&tag=b". Conversion failures
+// are reported as *BindError with status 400.
+//
+// Example:
+//
+//	type Filters struct {
+//	    Page   int      `query:"page" default:"1"`
+//	    Limit  int      `query:"limit" default:"20"`
+//	    Status string   `query:"status"`
+//	    Tags   []string `query:"tag"`
+//	}
+//
+//	router.Get("/orders").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    var f Filters
+//	    if err := velocity.BindQuery(r, &f); err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	})
+func BindQuery(r *http.Request, v any) error {
+	return bindValues(r.URL.Query(), "query", v)
+}
+
+// bindValues maps values onto the fields of the struct v points to using tag.
+func bindValues(values url.Values, tag string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("velocity: bind target must be a non-nil pointer to a struct")
+	}
+	if err := bindStruct(values, tag, rv.Elem()); err != nil {
+		return &BindError{Status: http.StatusBadRequest, Err: err}
+	}
+	return nil
+}
+
+func bindStruct(values url.Values, tag string, sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		fv := sv.Field(i)
+		name, ok := sf.Tag.Lookup(tag)
+		if !ok && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := bindStruct(values, tag, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok || name == "-" || !sf.IsExported() {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ",")
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			def, ok := sf.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			vals = []string{def}
+			if fv.Kind() == reflect.Slice {
+				vals = strings.Split(def, ",")
+			}
+		}
+		if err := setField(fv, vals); err != nil {
+			return fmt.Errorf("%s %q: %w", tag, name, err)
+		}
+	}
+	return nil
+}
+
+// setField converts vals to the type of fv and stores the result in fv.
+func setField(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(s.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setValue(fv, vals[0])
+}
+
+// setValue converts s to the type of v and stores the result in v.
+func setValue(v reflect.Value, s string) error {
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to duration", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to time", s)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to bool", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to %s", s, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to %s", s, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot convert %q to %s", s, v.Type())
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package velocity_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestBindQuery(t *testing.T) {
+	type Page struct {
+		Page  int `query:"page" default:"1"`
+		Limit int `query:"limit" default:"20"`
+	}
+	type filters struct {
+		Page
+		Status  string        `query:"status"`
+		Tags    []string      `query:"tag"`
+		Active  *bool         `query:"active"`
+		MinCost float64       `query:"min_cost"`
+		Since   time.Time     `query:"since"`
+		Window  time.Duration `query:"window" default:"1h"`
+		Ignored string
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		expected    filters
+		expectedErr string
+	}{
+		{
+			name:     "defaults",
+			query:    "",
+			expected: filters{Page: Page{Page: 1, Limit: 20}, Window: time.Hour},
+		},
+		{
+			name:  "all fields",
+			query: "page=3&limit=50&status=open&tag=a&tag=b&active=true&min_cost=9.5&since=2024-05-01T00:00:00Z&window=30m&Ignored=x",
+			expected: filters{
+				Page:    Page{Page: 3, Limit: 50},
+				Status:  "open",
+				Tags:    []string{"a", "b"},
+				Active:  func() *bool { b := true; return &b }(),
+				MinCost: 9.5,
+				Since:   time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+				Window:  30 * time.Minute,
+			},
+		},
+		{
+			name:        "invalid int",
+			query:       "page=two",
+			expectedErr: `query "page": cannot convert "two" to int`,
+		},
+		{
+			name:        "invalid bool",
+			query:       "active=maybe",
+			expectedErr: `query "active": cannot convert "maybe" to bool`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil)
+			var got filters
+			err := velocity.BindQuery(req, &got)
+
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBindQueryInvalidTarget(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/orders?page=1", nil)
+	var page int
+	if err := velocity.BindQuery(req, &page); err == nil {
+		t.Error("expected an error binding into a non-struct")
+	}
+}
+
+func TestBind(t *testing.T) {
+	type user struct {
+		XMLName xml.Name `json:"-" xml:"user"`
+		Name    string   `json:"name" xml:"name" form:"name"`
+		Age     int      `json:"age" xml:"age" form:"age"`
+	}
+
+	multipartBody := func() (string, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("name", "ada")
+		mw.WriteField("age", "36")
+		mw.Close()
+		return buf.String(), mw.FormDataContentType()
+	}
+	mpBody, mpType := multipartBody()
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		cfg            velocity.BindConfig
+		expected       user
+		expectedStatus int
+	}{
+		{name: "json", contentType: "application/json", body: `{"name":"ada","age":36}`, expected: user{Name: "ada", Age: 36}},
+		{name: "json suffix", contentType: "application/vnd.api+json; charset=utf-8", body: `{"name":"ada"}`, expected: user{Name: "ada"}},
+		{name: "no content type", body: `{"name":"ada"}`, expected: user{Name: "ada"}},
+		{name: "xml", contentType: "application/xml", body: `<user><name>ada</name><age>36</age></user>`, expected: user{Name: "ada", Age: 36}},
+		{name: "form", contentType: "application/x-www-form-urlencoded", body: "name=ada&age=36", expected: user{Name: "ada", Age: 36}},
+		{name: "multipart", contentType: mpType, body: mpBody, expected: user{Name: "ada", Age: 36}},
+		{name: "malformed json", contentType: "application/json", body: `{"name":`, expectedStatus: http.StatusBadRequest},
+		{name: "empty body", contentType: "application/json", body: "", expectedStatus: http.StatusBadRequest},
+		{name: "invalid form value", contentType: "application/x-www-form-urlencoded", body: "age=old", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "unknown field",
+			contentType:    "application/json",
+			body:           `{"name":"ada","admin":true}`,
+			cfg:            velocity.BindConfig{DisallowUnknownFields: true},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "body too large",
+			contentType:    "application/json",
+			body:           `{"name":"` + strings.Repeat("a", 64) + `"}`,
+			cfg:            velocity.BindConfig{MaxBodySize: 16},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{name: "unsupported type", contentType: "text/csv", body: "ada,36", expectedStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			var got user
+			err := velocity.Bind(req, &got, tt.cfg)
+
+			if tt.expectedStatus != 0 {
+				var be *velocity.BindError
+				if !errors.As(err, &be) {
+					t.Fatalf("expected *velocity.BindError, got %v", err)
+				}
+				if be.Status != tt.expectedStatus {
+					t.Errorf("expected status %d, got %d (%v)", tt.expectedStatus, be.Status, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got.XMLName = xml.Name{}
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
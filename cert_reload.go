@@ -0,0 +1,93 @@
+package velocity
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes.
+const certCheckInterval = time.Second
+
+// certReloader serves the certificate in certFile and keyFile, reloading it when
+// either file changes on disk, e.g. after rotation by cert-manager or certbot.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	onError  func(error)
+
+	cert    atomic.Pointer[tls.Certificate]
+	checked atomic.Int64 // Unix nanoseconds of the last check
+
+	mu      sync.Mutex // serializes reloads
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// newCertReloader loads the initial certificate, failing if it cannot be read.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := cr.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	cr.checked.Store(time.Now().UnixNano())
+	return cr, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. It serves the cached
+// certificate; at most once per certCheckInterval, one handshake re-stats the files
+// and reloads the certificate if they changed. A failed reload, e.g. while the files
+// are being replaced, is passed to onError and keeps serving the previous
+// certificate.
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	now := time.Now().UnixNano()
+	last := cr.checked.Load()
+	if now-last >= int64(certCheckInterval) && cr.checked.CompareAndSwap(last, now) {
+		cr.check()
+	}
+	return cr.cert.Load(), nil
+}
+
+// check reloads the certificate if the files changed since it was loaded.
+func (cr *certReloader) check() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil || (certMod.Equal(cr.certMod) && keyMod.Equal(cr.keyMod)) {
+		return
+	}
+	if err := cr.load(certMod, keyMod); err != nil && cr.onError != nil {
+		cr.onError(err)
+	}
+}
+
+// load reads the key pair and records the modification times it was read at.
+func (cr *certReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+	cr.cert.Store(&cert)
+	cr.certMod = certMod
+	cr.keyMod = keyMod
+	return nil
+}
+
+func (cr *certReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	fi, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	ki, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return fi.ModTime(), ki.ModTime(), nil
+}
@@ -0,0 +1,102 @@
+package velocity
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Ctx wraps the response writer and request of a handler registered with HandleCtx
+// and provides helpers for the common parts of API handlers. A Ctx is only valid
+// until the handler returns and must not be retained, e.g. by goroutines.
+type Ctx struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	status int
+	query  url.Values
+}
+
+var ctxPool = sync.Pool{New: func() any { return new(Ctx) }}
+
+// HandleCtx registers a handler using the Ctx signature. Returned errors are passed
+// to the nearest error handler, like with HandleE.
+//
+// Example:
+//
+//	router.Post("/users/:org").HandleCtx(func(c *velocity.Ctx) error {
+//	    var user User
+//	    if err := c.BodyParser(&user); err != nil {
+//	        return err
+//	    }
+//	    user.Org = c.Param("org")
+//	    return c.Status(http.StatusCreated).JSON(user)
+//	})
+func (r route) HandleCtx(h func(c *Ctx) error) error {
+	return r.HandleE(func(w http.ResponseWriter, req *http.Request) error {
+		c := ctxPool.Get().(*Ctx)
+		c.w, c.r = w, req
+		err := h(c)
+		*c = Ctx{}
+		ctxPool.Put(c)
+		return err
+	})
+}
+
+// Request returns the underlying request.
+func (c *Ctx) Request() *http.Request {
+	return c.r
+}
+
+// Writer returns the underlying response writer.
+func (c *Ctx) Writer() http.ResponseWriter {
+	return c.w
+}
+
+// Context returns the request context.
+func (c *Ctx) Context() context.Context {
+	return c.r.Context()
+}
+
+// Param returns the URL parameter key, or an empty string if it is not set.
+func (c *Ctx) Param(key string) string {
+	return Param(c.r, key)
+}
+
+// Query returns the first value of the query parameter key, or an empty string if
+// it is not set. The query string is parsed once per request.
+func (c *Ctx) Query(key string) string {
+	if c.query == nil {
+		c.query = c.r.URL.Query()
+	}
+	return c.query.Get(key)
+}
+
+// BindQuery maps the query parameters onto the struct v points to, see BindQuery.
+func (c *Ctx) BindQuery(v any) error {
+	return BindQuery(c.r, v)
+}
+
+// Status sets the status code used by the next response written through c, e.g.
+// with JSON. It defaults to 200 OK.
+func (c *Ctx) Status(code int) *Ctx {
+	c.status = code
+	return c
+}
+
+// JSON writes v as a JSON response with the status set by Status.
+func (c *Ctx) JSON(v any) error {
+	return JSON(c.w, c.statusCode(), v)
+}
+
+// BodyParser decodes the request body into v based on its Content-Type, see Bind.
+func (c *Ctx) BodyParser(v any, cfg ...BindConfig) error {
+	return Bind(c.r, v, cfg...)
+}
+
+func (c *Ctx) statusCode() int {
+	if c.status == 0 {
+		return http.StatusOK
+	}
+	return c.status
+}
@@ -0,0 +1,64 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestHandleCtx(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Org  string `json:"org"`
+		Role string `json:"role"`
+	}
+
+	app := velocity.New()
+	app.Router("/").Post("/orgs/:org/users").HandleCtx(func(c *velocity.Ctx) error {
+		var u user
+		if err := c.BodyParser(&u); err != nil {
+			return err
+		}
+		u.Org = c.Param("org")
+		u.Role = c.Query("role")
+		return c.Status(http.StatusCreated).JSON(u)
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid body",
+			body:           `{"name":"ada"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"name":"ada","org":"acme","role":"admin"}` + "\n",
+		},
+		{
+			name:           "invalid body goes to the error handler",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "velocity: decoding request body: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users?role=admin", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+		})
+	}
+}
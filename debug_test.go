@@ -0,0 +1,77 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestDebug(t *testing.T) {
+	app := velocity.New()
+	guard := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Debug") != "1" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+	if err := app.Router("/admin").Debug("/debug", guard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		header           bool
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{"guarded", "/admin/debug/vars", false, http.StatusForbidden, "", ""},
+		{"expvar", "/admin/debug/vars", true, http.StatusOK, "", `"memstats"`},
+		{"index redirect", "/admin/debug/pprof", true, http.StatusMovedPermanently, "/admin/debug/pprof/", ""},
+		{"index", "/admin/debug/pprof/", true, http.StatusOK, "", "heap?debug=1"},
+		{"named profile", "/admin/debug/pprof/goroutine?debug=1", true, http.StatusOK, "", "goroutine profile"},
+		{"cmdline", "/admin/debug/pprof/cmdline", true, http.StatusOK, "", ""},
+		{"unknown profile", "/admin/debug/pprof/missing", true, http.StatusNotFound, "", "Unknown profile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header {
+				req.Header.Set("X-Debug", "1")
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.expectedLocation {
+				t.Errorf("expected Location %q, got %q", tt.expectedLocation, loc)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDebugErrors(t *testing.T) {
+	app := velocity.New()
+	if err := app.Router("/").Debug("/:1bad"); err == nil {
+		t.Error("expected error for invalid prefix")
+	}
+
+	strict := velocity.New(velocity.AppConfig{StrictRoutes: true})
+	strict.Router("/").Get("/debug/vars").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	if err := strict.Router("/").Debug("/debug"); err == nil {
+		t.Error("expected error for endpoint conflicting with an existing route")
+	}
+}
@@ -0,0 +1,70 @@
+package velocity
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error that carries the status code and message to respond with.
+// Handlers registered with HandleE or HandleCtx can return it to have the default
+// error handler answer with Code and Message instead of 500 Internal Server Error.
+// A zero Code is treated as 500.
+//
+// Example:
+//
+//	router.Get("/users/:id").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    user, ok := users[velocity.Param(r, "id")]
+//	    if !ok {
+//	        return &velocity.HTTPError{Code: http.StatusNotFound, Message: "user not found"}
+//	    }
+//	    return velocity.JSON(w, http.StatusOK, user)
+//	})
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+// Error returns the message, or the status text of Code if the message is empty.
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.Code)
+	}
+	return e.Message
+}
+
+// errorStatus returns the status code and client-facing message for err. Errors
+// other than HTTPError, BindError and Problem are internal and their details are
+// not exposed.
+func errorStatus(err error) (int, string) {
+	var p *Problem
+	if errors.As(err, &p) && p.Status != 0 {
+		return p.Status, p.Error()
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		code := he.Code
+		if code == 0 {
+			code = http.StatusInternalServerError
+		}
+		msg := he.Message
+		if msg == "" {
+			msg = http.StatusText(code)
+		}
+		return code, msg
+	}
+	var be *BindError
+	if errors.As(err, &be) {
+		return be.Status, be.Error()
+	}
+	return http.StatusInternalServerError, "Internal server error"
+}
+
+// logErrors wraps h to log errors that result in a 5xx response with the app logger.
+func (a *App) logErrors(h ErrorHandler) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if status, _ := errorStatus(err); status >= http.StatusInternalServerError {
+			a.Logger().Error("handler error", "method", r.Method, "path", r.URL.Path, "err", err)
+		}
+		h(w, r, err)
+	}
+}
@@ -0,0 +1,64 @@
+package velocity_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestHTTPError(t *testing.T) {
+	var logs bytes.Buffer
+	app := velocity.New(velocity.AppConfig{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	router := app.Router("/")
+	router.Get("/missing").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Code: http.StatusNotFound, Message: "user not found"}
+	})
+	router.Get("/wrapped").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("loading user: %w", &velocity.HTTPError{Code: http.StatusForbidden})
+	})
+	router.Get("/no-code").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Message: "not configured"}
+	})
+	router.Get("/internal").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database unavailable")
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedBody   string
+		expectedLog    bool
+	}{
+		{"/missing", http.StatusNotFound, "user not found", false},
+		{"/wrapped", http.StatusForbidden, "Forbidden", false},
+		{"/no-code", http.StatusInternalServerError, "not configured", true},
+		{"/internal", http.StatusInternalServerError, "Internal server error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logs.Reset()
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+			if logged := strings.Contains(logs.String(), "handler error"); logged != tt.expectedLog {
+				t.Errorf("expected logged %v, got %v: %q", tt.expectedLog, logged, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package velocity
+
+import "net/http"
+
+// WrapHandler adapts an http.Handler, such as promhttp.Handler(), an http.ServeMux
+// or a generated gRPC-gateway mux, for route.Handle.
+//
+// Example:
+//
+//	router.Get("/metrics").Handle(velocity.WrapHandler(promhttp.Handler()))
+func WrapHandler(h http.Handler) http.HandlerFunc {
+	if fn, ok := h.(http.HandlerFunc); ok {
+		return fn
+	}
+	return h.ServeHTTP
+}
+
+// HandlerE adapts a handler function that may return an error for route.Handle and
+// anywhere else an http.HandlerFunc is expected, such as in middleware. Returned
+// errors are passed to the error handler of the app serving the request: the nearest
+// one set with Router.OnError once a route matched, like with route.HandleE, and
+// App.ErrorHandler otherwise. Outside an app, errors are answered like the default
+// error handler does.
+//
+// Example:
+//
+//	router.Get("/users/:id").Handle(velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+//	    user, err := findUser(velocity.Param(r, "id"))
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return velocity.JSON(w, http.StatusOK, user)
+//	}))
+func HandlerE(h func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			requestErrorHandler(r)(w, r, err)
+		}
+	}
+}
+
+// requestErrorHandler returns the error handler for r, see HandlerE.
+func requestErrorHandler(r *http.Request) ErrorHandler {
+	st := requestState(r)
+	switch {
+	case st == nil:
+		return internalError
+	case st.router != nil:
+		return st.router.errorHandler()
+	default:
+		return st.app.onError
+	}
+}
@@ -0,0 +1,152 @@
+package velocity_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestWrapHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1"))
+	})
+
+	app := velocity.New()
+	app.Router("/").Get("/metrics").Handle(velocity.WrapHandler(mux))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "up 1" {
+		t.Errorf("expected 200 %q, got %d %q", "up 1", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerE(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	var handled []error
+	router.OnError(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = append(handled, err)
+		http.Error(w, err.Error(), http.StatusTeapot)
+	})
+	boom := errors.New("boom")
+	router.Get("/fail/:id").Handle(velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return boom
+	}))
+	router.Get("/ok").Handle(velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("ok"))
+		return nil
+	}))
+	router.Get("/status").Handle(velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Code: http.StatusNotFound, Message: "missing"}
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedErrs   int
+	}{
+		{"error", "/fail/1", http.StatusTeapot, 1},
+		{"no error", "/ok", http.StatusOK, 0},
+		{"typed error", "/status", http.StatusTeapot, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled = nil
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if len(handled) != tt.expectedErrs {
+				t.Errorf("expected %d handled errors, got %v", tt.expectedErrs, handled)
+			}
+		})
+	}
+}
+
+func TestHandlerEDefaultErrorHandler(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/missing").Handle(velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Code: http.StatusNotFound, Message: "user not found"}
+	}))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandlerEOutsideRouteHandle(t *testing.T) {
+	app := velocity.New()
+	var handled []error
+	app.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = append(handled, err)
+		http.Error(w, err.Error(), http.StatusTeapot)
+	})
+	denied := errors.New("denied")
+	guard := func(next http.HandlerFunc) http.HandlerFunc {
+		return velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+			if r.Header.Get("X-Deny") != "" {
+				return denied
+			}
+			next(w, r)
+			return nil
+		})
+	}
+	app.Use(guard)
+	app.Router("/").Get("/ok").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	app.AddRoute(http.MethodGet, "/plugin", velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("plugin failed")
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		deny           bool
+		expectedStatus int
+	}{
+		{"middleware", "/ok", true, http.StatusTeapot},
+		{"runtime route", "/plugin", false, http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled = nil
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.deny {
+				req.Header.Set("X-Deny", "1")
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if len(handled) != 1 {
+				t.Errorf("expected 1 handled error, got %v", handled)
+			}
+		})
+	}
+
+	// Outside an app, errors are answered like the default error handler does
+	rec := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	mux.Handle("/", velocity.HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Code: http.StatusConflict, Message: "taken"}
+	}))
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package velocity
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headWriter discards the response body of HEAD requests (RFC 9110, section 9.3.2)
+// while keeping the headers the corresponding GET response would have sent. The
+// status line is deferred until the handler returns, so a Content-Length matching
+// the discarded body can still be set.
+type headWriter struct {
+	http.ResponseWriter
+	status    int
+	size      int
+	committed bool
+}
+
+func (hw *headWriter) WriteHeader(code int) {
+	if hw.status == 0 {
+		hw.status = code
+	}
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	if hw.status == 0 {
+		hw.status = http.StatusOK
+	}
+	hw.size += len(b)
+	return len(b), nil
+}
+
+// Flush sends the headers immediately; the discarded body is never flushed.
+func (hw *headWriter) Flush() {
+	hw.commit()
+	if f, ok := hw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (hw *headWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+func (hw *headWriter) commit() {
+	if hw.committed {
+		return
+	}
+	hw.committed = true
+	if hw.status == 0 {
+		hw.status = http.StatusOK
+	}
+	h := hw.ResponseWriter.Header()
+	if hw.size > 0 && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.Itoa(hw.size))
+	}
+	hw.ResponseWriter.WriteHeader(hw.status)
+}
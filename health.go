@@ -0,0 +1,261 @@
+package velocity
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck reports whether a dependency is healthy, returning an error if not.
+// It should return once ctx is done.
+type HealthCheck func(ctx context.Context) error
+
+// HealthConfig configures the endpoints registered by App.Health.
+type HealthConfig struct {
+	// LivenessPath is the path of the liveness endpoint.
+	// Default: "/healthz"
+	LivenessPath string
+
+	// ReadinessPath is the path of the readiness endpoint.
+	// Default: "/readyz"
+	ReadinessPath string
+
+	// Timeout bounds each check; a check still running when it expires fails.
+	// Default: 5s
+	Timeout time.Duration
+
+	// CacheTTL is how long results are reused before the checks run again, so
+	// frequent probes do not overload dependencies. A negative value disables caching.
+	// Default: 1s
+	CacheTTL time.Duration
+
+	// DrainDelay is how long Shutdown keeps serving requests after readiness started
+	// failing, giving load balancers time to stop routing traffic to the app.
+	// Default: 0
+	DrainDelay time.Duration
+}
+
+// HealthReport is the JSON body of the health endpoints. Status is "ok", "error"
+// if a check failed, or "draining" once the app is shutting down.
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the outcome of a single check in a HealthReport.
+type HealthCheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type health struct {
+	mu         sync.Mutex
+	cfg        HealthConfig
+	liveness   map[string]HealthCheck
+	readiness  map[string]HealthCheck
+	cache      map[string]cachedReport
+	draining   atomic.Bool
+	drainDelay atomic.Int64
+}
+
+type cachedReport struct {
+	report HealthReport
+	at     time.Time
+}
+
+var defaultHealthConfig = HealthConfig{
+	LivenessPath:  "/healthz",
+	ReadinessPath: "/readyz",
+	Timeout:       5 * time.Second,
+	CacheTTL:      time.Second,
+	DrainDelay:    0,
+}
+
+// Health registers GET liveness and readiness endpoints, "/healthz" and "/readyz" by
+// default. They run the checks added with AddLivenessCheck and AddReadinessCheck
+// concurrently and answer with a JSON HealthReport: 200 OK when all checks pass and
+// 503 Service Unavailable otherwise. Once Shutdown starts, readiness fails with the
+// status "draining" while in-flight requests finish. Health must be called before
+// the app starts serving.
+//
+// Example:
+//
+//	app.Health(velocity.HealthConfig{Timeout: 2 * time.Second, DrainDelay: 5 * time.Second})
+//	app.AddReadinessCheck("db", db.PingContext)
+//	app.AddReadinessCheck("cache", func(ctx context.Context) error {
+//	    return rdb.Ping(ctx).Err()
+//	})
+func (a *App) Health(cfg ...HealthConfig) error {
+	config := defaultHealthConfig
+	if len(cfg) > 0 {
+		if cfg[0].LivenessPath != "" {
+			config.LivenessPath = cfg[0].LivenessPath
+		}
+		if cfg[0].ReadinessPath != "" {
+			config.ReadinessPath = cfg[0].ReadinessPath
+		}
+		if cfg[0].Timeout > 0 {
+			config.Timeout = cfg[0].Timeout
+		}
+		if cfg[0].CacheTTL != 0 {
+			config.CacheTTL = cfg[0].CacheTTL
+		}
+		config.DrainDelay = cfg[0].DrainDelay
+	}
+	h := &a.health
+	h.mu.Lock()
+	h.cfg = config
+	h.mu.Unlock()
+	h.drainDelay.Store(int64(config.DrainDelay))
+
+	// Not added to the app's routers, so they do not affect fallback handling
+	r := &Router{path: "/", app: a}
+	if err := r.Get(config.LivenessPath).Handle(func(w http.ResponseWriter, req *http.Request) {
+		h.serve(w, req, config.LivenessPath, false)
+	}); err != nil {
+		return err
+	}
+	return r.Get(config.ReadinessPath).Handle(func(w http.ResponseWriter, req *http.Request) {
+		h.serve(w, req, config.ReadinessPath, true)
+	})
+}
+
+// AddLivenessCheck registers a named check run by the liveness endpoint. Liveness
+// checks should only fail when the process cannot recover without a restart.
+//
+// Example:
+//
+//	app.AddLivenessCheck("deadlock", func(ctx context.Context) error {
+//	    return workerPool.Heartbeat(ctx)
+//	})
+func (a *App) AddLivenessCheck(name string, check HealthCheck) {
+	a.health.add(&a.health.liveness, name, check)
+}
+
+// AddReadinessCheck registers a named check run by the readiness endpoint, e.g. for a
+// database the app cannot serve requests without.
+//
+// Example:
+//
+//	app.AddReadinessCheck("db", db.PingContext)
+func (a *App) AddReadinessCheck(name string, check HealthCheck) {
+	a.health.add(&a.health.readiness, name, check)
+}
+
+func (h *health) add(checks *map[string]HealthCheck, name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if *checks == nil {
+		*checks = make(map[string]HealthCheck)
+	}
+	(*checks)[name] = check
+	// Results computed without the new check are stale
+	clear(h.cache)
+}
+
+// drain makes readiness fail and waits for the configured drain delay or ctx.
+func (h *health) drain(ctx context.Context) {
+	h.draining.Store(true)
+	d := time.Duration(h.drainDelay.Load())
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func (h *health) serve(w http.ResponseWriter, r *http.Request, key string, readiness bool) {
+	w.Header().Set("Cache-Control", "no-store")
+	if readiness && h.draining.Load() {
+		JSON(w, http.StatusServiceUnavailable, HealthReport{Status: "draining"})
+		return
+	}
+	report := h.report(r.Context(), key, readiness)
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	JSON(w, status, report)
+}
+
+// report returns the cached report for key, or runs the checks to build a new one.
+func (h *health) report(ctx context.Context, key string, readiness bool) HealthReport {
+	h.mu.Lock()
+	cfg := h.cfg
+	if c, ok := h.cache[key]; ok && cfg.CacheTTL > 0 && time.Since(c.at) < cfg.CacheTTL {
+		h.mu.Unlock()
+		return c.report
+	}
+	checks := h.liveness
+	if readiness {
+		checks = h.readiness
+	}
+	names := make([]string, 0, len(checks))
+	fns := make([]HealthCheck, 0, len(checks))
+	for name, fn := range checks {
+		names = append(names, name)
+		fns = append(fns, fn)
+	}
+	h.mu.Unlock()
+
+	report := HealthReport{Status: "ok"}
+	if len(fns) > 0 {
+		report.Checks = make(map[string]HealthCheckResult, len(fns))
+	}
+	results := make([]HealthCheckResult, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, fn, cfg.Timeout)
+		}()
+	}
+	wg.Wait()
+	for i, res := range results {
+		report.Checks[names[i]] = res
+		if res.Status != "ok" {
+			report.Status = "error"
+		}
+	}
+
+	h.mu.Lock()
+	if h.cache == nil {
+		h.cache = make(map[string]cachedReport)
+	}
+	h.cache[key] = cachedReport{report: report, at: time.Now()}
+	h.mu.Unlock()
+	return report
+}
+
+// runCheck runs fn with timeout, failing it if it has not returned by then.
+func runCheck(ctx context.Context, fn HealthCheck, timeout time.Duration) HealthCheckResult {
+	// Detached from the probe's context so cached results are not skewed by a
+	// client disconnecting
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := HealthCheckResult{Status: "ok", Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package velocity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestHealth(t *testing.T) {
+	app := velocity.New()
+	if err := app.Health(velocity.HealthConfig{Timeout: 50 * time.Millisecond, CacheTTL: -1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app.AddLivenessCheck("loop", func(ctx context.Context) error { return nil })
+	app.AddReadinessCheck("db", func(ctx context.Context) error { return nil })
+	app.AddReadinessCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+	app.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedReport velocity.HealthReport
+	}{
+		{
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+			expectedReport: velocity.HealthReport{Status: "ok", Checks: map[string]velocity.HealthCheckResult{
+				"loop": {Status: "ok"},
+			}},
+		},
+		{
+			path:           "/readyz",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: velocity.HealthReport{Status: "error", Checks: map[string]velocity.HealthCheckResult{
+				"db":    {Status: "ok"},
+				"cache": {Status: "error", Error: "connection refused"},
+				"slow":  {Status: "error", Error: context.DeadlineExceeded.Error()},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected JSON, got %q", ct)
+			}
+			var report velocity.HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("decoding report: %v", err)
+			}
+			if report.Status != tt.expectedReport.Status || len(report.Checks) != len(tt.expectedReport.Checks) {
+				t.Fatalf("expected report %+v, got %+v", tt.expectedReport, report)
+			}
+			for name, expected := range tt.expectedReport.Checks {
+				got := report.Checks[name]
+				if got.Status != expected.Status || got.Error != expected.Error {
+					t.Errorf("check %s: expected %+v, got %+v", name, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthCache(t *testing.T) {
+	app := velocity.New()
+	app.Health(velocity.HealthConfig{CacheTTL: time.Minute})
+	var calls atomic.Int32
+	app.AddReadinessCheck("db", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the check to run once, ran %d times", n)
+	}
+
+	// Adding a check invalidates cached results
+	app.AddReadinessCheck("cache", func(ctx context.Context) error { return nil })
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected the check to run again, ran %d times", n)
+	}
+}
+
+func TestHealthDraining(t *testing.T) {
+	app := velocity.New()
+	app.Health(velocity.HealthConfig{DrainDelay: 300 * time.Millisecond})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go app.Serve(l)
+	url := "http://" + l.Addr().String() + "/readyz"
+	waitForServer(t, url)
+
+	done := make(chan error, 1)
+	go func() { done <- app.Shutdown(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report velocity.HealthReport
+	json.NewDecoder(res.Body).Decode(&report)
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || report.Status != "draining" {
+		t.Errorf("expected 503 draining, got %d %q", res.StatusCode, report.Status)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package velocity
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// stateKey is boxed once so looking it up on every request does not allocate.
+var stateKey any = struct {
+	name string
+}{name: "reqState"}
+
+// reqState is the per-request state an App attaches to the request context once,
+// when it starts serving the request: the locals set with SetLocal, the params of
+// the matched route, the fields added with LogField and the app and router whose
+// error handler HandlerE uses. It is pooled and recycled once the request has been
+// served.
+type reqState struct {
+	app *App
+	// router is the router of the matched route, nil before a route matched and for
+	// routes added with App.AddRoute
+	router *Router
+	locals map[any]any
+	// parent is the state of the enclosing request when the app is mounted in another
+	// app, whose locals are shared
+	parent *reqState
+	params routeParams
+	// logMu guards logAttrs, which handlers that outlive a timeout may still set
+	logMu    sync.Mutex
+	logAttrs []LogAttr
+}
+
+var statePool = sync.Pool{New: func() any {
+	return &reqState{
+		locals: make(map[any]any, 4),
+		// Sized for the params of typical routes, grown as needed
+		params: routeParams{pairs: make([]pathParam, 0, 8), values: make([]string, 0, 8)},
+	}
+}}
+
+// stateRequest is the request handed to middleware and handlers together with the
+// context carrying its state, so attaching the state takes a single allocation.
+type stateRequest struct {
+	ctx stateCtx
+	req http.Request
+}
+
+// stateCtx is a context carrying the request state, like context.WithValue.
+type stateCtx struct {
+	context.Context
+	st *reqState
+}
+
+func (c *stateCtx) Value(key any) any {
+	if key == stateKey {
+		return c.st
+	}
+	return c.Context.Value(key)
+}
+
+// attachState returns a request carrying a pooled state for app, linked to the state
+// of r if r is already being served by an enclosing app.
+func attachState(app *App, r *http.Request) (*http.Request, *reqState) {
+	st := statePool.Get().(*reqState)
+	st.app = app
+	st.parent = requestState(r)
+	sr := &stateRequest{ctx: stateCtx{Context: r.Context(), st: st}}
+	sr.req = *r.WithContext(&sr.ctx)
+	return &sr.req, st
+}
+
+// requestState returns the state attached to r, or nil if r is not served by an App.
+func requestState(r *http.Request) *reqState {
+	st, _ := r.Context().Value(stateKey).(*reqState)
+	return st
+}
+
+// root returns the state of the outermost request, which holds the locals and log
+// fields shared with mounted apps.
+func (st *reqState) root() *reqState {
+	for st.parent != nil {
+		st = st.parent
+	}
+	return st
+}
+
+// store returns the locals of the outermost request.
+func (st *reqState) store() map[any]any {
+	return st.root().locals
+}
+
+// releaseState recycles st once its request has been served.
+func releaseState(st *reqState) {
+	clear(st.locals)
+	clear(st.logAttrs)
+	st.logAttrs = st.logAttrs[:0]
+	st.app, st.router, st.parent = nil, nil, nil
+	st.params.reset()
+	statePool.Put(st)
+}
+
+// SetLocal stores value under key for the rest of the request, so middleware can pass
+// computed values such as the authenticated user to handlers. The store is attached
+// to the request context by the App serving the request, so there is no need to call
+// r.WithContext, and requests derived from r share it. SetLocal has no effect on
+// requests that are not served by an App.
+//
+// Values are only valid while the request is being served and must not be read after
+// the handler returns, e.g. from goroutines, since the store is reused.
+//
+// Example:
+//
+//	func auth(next http.HandlerFunc) http.HandlerFunc {
+//	    return func(w http.ResponseWriter, r *http.Request) {
+//	        velocity.SetLocal(r, "user", currentUser(r))
+//	        next(w, r)
+//	    }
+//	}
+func SetLocal(r *http.Request, key, value any) {
+	if st := requestState(r); st != nil {
+		st.store()[key] = value
+	}
+}
+
+// GetLocal returns the value stored under key with SetLocal, or nil if there is none.
+//
+// Example:
+//
+//	user, _ := velocity.GetLocal(r, "user").(*User)
+func GetLocal(r *http.Request, key any) any {
+	st := requestState(r)
+	if st == nil {
+		return nil
+	}
+	return st.store()[key]
+}
+
+// Local returns the value stored under key with SetLocal as a T. It reports false if
+// there is no value or it is not a T.
+//
+// Example:
+//
+//	user, ok := velocity.Local[*User](r, "user")
+func Local[T any](r *http.Request, key any) (T, bool) {
+	v, ok := GetLocal(r, key).(T)
+	return v, ok
+}
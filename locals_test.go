@@ -0,0 +1,113 @@
+package velocity_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestLocals(t *testing.T) {
+	type user struct{ name string }
+	type ctxKey struct{}
+
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if name := r.Header.Get("X-User"); name != "" {
+				velocity.SetLocal(r, "user", &user{name: name})
+			}
+			next(w, r)
+		}
+	}
+	tenant := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Requests derived from r share its store
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, true))
+			velocity.SetLocal(r, "tenant", "acme")
+			next(w, r)
+		}
+	}
+
+	app := velocity.New()
+	app.Use(auth)
+	router := app.Router("/", tenant)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		u, ok := velocity.Local[*user](r, "user")
+		if !ok {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(u.name + "@" + velocity.GetLocal(r, "tenant").(string)))
+	}
+	router.Get("/me").Handle(handler)
+	router.Get("/users/:id").Handle(handler)
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		expected string
+	}{
+		{"static route", "/me", "ada", "ada@acme"},
+		{"param route", "/users/1", "grace", "grace@acme"},
+		{"no user after reuse", "/me", "", "anonymous"},
+		{"no user on param route", "/users/1", "", "anonymous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				req.Header.Set("X-User", tt.user)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if got := rec.Body.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetLocalWithoutStore(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if v := velocity.GetLocal(req, "user"); v != nil {
+		t.Errorf("expected nil, got %v", v)
+	}
+	if _, ok := velocity.Local[string](req, "user"); ok {
+		t.Error("expected no value")
+	}
+}
+
+func TestSetLocalKeepsRequest(t *testing.T) {
+	sub := velocity.New()
+	sub.Router("/").Get("/me").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(velocity.GetLocal(r, "user").(string)))
+	})
+	app := velocity.New()
+	app.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			velocity.SetLocal(r, "user", "ada")
+			next(w, r)
+		}
+	})
+	app.Mount("/sub", sub)
+
+	req := httptest.NewRequest(http.MethodGet, "/sub/me", nil)
+	ctx := req.Context()
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if got := rec.Body.String(); got != "ada" {
+		t.Errorf("expected mounted app to share locals, got %q", got)
+	}
+	if req.Context() != ctx {
+		t.Error("expected the caller's request to be left unchanged")
+	}
+	if v := velocity.GetLocal(req, "user"); v != nil {
+		t.Errorf("expected no locals on the caller's request, got %v", v)
+	}
+}
@@ -0,0 +1,55 @@
+package velocity
+
+import "net/http"
+
+// LogAttr is a key/value pair attached to a request's access log record.
+type LogAttr struct {
+	Key   string
+	Value any
+}
+
+// LogField attaches a key/value pair to the request's access log record. Setting the
+// same key again replaces its value. Fields are kept with the request state attached
+// by the App serving the request, so middleware can add them before or after the
+// logging middleware runs. LogField has no effect on requests that are not served by
+// an App.
+//
+// Example:
+//
+//	auth := func(next http.HandlerFunc) http.HandlerFunc {
+//	    return func(w http.ResponseWriter, r *http.Request) {
+//	        velocity.LogField(r, "tenant", tenantID(r))
+//	        next(w, r)
+//	    }
+//	}
+func LogField(r *http.Request, key string, value any) {
+	st := requestState(r)
+	if st == nil {
+		return
+	}
+	st = st.root()
+	st.logMu.Lock()
+	defer st.logMu.Unlock()
+	for i := range st.logAttrs {
+		if st.logAttrs[i].Key == key {
+			st.logAttrs[i].Value = value
+			return
+		}
+	}
+	st.logAttrs = append(st.logAttrs, LogAttr{Key: key, Value: value})
+}
+
+// LogFields returns the key/value pairs attached to the request in insertion order.
+func LogFields(r *http.Request) []LogAttr {
+	st := requestState(r)
+	if st == nil {
+		return nil
+	}
+	st = st.root()
+	st.logMu.Lock()
+	defer st.logMu.Unlock()
+	if len(st.logAttrs) == 0 {
+		return nil
+	}
+	return append([]LogAttr(nil), st.logAttrs...)
+}
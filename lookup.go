@@ -0,0 +1,145 @@
+package velocity
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// RouteInfo describes a registered route. It marshals to JSON with lowercase keys,
+// omitting empty metadata.
+type RouteInfo struct {
+	// Method is the HTTP method the route is registered for, e.g. "GET"
+	Method string `json:"method"`
+
+	// Pattern is the path the route was registered with, e.g. "/users/:id"
+	Pattern string `json:"pattern"`
+
+	// Host is the host the route is restricted to, or empty for any host
+	Host string `json:"host,omitempty"`
+
+	// Name is the name assigned with route.Name, if any
+	Name string `json:"name,omitempty"`
+
+	// Params lists the route's param names in path order; the catch-all is "*"
+	Params []string `json:"params"`
+
+	// Middleware is the number of middleware wrapping the handler, excluding
+	// the app's fallback handling
+	Middleware int `json:"middleware"`
+
+	// Deprecated reports whether the route was marked with route.Deprecated
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+type routeMeta struct {
+	router     *Router
+	host       string
+	name       string
+	mws        int
+	deprecated *deprecation
+	internal   bool
+}
+
+// RouteList returns all registered routes sorted by host, pattern and method,
+// including the routes of apps attached with App.Mount under their mount prefix.
+//
+// Example:
+//
+//	for _, route := range app.RouteList() {
+//	    fmt.Println(route.Method, route.Pattern, route.Params)
+//	}
+//	// or export the routing table
+//	b, err := json.MarshalIndent(app.RouteList(), "", "  ")
+func (a *App) RouteList() []RouteInfo {
+	list := []RouteInfo{}
+	rt := a.table.Load()
+	for m, t := range rt.trees {
+		for _, e := range t.captureEndpoints() {
+			list = append(list, e.info(rt.methodNames[m]))
+		}
+	}
+	for _, trees := range rt.hosts {
+		for m, t := range trees {
+			for _, e := range t.captureEndpoints() {
+				list = append(list, e.info(rt.methodNames[m]))
+			}
+		}
+	}
+	for _, mt := range a.mounts {
+		for _, info := range mt.app.RouteList() {
+			info.Pattern = cleanPath(mt.prefix + "/" + strings.TrimPrefix(info.Pattern, "/"))
+			list = append(list, info)
+		}
+	}
+	slices.SortFunc(list, func(x, y RouteInfo) int {
+		return cmp.Or(
+			cmp.Compare(x.Host, y.Host),
+			cmp.Compare(x.Pattern, y.Pattern),
+			cmp.Compare(x.Method, y.Method),
+		)
+	})
+	return list
+}
+
+// Lookup reports which route would handle a request for method and target without
+// serving it, along with the params it would receive. Target is a path, or an absolute
+// URL whose host is used for host routing. HEAD is resolved to the GET route.
+//
+// Example:
+//
+//	info, params, ok := app.Lookup(http.MethodGet, "/users/42")
+//	// info.Pattern == "/users/:id", params["id"] == "42"
+func (a *App) Lookup(method, target string) (RouteInfo, map[string]string, bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return RouteInfo{}, nil, false
+	}
+	rt := a.table.Load()
+	m, ok := rt.methods[method]
+	if !ok {
+		return RouteInfo{}, nil, false
+	}
+	r := &http.Request{Method: method, URL: u, Host: u.Host}
+	rp := &routeParams{}
+	e := a.lookup(m, r, rp)
+	if e == nil {
+		return RouteInfo{}, nil, false
+	}
+	if err := a.decodeParams(r, rp); err != nil {
+		return RouteInfo{}, nil, false
+	}
+	return e.info(rt.methodNames[m]), rp.toMap(), true
+}
+
+// AllowedMethods returns the methods registered for the request's path and host, as
+// sent in the Allow header of automatic OPTIONS and 405 responses, or nil if no route
+// matches. HEAD follows GET, and OPTIONS is included whenever a route matches.
+//
+// Example:
+//
+//	methods := app.AllowedMethods(r)
+//	// e.g. []string{"GET", "HEAD", "POST", "OPTIONS"}
+func (a *App) AllowedMethods(r *http.Request) []string {
+	if allowed := a.allowedMethods(r); len(allowed) > 0 {
+		return allowed
+	}
+	return nil
+}
+
+func (e *endpoint) info(method string) RouteInfo {
+	info := RouteInfo{
+		Method:  method,
+		Pattern: e.fullPath,
+		Params:  append([]string{}, e.pKeys...),
+	}
+	if e.meta != nil {
+		info.Host = e.meta.host
+		info.Name = e.meta.name
+		info.Middleware = e.meta.mws
+		info.Deprecated = e.meta.deprecated != nil
+	}
+	return info
+}
@@ -0,0 +1,151 @@
+package velocity_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestLookup(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/api")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users/:id").Name("user.show").Handle(handler)
+	router.Post("/users").Deprecated(time.Time{}, "").Handle(handler)
+	app.Host("admin.example.com").Get("/settings").Handle(handler)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		expectedOK     bool
+		expectedInfo   velocity.RouteInfo
+		expectedParams map[string]string
+	}{
+		{
+			name:           "param route",
+			method:         http.MethodGet,
+			target:         "/api/users/42",
+			expectedOK:     true,
+			expectedInfo:   velocity.RouteInfo{Method: "GET", Pattern: "/api/users/:id", Name: "user.show", Params: []string{"id"}},
+			expectedParams: map[string]string{"id": "42"},
+		},
+		{
+			name:           "HEAD resolves to GET",
+			method:         http.MethodHead,
+			target:         "/api/users/42",
+			expectedOK:     true,
+			expectedInfo:   velocity.RouteInfo{Method: "GET", Pattern: "/api/users/:id", Name: "user.show", Params: []string{"id"}},
+			expectedParams: map[string]string{"id": "42"},
+		},
+		{
+			name:           "deprecated route",
+			method:         http.MethodPost,
+			target:         "/api/users",
+			expectedOK:     true,
+			expectedInfo:   velocity.RouteInfo{Method: "POST", Pattern: "/api/users", Params: []string{}, Deprecated: true},
+			expectedParams: map[string]string{},
+		},
+		{
+			name:           "host route",
+			method:         http.MethodGet,
+			target:         "https://admin.example.com/settings",
+			expectedOK:     true,
+			expectedInfo:   velocity.RouteInfo{Method: "GET", Pattern: "/settings", Host: "admin.example.com", Params: []string{}},
+			expectedParams: map[string]string{},
+		},
+		{"host route without host", http.MethodGet, "/settings", false, velocity.RouteInfo{}, nil},
+		{"missing route", http.MethodGet, "/api/missing", false, velocity.RouteInfo{}, nil},
+		{"unknown method", "PURGE", "/api/users", false, velocity.RouteInfo{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, params, ok := app.Lookup(tt.method, tt.target)
+			if ok != tt.expectedOK {
+				t.Fatalf("expected ok %v, got %v", tt.expectedOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if info.Method != tt.expectedInfo.Method || info.Pattern != tt.expectedInfo.Pattern ||
+				info.Host != tt.expectedInfo.Host || info.Name != tt.expectedInfo.Name ||
+				info.Deprecated != tt.expectedInfo.Deprecated || !slices.Equal(info.Params, tt.expectedInfo.Params) {
+				t.Errorf("expected info %+v, got %+v", tt.expectedInfo, info)
+			}
+			if len(params) != len(tt.expectedParams) {
+				t.Errorf("expected params %v, got %v", tt.expectedParams, params)
+			}
+			for k, v := range tt.expectedParams {
+				if params[k] != v {
+					t.Errorf("expected param %s=%q, got %q", k, v, params[k])
+				}
+			}
+		})
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/api")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Post("/users").Handle(handler)
+	router.Delete("/users/:id").Handle(handler)
+	app.Host("admin.example.com").Put("/settings").Handle(handler)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected []string
+	}{
+		{"static route", "/api/users", []string{"GET", "HEAD", "POST", "OPTIONS"}},
+		{"param route", "/api/users/42", []string{"DELETE", "OPTIONS"}},
+		{"host route", "https://admin.example.com/settings", []string{"PUT", "OPTIONS"}},
+		{"missing route", "/api/missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.target, nil)
+			if got := app.AllowedMethods(req); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected methods %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRouteList(t *testing.T) {
+	mw := func(next http.HandlerFunc) http.HandlerFunc { return next }
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	app := velocity.New()
+	router := app.Router("/", mw)
+	router.Get("/users/:id", mw).Name("user.show").Handle(handler)
+	router.Post("/users").Handle(handler)
+
+	list := app.RouteList()
+	if len(list) != 2 {
+		t.Fatalf("expected 2 routes, got %d: %+v", len(list), list)
+	}
+	if list[0].Pattern != "/users" || list[0].Method != "POST" || list[0].Middleware != 1 {
+		t.Errorf("unexpected first route %+v", list[0])
+	}
+	if list[1].Pattern != "/users/:id" || list[1].Name != "user.show" || list[1].Middleware != 2 {
+		t.Errorf("unexpected second route %+v", list[1])
+	}
+
+	b, err := json.Marshal(list[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"method":"GET","pattern":"/users/:id","name":"user.show","params":["id"],"middleware":2}`
+	if string(b) != expected {
+		t.Errorf("expected JSON %s, got %s", expected, b)
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+// BasicAuthConfig configures the BasicAuth middleware.
+type BasicAuthConfig struct {
+	// Validator reports whether user and pass are valid credentials. It should
+	// compare secrets in constant time, e.g. with SecureCompare.
+	Validator func(user, pass string) bool
+
+	// Users maps usernames to passwords, checked in constant time when Validator is nil
+	Users map[string]string
+
+	// Realm is the protection space sent in the WWW-Authenticate header
+	Realm string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultBasicAuthRealm = "Restricted"
+
+var basicAuthUserKey = struct {
+	name string
+}{name: "basicAuthUser"}
+
+// BasicAuth returns a middleware that requires HTTP Basic credentials accepted by
+// Validator, or listed in Users. Other requests are rejected with 401 Unauthorized
+// and a WWW-Authenticate challenge. The authenticated username is available to
+// handlers through GetBasicAuthUser.
+//
+// Example:
+//
+//	router := app.Router("/admin", middleware.BasicAuth(middleware.BasicAuthConfig{
+//	    Validator: func(user, pass string) bool {
+//	        return middleware.SecureCompare(user, "admin") && middleware.SecureCompare(pass, os.Getenv("ADMIN_PASSWORD"))
+//	    },
+//	    Realm: "admin",
+//	}))
+func BasicAuth(cfg BasicAuthConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	validator := cfg.Validator
+	if validator == nil {
+		validator = usersValidator(cfg.Users)
+	}
+	realm := cfg.Realm
+	if realm == "" {
+		realm = defaultBasicAuthRealm
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skipper != nil && cfg.Skipper(r) {
+				next(w, r)
+				return
+			}
+			user, pass, ok := r.BasicAuth()
+			if !ok || !validator(user, pass) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), basicAuthUserKey, user)
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// usersValidator checks credentials against users. Unknown users are compared
+// against an empty password too, so the response time does not reveal which
+// usernames exist.
+func usersValidator(users map[string]string) func(user, pass string) bool {
+	return func(user, pass string) bool {
+		want, ok := users[user]
+		return SecureCompare(pass, want) && ok
+	}
+}
+
+// SecureCompare reports whether a and b are equal in time that does not depend on
+// their contents or lengths, for comparing secrets such as passwords.
+func SecureCompare(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// GetBasicAuthUser retrieves the username authenticated by BasicAuth from the
+// request context.
+func GetBasicAuthUser(r *http.Request) string {
+	user, ok := r.Context().Value(basicAuthUserKey).(string)
+	if !ok {
+		return ""
+	}
+	return user
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestBasicAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         middleware.BasicAuthConfig
+		user, pass     string
+		setAuth        bool
+		expectedStatus int
+		expectedUser   string
+	}{
+		{
+			name: "valid credentials",
+			config: middleware.BasicAuthConfig{Validator: func(user, pass string) bool {
+				return middleware.SecureCompare(user, "admin") && middleware.SecureCompare(pass, "secret")
+			}},
+			user: "admin", pass: "secret", setAuth: true,
+			expectedStatus: http.StatusOK,
+			expectedUser:   "admin",
+		},
+		{
+			name: "invalid password",
+			config: middleware.BasicAuthConfig{Validator: func(user, pass string) bool {
+				return pass == "secret"
+			}},
+			user: "admin", pass: "wrong", setAuth: true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing credentials",
+			config:         middleware.BasicAuthConfig{Users: map[string]string{"admin": "secret"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "users map",
+			config: middleware.BasicAuthConfig{Users: map[string]string{"admin": "secret"}},
+			user:   "admin", pass: "secret", setAuth: true,
+			expectedStatus: http.StatusOK,
+			expectedUser:   "admin",
+		},
+		{
+			name:   "unknown user with empty password",
+			config: middleware.BasicAuthConfig{Users: map[string]string{"admin": "secret"}},
+			user:   "guest", pass: "", setAuth: true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser string
+			handler := middleware.BasicAuth(tt.config)(func(w http.ResponseWriter, r *http.Request) {
+				gotUser = middleware.GetBasicAuthUser(r)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.setAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if gotUser != tt.expectedUser {
+				t.Errorf("expected user %q, got %q", tt.expectedUser, gotUser)
+			}
+			if tt.expectedStatus == http.StatusUnauthorized {
+				want := `Basic realm="Restricted", charset="UTF-8"`
+				if got := rec.Header().Get("WWW-Authenticate"); got != want {
+					t.Errorf("expected WWW-Authenticate %q, got %q", want, got)
+				}
+			}
+		})
+	}
+}
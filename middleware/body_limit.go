@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ContentTypeBodyLimitConfig configures the ContentTypeBodyLimit middleware.
+type ContentTypeBodyLimitConfig struct {
+	// Default is the limit in bytes applied to content types without an explicit limit.
+	// A negative value disables the limit for unmatched content types.
+	Default *int64
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultBodyLimit int64 = 1 << 20
+var defaultContentTypeBodyLimitConfig = ContentTypeBodyLimitConfig{
+	Default: &defaultBodyLimit,
+}
+
+// ContentTypeBodyLimit returns a middleware that limits the request body size
+// based on the request's Content-Type. Media types are matched without parameters,
+// so "application/json; charset=utf-8" uses the "application/json" limit.
+// Requests exceeding the limit are rejected with 413 Request Entity Too Large.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.ContentTypeBodyLimit(map[string]int64{
+//	    "application/json":    1 << 20,
+//	    "multipart/form-data": 50 << 20,
+//	}))
+//	// or with config
+//	router := app.Router("/api", middleware.ContentTypeBodyLimit(limits, middleware.ContentTypeBodyLimitConfig{
+//	    Default: int64Ptr(64 << 10),
+//	}))
+func ContentTypeBodyLimit(limits map[string]int64, cfg ...ContentTypeBodyLimitConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultContentTypeBodyLimitConfig
+	if len(cfg) > 0 {
+		if cfg[0].Default != nil {
+			config.Default = cfg[0].Default
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	normalized := make(map[string]int64, len(limits))
+	for ct, limit := range limits {
+		normalized[strings.ToLower(strings.TrimSpace(ct))] = limit
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if r.Body == nil || r.Body == http.NoBody {
+				next(w, r)
+				return
+			}
+
+			limit := *config.Default
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+				if l, ok := normalized[mediaType]; ok {
+					limit = l
+				}
+			}
+
+			if limit < 0 {
+				next(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next(w, r)
+		}
+	}
+}
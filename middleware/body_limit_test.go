@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestContentTypeBodyLimit(t *testing.T) {
+	limits := map[string]int64{
+		"application/json":    16,
+		"multipart/form-data": 1 << 20,
+	}
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	part, _ := mw.CreateFormFile("upload", "data.bin")
+	part.Write(bytes.Repeat([]byte("a"), 1024))
+	mw.Close()
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "JSON over limit",
+			contentType:    "application/json; charset=utf-8",
+			body:           `{"name":"a very long name"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "JSON under limit",
+			contentType:    "application/json",
+			body:           `{"name":"a"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "multipart under larger limit",
+			contentType:    mw.FormDataContentType(),
+			body:           multipartBody.String(),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	handler := middleware.ContentTypeBodyLimit(limits)(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores cached responses as opaque byte slices. Implementations must be
+// safe for concurrent use; a store shared between replicas, such as the one in
+// package redisstore, shares cached responses between them.
+type CacheStore interface {
+	// Get returns the value stored under key, or false if there is none or it expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheConfig configures the Cache middleware.
+type CacheConfig struct {
+	// TTL is how long a response is served from the cache
+	TTL *time.Duration
+
+	// KeyFunc returns the key a response is cached under. Defaults to the host and
+	// request URI. Include any request header the response varies on.
+	KeyFunc func(r *http.Request) string
+
+	// Store holds cached responses. Defaults to an in-memory store local to the process.
+	Store CacheStore
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultCacheTTL = time.Minute
+var defaultCacheConfig = CacheConfig{
+	TTL:     &defaultCacheTTL,
+	KeyFunc: cacheKey,
+}
+
+// cachedResponse is the stored form of a response.
+type cachedResponse struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	StoredAt time.Time
+}
+
+// Cache returns a middleware that caches successful GET responses for TTL and
+// serves them, with an Age header, to later GET and HEAD requests with the same key.
+// The X-Cache response header tells whether a response was a HIT or a MISS.
+//
+// Request directives are honored: "no-store" bypasses the cache, and "no-cache" or
+// "max-age=0" skip cached responses but store the fresh one. Responses that set
+// cookies, are marked "no-store" or "private", or are flushed are not cached. If the
+// store fails, the request is handled as a miss.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.Cache())
+//	// or with config
+//	router := app.Router("/api", middleware.Cache(middleware.CacheConfig{
+//	    TTL:   durationPtr(30 * time.Second),
+//	    Store: redisstore.NewCacheStore(client, "cache:"),
+//	}))
+func Cache(cfg ...CacheConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultCacheConfig
+	if len(cfg) > 0 {
+		if cfg[0].TTL != nil {
+			config.TTL = cfg[0].TTL
+		}
+		if cfg[0].KeyFunc != nil {
+			config.KeyFunc = cfg[0].KeyFunc
+		}
+		if cfg[0].Store != nil {
+			config.Store = cfg[0].Store
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryCacheStore()
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next(w, r)
+				return
+			}
+			directives := r.Header.Get("Cache-Control")
+			if hasDirective(directives, "no-store") {
+				next(w, r)
+				return
+			}
+
+			key := config.KeyFunc(r)
+			if !hasDirective(directives, "no-cache") && !hasDirective(directives, "max-age=0") {
+				if data, ok, err := config.Store.Get(r.Context(), key); err == nil && ok {
+					var res cachedResponse
+					if gob.NewDecoder(bytes.NewReader(data)).Decode(&res) == nil {
+						writeCached(w, r, &res)
+						return
+					}
+				}
+			}
+
+			if r.Method == http.MethodHead {
+				next(w, r)
+				return
+			}
+			w.Header().Set("X-Cache", "MISS")
+			cw := &cacheWriter{ResponseWriter: w}
+			next(cw, r)
+			if !cw.cacheable() {
+				return
+			}
+
+			res := cachedResponse{
+				Status:   cw.status,
+				Header:   cw.header,
+				Body:     cw.buf.Bytes(),
+				StoredAt: time.Now(),
+			}
+			res.Header.Del("X-Cache")
+			var data bytes.Buffer
+			if gob.NewEncoder(&data).Encode(&res) == nil {
+				config.Store.Set(r.Context(), key, data.Bytes(), *config.TTL)
+			}
+		}
+	}
+}
+
+func cacheKey(r *http.Request) string {
+	return r.Host + r.URL.RequestURI()
+}
+
+// hasDirective reports whether the Cache-Control value header contains directive.
+func hasDirective(header, directive string) bool {
+	for _, d := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), directive) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeCached(w http.ResponseWriter, r *http.Request, res *cachedResponse) {
+	h := w.Header()
+	for k, v := range res.Header {
+		h[k] = v
+	}
+	h.Set("X-Cache", "HIT")
+	h.Set("Age", strconv.Itoa(int(time.Since(res.StoredAt).Seconds())))
+	h.Set("Content-Length", strconv.Itoa(len(res.Body)))
+	w.WriteHeader(res.Status)
+	if r.Method != http.MethodHead {
+		w.Write(res.Body)
+	}
+}
+
+// cacheWriter passes the response through while keeping a copy of it.
+type cacheWriter struct {
+	http.ResponseWriter
+	status  int
+	header  http.Header
+	buf     bytes.Buffer
+	flushed bool
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if cw.status == 0 && code >= 200 {
+		cw.status = code
+		cw.header = cw.Header().Clone()
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.buf.Write(p)
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *cacheWriter) Flush() {
+	cw.flushed = true
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.flushed = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// cacheable reports whether the captured response may be stored.
+func (cw *cacheWriter) cacheable() bool {
+	if cw.flushed || cw.status != http.StatusOK {
+		return false
+	}
+	if len(cw.header["Set-Cookie"]) > 0 {
+		return false
+	}
+	cc := cw.header.Get("Cache-Control")
+	return !hasDirective(cc, "no-store") && !hasDirective(cc, "private")
+}
+
+// MemoryCacheStore is a CacheStore that keeps responses in memory. Entries are
+// per process; use a shared store when running several replicas.
+type MemoryCacheStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryCacheEntry
+	nextSweep time.Time
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns an empty in-memory store.
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get implements CacheStore.
+func (s *MemoryCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !time.Now().Before(e.expires) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements CacheStore. Expired entries are removed at most once per ttl, so
+// stale responses do not accumulate.
+func (s *MemoryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(ttl)
+	}
+	s.entries[key] = memoryCacheEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
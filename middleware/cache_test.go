@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	handler := middleware.Cache()(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		io.WriteString(w, "call "+strconv.Itoa(calls))
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		cacheControl  string
+		expectedBody  string
+		expectedCache string
+	}{
+		{"first request", http.MethodGet, "/", "", "call 1", "MISS"},
+		{"cached", http.MethodGet, "/", "", "call 1", "HIT"},
+		{"head from cache", http.MethodHead, "/", "", "", "HIT"},
+		{"no-cache refreshes", http.MethodGet, "/", "no-cache", "call 2", "MISS"},
+		{"refreshed entry", http.MethodGet, "/", "", "call 2", "HIT"},
+		{"no-store bypasses", http.MethodGet, "/", "no-store", "call 3", ""},
+		{"other key", http.MethodGet, "/?page=2", "", "call 4", "MISS"},
+		{"private response", http.MethodGet, "/private", "", "call 5", "MISS"},
+		{"private not cached", http.MethodGet, "/private", "", "call 6", "MISS"},
+		{"error response", http.MethodGet, "/missing", "", "call 7", "MISS"},
+		{"error not cached", http.MethodGet, "/missing", "", "call 8", "MISS"},
+		{"unsafe method", http.MethodPost, "/", "", "call 9", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.cacheControl != "" {
+				req.Header.Set("Cache-Control", tt.cacheControl)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if got := rec.Header().Get("X-Cache"); got != tt.expectedCache {
+				t.Errorf("expected X-Cache %q, got %q", tt.expectedCache, got)
+			}
+			if tt.expectedCache == "HIT" {
+				if rec.Header().Get("Age") == "" {
+					t.Error("expected Age header on cache hit")
+				}
+				if rec.Header().Get("Content-Type") != "text/plain" {
+					t.Error("expected cached headers to be restored")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CanonicalHostConfig configures the CanonicalHost middleware.
+type CanonicalHostConfig struct {
+	// TrustProxy enables the X-Forwarded-Host and X-Forwarded-Proto headers when true
+	TrustProxy *bool
+
+	// Skip defines paths that are never redirected, such as health checks
+	Skip *[]string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultCanonicalTrustProxy = false
+var defaultCanonicalHostConfig = CanonicalHostConfig{
+	TrustProxy: &defaultCanonicalTrustProxy,
+	Skip:       &[]string{},
+}
+
+// CanonicalHost returns a middleware that redirects requests whose host does not match
+// target to the same path and query on target. A code of 0 defaults to 301 Moved Permanently.
+//
+// Example:
+//
+//	router := app.Router("/", middleware.CanonicalHost("example.com", http.StatusMovedPermanently))
+//	// or with config
+//	router := app.Router("/", middleware.CanonicalHost("example.com", http.StatusPermanentRedirect, middleware.CanonicalHostConfig{
+//	    TrustProxy: boolPtr(true),
+//	    Skip: &[]string{"/health"},
+//	}))
+func CanonicalHost(target string, code int, cfg ...CanonicalHostConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultCanonicalHostConfig
+	if len(cfg) > 0 {
+		if cfg[0].TrustProxy != nil {
+			config.TrustProxy = cfg[0].TrustProxy
+		}
+		if cfg[0].Skip != nil {
+			config.Skip = cfg[0].Skip
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	if code == 0 {
+		code = http.StatusMovedPermanently
+	}
+	_, _, err := net.SplitHostPort(target)
+	targetHasPort := err == nil
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if contains(*config.Skip, r.URL.Path) {
+				next(w, r)
+				return
+			}
+
+			host := r.Host
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			if *config.TrustProxy {
+				if fh := r.Header.Get("X-Forwarded-Host"); fh != "" {
+					host = strings.TrimSpace(strings.Split(fh, ",")[0])
+				}
+				if fp := r.Header.Get("X-Forwarded-Proto"); fp != "" {
+					scheme = strings.TrimSpace(strings.Split(fp, ",")[0])
+				}
+			}
+
+			if !targetHasPort {
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+			}
+
+			if strings.EqualFold(host, target) {
+				next(w, r)
+				return
+			}
+
+			http.Redirect(w, r, scheme+"://"+target+r.URL.RequestURI(), code)
+		}
+	}
+}
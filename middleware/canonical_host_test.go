@@ -0,0 +1,76 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	trust := true
+
+	tests := []struct {
+		name             string
+		host             string
+		forwardedHost    string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "non-canonical host redirects",
+			host:             "www.example.com",
+			path:             "/users?page=2",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "http://example.com/users?page=2",
+		},
+		{
+			name:           "canonical host passes through",
+			host:           "example.com:8080",
+			path:           "/users",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "trusted forwarded host redirects",
+			host:             "example.com",
+			forwardedHost:    "www.example.com",
+			path:             "/",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "http://example.com/",
+		},
+		{
+			name:           "skipped path passes through",
+			host:           "10.0.0.1",
+			path:           "/health",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	handler := middleware.CanonicalHost("example.com", 0, middleware.CanonicalHostConfig{
+		TrustProxy: &trust,
+		Skip:       &[]string{"/health"},
+	})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			if tt.forwardedHost != "" {
+				req.Header.Set("X-Forwarded-Host", tt.forwardedHost)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.expectedLocation {
+				t.Errorf("expected location %q, got %q", tt.expectedLocation, got)
+			}
+		})
+	}
+}
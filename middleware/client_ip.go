@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
@@ -14,6 +15,15 @@ type ClientIPConfig struct {
 
 	// TrustProxy enables proxy headers when true
 	TrustProxy *bool
+
+	// TrustedProxies lists the IPs and CIDR ranges of trusted proxies, e.g.
+	// "10.0.0.0/8". When set, proxy headers are only honored for requests from a
+	// trusted proxy, and the client IP is the last address in the forwarding chain
+	// that is not a trusted proxy.
+	TrustedProxies *[]string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
 }
 
 var defaultRealIPHeader = "X-Real-IP"
@@ -29,6 +39,14 @@ var clientIPKey = struct {
 
 // ClientIP returns a middleware that sets the client's IP address.
 //
+// Without TrustedProxies, proxy headers are trusted from any peer when TrustProxy
+// is set, so clients can spoof their IP. With TrustedProxies, the RFC 7239
+// Forwarded header, X-Forwarded-For and Header are checked in that order, but only
+// if the request comes from a trusted proxy. The forwarding chain is walked from
+// the nearest hop backwards, skipping trusted proxies, so entries prepended by the
+// client are ignored. It panics if an entry of TrustedProxies is not a valid IP or
+// CIDR range.
+//
 // Example:
 //
 //	router := app.Router("/api", middleware.ClientIP())
@@ -37,6 +55,10 @@ var clientIPKey = struct {
 //	    Header: stringPtr("X-Real-IP"),
 //	    TrustProxy: boolPtr(true),
 //	}))
+//	// or behind a load balancer in 10.0.0.0/8
+//	router := app.Router("/api", middleware.ClientIP(middleware.ClientIPConfig{
+//	    TrustedProxies: &[]string{"10.0.0.0/8"},
+//	}))
 func ClientIP(cfg ...ClientIPConfig) func(next http.HandlerFunc) http.HandlerFunc {
 	config := defaultClientIPConfig
 	if len(cfg) > 0 {
@@ -46,15 +68,34 @@ func ClientIP(cfg ...ClientIPConfig) func(next http.HandlerFunc) http.HandlerFun
 		if cfg[0].TrustProxy != nil {
 			config.TrustProxy = cfg[0].TrustProxy
 		}
+		config.TrustedProxies = cfg[0].TrustedProxies
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	var trusted []netip.Prefix
+	if config.TrustedProxies != nil {
+		trusted = parsePrefixes(*config.TrustedProxies)
 	}
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			clientIP := ""
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				remoteIP = r.RemoteAddr
+			}
 
-			if *config.TrustProxy {
+			clientIP := ""
+			switch {
+			case config.TrustedProxies != nil:
+				clientIP = trustedClientIP(r, remoteIP, trusted, *config.Header)
+			case *config.TrustProxy:
 				if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-					clientIP = strings.Split(xff, ",")[0]
+					clientIP = strings.TrimSpace(strings.Split(xff, ",")[0])
 				}
 
 				if clientIP == "" && *config.Header != "" {
@@ -63,12 +104,7 @@ func ClientIP(cfg ...ClientIPConfig) func(next http.HandlerFunc) http.HandlerFun
 			}
 
 			if clientIP == "" {
-				ip, _, err := net.SplitHostPort(r.RemoteAddr)
-				if err != nil {
-					clientIP = r.RemoteAddr
-				} else {
-					clientIP = ip
-				}
+				clientIP = remoteIP
 			}
 
 			w.Header().Set("X-Client-IP", clientIP)
@@ -78,6 +114,115 @@ func ClientIP(cfg ...ClientIPConfig) func(next http.HandlerFunc) http.HandlerFun
 	}
 }
 
+func parsePrefixes(entries []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		if p, err := netip.ParsePrefix(e); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			panic("velocity: invalid trusted proxy " + e)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedClientIP returns the client IP from the proxy headers of a request sent by
+// remoteIP, or an empty string if remoteIP is not trusted or the headers are unusable.
+func trustedClientIP(r *http.Request, remoteIP string, trusted []netip.Prefix, header string) string {
+	if !isTrusted(remoteIP, trusted) {
+		return ""
+	}
+	if hops := forwardedFor(r.Header.Values("Forwarded")); len(hops) > 0 {
+		return lastUntrusted(hops, trusted)
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		var hops []string
+		for _, v := range xff {
+			for _, hop := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		return lastUntrusted(hops, trusted)
+	}
+	if header != "" {
+		if ip := strings.TrimSpace(r.Header.Get(header)); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	return ""
+}
+
+// lastUntrusted walks hops from the nearest proxy backwards and returns the first
+// address that is not a trusted proxy. If an address is malformed, the chain cannot
+// be followed further and an empty string is returned.
+func lastUntrusted(hops []string, trusted []netip.Prefix) string {
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			return ""
+		}
+		if !isTrusted(hops[i], trusted) {
+			return hops[i]
+		}
+	}
+	// Every hop is trusted; the first one is the furthest known client
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return ""
+}
+
+// forwardedFor returns the addresses of the "for" parameters of RFC 7239 Forwarded
+// header values, in order, with quotes, brackets and ports removed.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				hops = append(hops, forwardedNode(value))
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedNode strips quotes, IPv6 brackets and the port from a Forwarded node,
+// e.g. "[2001:db8::1]:4711" becomes 2001:db8::1.
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
 // GetClientIP retrieves the client IP from the request context.
 func GetClientIP(r *http.Request) string {
 	id, ok := r.Context().Value(clientIPKey).(string)
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	mw := middleware.ClientIP(middleware.ClientIPConfig{
+		TrustedProxies: &[]string{"10.0.0.0/8", "2001:db8::1"},
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted peer spoofing XFF", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"spoofed entry prepended by client", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"malformed chain", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "garbage"}, "10.0.0.2"},
+		{"Forwarded header", "10.0.0.2:80", map[string]string{"Forwarded": `for=192.0.2.60;proto=http;by=203.0.113.43, for="10.0.0.9:8080"`}, "192.0.2.60"},
+		{"Forwarded IPv6", "10.0.0.2:80", map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711"`}, "2001:db8:cafe::17"},
+		{"Forwarded preferred", "10.0.0.2:80", map[string]string{"Forwarded": "for=192.0.2.60", "X-Forwarded-For": "198.51.100.1"}, "192.0.2.60"},
+		{"X-Real-IP", "[2001:db8::1]:443", map[string]string{"X-Real-IP": "198.51.100.9"}, "198.51.100.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := mw(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.GetClientIP(r)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler(httptest.NewRecorder(), req)
+
+			if got != tt.expected {
+				t.Errorf("expected client IP %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientIPInvalidTrustedProxy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid trusted proxy")
+		}
+	}()
+	middleware.ClientIP(middleware.ClientIPConfig{TrustedProxies: &[]string{"not-an-ip"}})
+}
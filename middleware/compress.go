@@ -0,0 +1,370 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EncoderWriter is a compressing writer that can be reused for another response
+// after Reset, such as *gzip.Writer.
+type EncoderWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoder provides compressing writers for one content coding. Writers are pooled
+// by the Compress middleware and reset for each response, so they are not
+// allocated per request.
+type Encoder struct {
+	// Name is the content coding, as used in Accept-Encoding, e.g. "gzip"
+	Name string
+
+	// New returns a writer that compresses to w
+	New func(w io.Writer) EncoderWriter
+}
+
+// GzipEncoder returns an Encoder for the gzip content coding at the given level,
+// e.g. gzip.DefaultCompression.
+func GzipEncoder(level int) Encoder {
+	return Encoder{Name: "gzip", New: func(w io.Writer) EncoderWriter {
+		zw, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			zw = gzip.NewWriter(w)
+		}
+		return zw
+	}}
+}
+
+// DeflateEncoder returns an Encoder for the deflate content coding at the given
+// level, e.g. flate.DefaultCompression.
+func DeflateEncoder(level int) Encoder {
+	return Encoder{Name: "deflate", New: func(w io.Writer) EncoderWriter {
+		fw, err := flate.NewWriter(w, level)
+		if err != nil {
+			fw, _ = flate.NewWriter(w, flate.DefaultCompression)
+		}
+		return fw
+	}}
+}
+
+// CompressConfig configures the Compress middleware.
+type CompressConfig struct {
+	// Encoders are the supported content codings, in order of preference when the
+	// client accepts several of them with the same quality value
+	Encoders *[]Encoder
+
+	// MinLength is the minimum response size in bytes worth compressing.
+	// Smaller responses are sent as they are.
+	MinLength *int
+
+	// Types lists the compressible media types. Entries ending in "/" match a
+	// whole type, e.g. "text/". Responses without a Content-Type are sniffed first.
+	Types *[]string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultCompressEncoders = []Encoder{GzipEncoder(gzip.DefaultCompression), DeflateEncoder(flate.DefaultCompression)}
+var defaultCompressMinLength = 1024
+var defaultCompressTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"application/problem+json",
+	"application/wasm",
+	"image/svg+xml",
+}
+var defaultCompressConfig = CompressConfig{
+	Encoders:  &defaultCompressEncoders,
+	MinLength: &defaultCompressMinLength,
+	Types:     &defaultCompressTypes,
+}
+
+// Compress returns a middleware that compresses responses using the content coding
+// the client prefers according to the quality values in Accept-Encoding. Responses
+// that are small, already encoded, partial or of an incompressible type are sent
+// unchanged. Flushing is supported, so streamed responses are compressed in chunks.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.Compress())
+//	// or with config, e.g. adding brotli and zstd
+//	router := app.Router("/api", middleware.Compress(middleware.CompressConfig{
+//	    Encoders: &[]middleware.Encoder{encoders.Zstd(3), encoders.Brotli(5), middleware.GzipEncoder(6)},
+//	}))
+func Compress(cfg ...CompressConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultCompressConfig
+	if len(cfg) > 0 {
+		if cfg[0].Encoders != nil {
+			config.Encoders = cfg[0].Encoders
+		}
+		if cfg[0].MinLength != nil {
+			config.MinLength = cfg[0].MinLength
+		}
+		if cfg[0].Types != nil {
+			config.Types = cfg[0].Types
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	encoders := make([]*pooledEncoder, len(*config.Encoders))
+	for i, e := range *config.Encoders {
+		pe := &pooledEncoder{name: e.Name}
+		pe.pool.New = func() any { return e.New(io.Discard) }
+		encoders[i] = pe
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"), encoders)
+			if enc == nil || r.Method == http.MethodHead {
+				next(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				enc:            enc,
+				minLength:      *config.MinLength,
+				types:          *config.Types,
+			}
+			defer cw.close()
+			next(cw, r)
+		}
+	}
+}
+
+type pooledEncoder struct {
+	name string
+	pool sync.Pool
+}
+
+// negotiateEncoding returns the encoder with the highest quality value in header,
+// preferring earlier encoders on ties, or nil if none is acceptable.
+func negotiateEncoding(header string, encoders []*pooledEncoder) *pooledEncoder {
+	if header == "" {
+		return nil
+	}
+	var best *pooledEncoder
+	bestQ := 0.0
+	for _, e := range encoders {
+		q := acceptQuality(header, e.name)
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality value header assigns to coding, falling back to
+// the "*" entry, or 0 if coding is not acceptable.
+func acceptQuality(header, coding string) float64 {
+	q, wildcard := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		v := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, val, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(val, 64); err == nil {
+					v = f
+				}
+			}
+		}
+		if name == coding {
+			q = v
+		} else {
+			wildcard = v
+		}
+	}
+	if q >= 0 {
+		return q
+	}
+	return max(wildcard, 0)
+}
+
+// compressWriter buffers the start of a response until it knows whether to compress
+// it: once MinLength bytes were written, or the response is flushed or complete.
+type compressWriter struct {
+	http.ResponseWriter
+	enc       *pooledEncoder
+	minLength int
+	types     []string
+
+	status  int
+	buf     []byte
+	decided bool
+	zw      EncoderWriter
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		if code < 200 {
+			cw.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	// Informational responses, e.g. 101 Switching Protocols, pass through
+	if code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		if code == http.StatusSwitchingProtocols {
+			cw.decided = true
+		}
+		return
+	}
+	cw.status = code
+	if !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minLength {
+			return len(p), nil
+		}
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data, compressing it if the response qualifies regardless of
+// MinLength, since a flushed response is likely streamed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if !cw.decided {
+			cw.start(cw.compressible())
+		}
+	}
+	if cw.zw != nil {
+		cw.zw.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.decided = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed based on its status
+// and headers. It sniffs the content type from the buffered data if none is set.
+func (cw *compressWriter) compressible() bool {
+	if cw.status == http.StatusNoContent || cw.status == http.StatusNotModified || cw.status == http.StatusPartialContent {
+		return false
+	}
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < cw.minLength {
+			return false
+		}
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		if len(cw.buf) == 0 {
+			// Decide once there is data to sniff
+			return true
+		}
+		ct = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", ct)
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, t := range cw.types {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// start writes the headers and buffered data, compressed if compress is true.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	if compress {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.enc.name)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed representation is no longer byte-identical
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.zw = cw.enc.pool.Get().(EncoderWriter)
+		cw.zw.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// close finishes the response once the handler has returned.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Nothing was written; let net/http send its default response
+			return
+		}
+		// The whole response is smaller than MinLength
+		cw.start(false)
+	}
+	if cw.zw != nil {
+		cw.zw.Close()
+		cw.zw.Reset(io.Discard)
+		cw.enc.pool.Put(cw.zw)
+		cw.zw = nil
+	}
+}
@@ -0,0 +1,172 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+	"github.com/Juanfec4/velocity/middleware/encoders"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("velocity ", 500)
+	mw := middleware.Compress(middleware.CompressConfig{
+		Encoders: &[]middleware.Encoder{
+			encoders.Zstd(zstd.SpeedDefault),
+			encoders.Brotli(brotli.DefaultCompression),
+			middleware.GzipEncoder(gzip.DefaultCompression),
+		},
+	})
+
+	handler := func(status int, contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		status           int
+		contentType      string
+		body             string
+		expectedEncoding string
+	}{
+		{"no Accept-Encoding", "", 200, "text/plain", body, ""},
+		{"server preference on tie", "gzip, br, zstd", 200, "text/plain", body, "zstd"},
+		{"quality values", "gzip;q=1, br;q=0.8, zstd;q=0.5", 200, "text/plain", body, "gzip"},
+		{"brotli", "br", 200, "application/json", body, "br"},
+		{"wildcard", "*", 200, "text/html", body, "zstd"},
+		{"excluded coding", "zstd;q=0, br", 200, "text/plain", body, "br"},
+		{"unsupported coding", "compress", 200, "text/plain", body, ""},
+		{"small response", "gzip", 200, "text/plain", "short", ""},
+		{"incompressible type", "gzip", 200, "image/png", body, ""},
+		{"sniffed type", "gzip", 200, "", body, "gzip"},
+		{"no content", "gzip", 204, "text/plain", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			mw(handler(tt.status, tt.contentType, tt.body))(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.expectedEncoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", tt.expectedEncoding, got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %q", got)
+			}
+			if got := decode(t, tt.expectedEncoding, rec.Body); got != tt.body {
+				t.Errorf("expected body of length %d, got length %d", len(tt.body), len(got))
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	mw := middleware.Compress()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		http.NewResponseController(w).Flush()
+		io.WriteString(w, "data: second\n\n")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	mw(handler)(rec, req)
+
+	if !rec.Flushed {
+		t.Error("expected response to be flushed")
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	if got := decode(t, "gzip", rec.Body); got != "data: first\n\ndata: second\n\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+func TestCompressPreEncoded(t *testing.T) {
+	mw := middleware.Compress(middleware.CompressConfig{MinLength: new(int)})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "identity")
+		io.WriteString(w, "plain")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	mw(handler)(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "identity" {
+		t.Errorf("expected Content-Encoding to be kept, got %q", got)
+	}
+	if rec.Body.String() != "plain" {
+		t.Errorf("expected body %q, got %q", "plain", rec.Body.String())
+	}
+}
+
+func decode(t *testing.T, encoding string, body *bytes.Buffer) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "":
+		return body.String()
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "br":
+		r = brotli.NewReader(body)
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func BenchmarkCompress(b *testing.B) {
+	body := []byte(strings.Repeat("velocity ", 500))
+	h := middleware.Compress()(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h(httptest.NewRecorder(), req)
+	}
+}
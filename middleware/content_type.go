@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AllowContentTypeConfig configures the AllowContentType middleware.
+type AllowContentTypeConfig struct {
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+// AllowContentType returns a middleware that rejects requests with a body whose
+// Content-Type is not one of types with 415 Unsupported Media Type. Parameters such
+// as charset are ignored when matching. Requests without a body are passed through.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.AllowContentType([]string{"application/json"}))
+//	// or with config
+//	router := app.Router("/api", middleware.AllowContentType([]string{"application/json"}, middleware.AllowContentTypeConfig{
+//	    Skipper: middleware.SkipPaths("/api/uploads/*"),
+//	}))
+func AllowContentType(types []string, cfg ...AllowContentTypeConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	var config AllowContentTypeConfig
+	if len(cfg) > 0 {
+		config.Skipper = cfg[0].Skipper
+	}
+	allowed := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = struct{}{}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next(w, r)
+				return
+			}
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if _, ok := allowed[mediaType]; err != nil || !ok {
+				http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireAcceptConfig configures the RequireAccept middleware.
+type RequireAcceptConfig struct {
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+// RequireAccept returns a middleware that rejects requests whose Accept header
+// accepts none of types with 406 Not Acceptable. Wildcards such as "*/*" and
+// "application/*" and quality values are honored; a missing Accept header accepts
+// anything.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.RequireAccept([]string{"application/json", "application/problem+json"}))
+//	// or with config
+//	router := app.Router("/api", middleware.RequireAccept([]string{"application/json"}, middleware.RequireAcceptConfig{
+//	    Skipper: middleware.SkipPaths("/api/export/*"),
+//	}))
+func RequireAccept(types []string, cfg ...RequireAcceptConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	var config RequireAcceptConfig
+	if len(cfg) > 0 {
+		config.Skipper = cfg[0].Skipper
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			accept := r.Header.Values("Accept")
+			if len(accept) == 0 {
+				next(w, r)
+				return
+			}
+			header := strings.Join(accept, ",")
+			for _, t := range types {
+				if acceptsMediaType(header, t) {
+					next(w, r)
+					return
+				}
+			}
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		}
+	}
+}
+
+// acceptsMediaType reports whether the Accept header value accepts mediaType with a
+// non-zero quality. The most specific matching range decides, as in RFC 9110.
+func acceptsMediaType(header, mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	typ, _, _ := strings.Cut(mediaType, "/")
+	bestSpecificity, q := -1, 0.0
+	for _, part := range strings.Split(header, ",") {
+		rng, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		rng = strings.ToLower(strings.TrimSpace(rng))
+
+		specificity := -1
+		switch {
+		case rng == mediaType:
+			specificity = 2
+		case rng == typ+"/*":
+			specificity = 1
+		case rng == "*/*":
+			specificity = 0
+		}
+		if specificity <= bestSpecificity {
+			continue
+		}
+		bestSpecificity, q = specificity, 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+	}
+	return q > 0
+}
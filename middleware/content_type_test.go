@@ -0,0 +1,81 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestAllowContentType(t *testing.T) {
+	skipCSV := func(r *http.Request) bool { return r.Header.Get("Content-Type") == "text/csv" }
+	handler := middleware.AllowContentType([]string{"application/json", "application/xml"}, middleware.AllowContentTypeConfig{Skipper: skipCSV})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{"allowed type", http.MethodPost, "application/json", "{}", http.StatusOK},
+		{"allowed type with charset", http.MethodPost, "Application/JSON; charset=utf-8", "{}", http.StatusOK},
+		{"other type", http.MethodPost, "text/plain", "hi", http.StatusUnsupportedMediaType},
+		{"missing type", http.MethodPost, "", "hi", http.StatusUnsupportedMediaType},
+		{"no body", http.MethodGet, "", "", http.StatusOK},
+		{"skipped", http.MethodPost, "text/csv", "a,b", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRequireAccept(t *testing.T) {
+	handler := middleware.RequireAccept([]string{"application/json"})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		accept         string
+		expectedStatus int
+	}{
+		{"missing Accept", "", http.StatusOK},
+		{"exact", "application/json", http.StatusOK},
+		{"type wildcard", "application/*", http.StatusOK},
+		{"any", "text/html, */*;q=0.1", http.StatusOK},
+		{"not accepted", "text/html", http.StatusNotAcceptable},
+		{"explicitly refused", "application/json;q=0, */*", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Juanfec4/velocity"
 )
 
 // CorsConfig configures the CORS middleware.
@@ -17,26 +20,62 @@ type CorsConfig struct {
 	// ExposedHeaders defines headers exposed to the client
 	ExposedHeaders *[]string
 
-	// AllowedOrigins defines allowed origins
+	// AllowedOrigins defines allowed origins. "*" allows any origin, and a "*" in
+	// place of the leftmost host labels matches any subdomain, e.g.
+	// "https://*.example.com".
 	AllowedOrigins *[]string
+
+	// AllowOriginFunc is called for origins not matched by AllowedOrigins and
+	// allows the origin if it returns true
+	AllowOriginFunc func(origin string) bool
+
+	// AllowCredentials sets Access-Control-Allow-Credentials, letting browsers send
+	// cookies and authorization headers. The request origin is then echoed instead of "*".
+	AllowCredentials *bool
+
+	// MaxAge is how long browsers may cache preflight results. Zero omits the header.
+	MaxAge *time.Duration
+
+	// App makes preflight responses list the methods registered for the requested
+	// path instead of AllowedMethods. Preflights for paths without routes get no
+	// Access-Control-Allow-Methods header, so the browser rejects them.
+	App *velocity.App
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
 }
 
+var defaultCorsAllowCredentials = false
+var defaultCorsMaxAge = time.Duration(0)
 var defaultConfig = CorsConfig{
-	AllowedMethods: &[]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
-	AllowedHeaders: &[]string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
-	ExposedHeaders: &[]string{},
-	AllowedOrigins: &[]string{"*"},
+	AllowedMethods:   &[]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
+	AllowedHeaders:   &[]string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+	ExposedHeaders:   &[]string{},
+	AllowedOrigins:   &[]string{"*"},
+	AllowCredentials: &defaultCorsAllowCredentials,
+	MaxAge:           &defaultCorsMaxAge,
 }
 
-// CORS returns a middleware that handles CORS.
+// CORS returns a middleware that handles CORS. Preflight requests, OPTIONS requests
+// with an Access-Control-Request-Method header, are answered with 204 No Content;
+// other requests get the CORS response headers and are passed on. Requests without
+// an Origin header are not cross-origin and are passed on unchanged.
+//
+// Preflights usually target paths without an OPTIONS route, so they are answered by
+// the router's automatic OPTIONS handling, which runs app-level middleware and the
+// middleware of the matching top-level router. Set App to advertise the methods
+// actually registered for each path.
 //
 // Example:
 //
 //	router := app.Router("/api", middleware.CORS())
 //	// or with config
 //	router := app.Router("/api", middleware.CORS(middleware.CorsConfig{
-//	    AllowedOrigins: &[]string{"https://example.com"},
+//	    AllowedOrigins: &[]string{"https://example.com", "https://*.example.com"},
+//	    AllowCredentials: boolPtr(true),
 //	}))
+//	// or advertising the registered methods of each path
+//	app.Use(middleware.CORS(middleware.CorsConfig{App: app}))
 func CORS(cfg ...CorsConfig) func(next http.HandlerFunc) http.HandlerFunc {
 	config := defaultConfig
 	if len(cfg) > 0 {
@@ -52,48 +91,102 @@ func CORS(cfg ...CorsConfig) func(next http.HandlerFunc) http.HandlerFunc {
 		if cfg[0].AllowedOrigins != nil {
 			config.AllowedOrigins = cfg[0].AllowedOrigins
 		}
+		if cfg[0].AllowOriginFunc != nil {
+			config.AllowOriginFunc = cfg[0].AllowOriginFunc
+		}
+		if cfg[0].AllowCredentials != nil {
+			config.AllowCredentials = cfg[0].AllowCredentials
+		}
+		if cfg[0].MaxAge != nil {
+			config.MaxAge = cfg[0].MaxAge
+		}
+		if cfg[0].App != nil {
+			config.App = cfg[0].App
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	allowAll := contains(*config.AllowedOrigins, "*")
+	maxAge := ""
+	if *config.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
 	}
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-
-			origin := r.Header.Get("Origin")
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			h := w.Header()
+			origin := GetOrigin(r)
 			if origin == "" {
-				origin = GetOrigin(r)
+				next(w, r)
+				return
 			}
 
-			if (*config.AllowedOrigins)[0] == "*" {
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-			} else if contains(*config.AllowedOrigins, origin) {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
+			allowed := allowAll || originAllowed(*config.AllowedOrigins, origin) ||
+				(config.AllowOriginFunc != nil && config.AllowOriginFunc(origin))
+			if allowAll && !*config.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				// The response depends on the request origin
+				h.Add("Vary", "Origin")
+				if allowed {
+					h.Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			if allowed && *config.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
 			}
 
-			if r.Method == http.MethodOptions {
-				w.Header().Set("Access-Control-Allow-Methods", strings.Join(*config.AllowedMethods, ", "))
-				w.Header().Set("Access-Control-Allow-Headers", strings.Join(*config.AllowedHeaders, ", "))
-				if len(*config.ExposedHeaders) > 0 {
-					w.Header().Set("Access-Control-Expose-Headers", strings.Join(*config.ExposedHeaders, ", "))
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				methods := *config.AllowedMethods
+				if config.App != nil {
+					methods = config.App.AllowedMethods(r)
+				}
+				if allowed && len(methods) > 0 {
+					h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+					h.Set("Access-Control-Allow-Headers", strings.Join(*config.AllowedHeaders, ", "))
+					if maxAge != "" {
+						h.Set("Access-Control-Max-Age", maxAge)
+					}
 				}
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
+			if allowed && len(*config.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(*config.ExposedHeaders, ", "))
+			}
 			next(w, r)
 		}
 	}
 }
 
-func GetOrigin(r *http.Request) string {
-	if origin := r.Header.Get("Origin"); origin != "" {
-		return origin
-	}
-	if referer := r.Header.Get("Referer"); referer != "" {
-		return referer
-	}
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
+// originAllowed reports whether origin matches one of patterns, where a "*" in
+// place of the leftmost host labels matches one or more subdomains.
+func originAllowed(patterns []string, origin string) bool {
+	for _, p := range patterns {
+		if p == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(p, "://*.")
+		if !ok {
+			continue
+		}
+		rest, ok := strings.CutPrefix(origin, scheme+"://")
+		if ok && strings.HasSuffix(rest, "."+host) && len(rest) > len(host)+1 {
+			return true
+		}
 	}
-	return fmt.Sprintf("%s://%s", scheme, r.Host)
+	return false
+}
+
+// GetOrigin returns the request's Origin header, or an empty string for requests
+// that are not cross-origin.
+func GetOrigin(r *http.Request) string {
+	return r.Header.Get("Origin")
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,170 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestCORS(t *testing.T) {
+	credentials := true
+	maxAge := 10 * time.Minute
+	restricted := middleware.CORS(middleware.CorsConfig{
+		AllowedOrigins:   &[]string{"https://example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".internal") },
+		AllowCredentials: &credentials,
+		MaxAge:           &maxAge,
+		ExposedHeaders:   &[]string{"X-Total-Count"},
+	})
+
+	tests := []struct {
+		name            string
+		mw              func(http.HandlerFunc) http.HandlerFunc
+		method          string
+		origin          string
+		preflight       bool
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			name: "wildcard", mw: middleware.CORS(), method: http.MethodGet, origin: "https://any.com",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "*", "Vary": ""},
+		},
+		{
+			name: "no origin", mw: restricted, method: http.MethodGet,
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name: "listed origin", mw: restricted, method: http.MethodGet, origin: "https://example.com",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Total-Count",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name: "subdomain pattern", mw: restricted, method: http.MethodGet, origin: "https://app.eu.example.org",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "https://app.eu.example.org"},
+		},
+		{
+			name: "pattern requires a subdomain", mw: restricted, method: http.MethodGet, origin: "https://example.org",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "pattern checks scheme", mw: restricted, method: http.MethodGet, origin: "http://app.example.org",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "origin func", mw: restricted, method: http.MethodGet, origin: "http://tools.internal",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "http://tools.internal"},
+		},
+		{
+			name: "disallowed origin", mw: restricted, method: http.MethodGet, origin: "https://evil.com",
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name: "preflight", mw: restricted, method: http.MethodOptions, origin: "https://example.com", preflight: true,
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Max-Age":       "600",
+				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS, PATCH, HEAD",
+			},
+		},
+		{
+			name: "plain OPTIONS", mw: restricted, method: http.MethodOptions, origin: "https://example.com",
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Max-Age": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			}
+			rec := httptest.NewRecorder()
+			tt.mw(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			for k, v := range tt.expectedHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected %s %q, got %q", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSRegisteredMethods(t *testing.T) {
+	app := velocity.New()
+	app.Use(middleware.CORS(middleware.CorsConfig{App: app}))
+	router := app.Router("/api")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Post("/users").Handle(handler)
+	router.Delete("/users/:id").Handle(handler)
+
+	tests := []struct {
+		name            string
+		path            string
+		preflight       bool
+		expectedStatus  int
+		expectedMethods string
+		expectedAllow   string
+	}{
+		{"preflight", "/api/users", true, http.StatusNoContent, "GET, HEAD, POST, OPTIONS", ""},
+		{"preflight with params", "/api/users/42", true, http.StatusNoContent, "DELETE, OPTIONS", ""},
+		{"preflight for missing route", "/api/missing", true, http.StatusNoContent, "", ""},
+		{"plain OPTIONS", "/api/users", false, http.StatusNoContent, "", "GET, HEAD, POST, OPTIONS"},
+		{"plain OPTIONS for missing route", "/api/missing", false, http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", "https://example.com")
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.expectedMethods {
+				t.Errorf("expected Access-Control-Allow-Methods %q, got %q", tt.expectedMethods, got)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("expected Allow %q, got %q", tt.expectedAllow, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// CSRFConfig configures the CSRF middleware.
+type CSRFConfig struct {
+	// CookieName is the name of the cookie holding the token
+	CookieName *string
+
+	// HeaderName is the request header checked for the submitted token
+	HeaderName *string
+
+	// FormField is the form field checked for the submitted token when the header is absent
+	FormField *string
+
+	// CookiePath is the Path attribute of the token cookie
+	CookiePath *string
+
+	// CookieDomain is the Domain attribute of the token cookie
+	CookieDomain *string
+
+	// Secure sets the Secure attribute of the token cookie
+	Secure *bool
+
+	// HTTPOnly sets the HttpOnly attribute of the token cookie. Leave it false when
+	// client-side scripts read the cookie to submit the token in a header.
+	HTTPOnly *bool
+
+	// SameSite is the SameSite attribute of the token cookie
+	SameSite *http.SameSite
+
+	// MaxAge is the lifetime of the token cookie
+	MaxAge *time.Duration
+
+	// Skip defines paths that are never checked, such as webhook endpoints
+	Skip *[]string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultCSRFCookieName = "_csrf"
+var defaultCSRFHeaderName = "X-CSRF-Token"
+var defaultCSRFFormField = "_csrf"
+var defaultCSRFCookiePath = "/"
+var defaultCSRFCookieDomain = ""
+var defaultCSRFSecure = true
+var defaultCSRFHTTPOnly = false
+var defaultCSRFSameSite = http.SameSiteLaxMode
+var defaultCSRFMaxAge = 12 * time.Hour
+var defaultCSRFConfig = CSRFConfig{
+	CookieName:   &defaultCSRFCookieName,
+	HeaderName:   &defaultCSRFHeaderName,
+	FormField:    &defaultCSRFFormField,
+	CookiePath:   &defaultCSRFCookiePath,
+	CookieDomain: &defaultCSRFCookieDomain,
+	Secure:       &defaultCSRFSecure,
+	HTTPOnly:     &defaultCSRFHTTPOnly,
+	SameSite:     &defaultCSRFSameSite,
+	MaxAge:       &defaultCSRFMaxAge,
+	Skip:         &[]string{},
+}
+
+const csrfTokenLength = 32
+
+var csrfKey = struct {
+	name string
+}{name: "csrf"}
+
+var csrfFormFieldKey = struct {
+	name string
+}{name: "csrfFormField"}
+
+// CSRF returns a middleware that protects against cross-site request forgery using
+// the double-submit cookie pattern. A random token is stored in a cookie, and requests
+// with unsafe methods (anything but GET, HEAD, OPTIONS and TRACE) must submit the same
+// token in the HeaderName header or the FormField form field, or are rejected with
+// 403 Forbidden. Templates get the token with GetCSRFToken or CSRFField; it is masked
+// differently on every request so it cannot be recovered through compression
+// side channels such as BREACH.
+//
+// Example:
+//
+//	router := app.Router("/", middleware.CSRF())
+//	// or with config
+//	router := app.Router("/", middleware.CSRF(middleware.CSRFConfig{
+//	    SameSite: &strictMode,
+//	    Skip: &[]string{"/webhooks/stripe"},
+//	}))
+func CSRF(cfg ...CSRFConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultCSRFConfig
+	if len(cfg) > 0 {
+		if cfg[0].CookieName != nil {
+			config.CookieName = cfg[0].CookieName
+		}
+		if cfg[0].HeaderName != nil {
+			config.HeaderName = cfg[0].HeaderName
+		}
+		if cfg[0].FormField != nil {
+			config.FormField = cfg[0].FormField
+		}
+		if cfg[0].CookiePath != nil {
+			config.CookiePath = cfg[0].CookiePath
+		}
+		if cfg[0].CookieDomain != nil {
+			config.CookieDomain = cfg[0].CookieDomain
+		}
+		if cfg[0].Secure != nil {
+			config.Secure = cfg[0].Secure
+		}
+		if cfg[0].HTTPOnly != nil {
+			config.HTTPOnly = cfg[0].HTTPOnly
+		}
+		if cfg[0].SameSite != nil {
+			config.SameSite = cfg[0].SameSite
+		}
+		if cfg[0].MaxAge != nil {
+			config.MaxAge = cfg[0].MaxAge
+		}
+		if cfg[0].Skip != nil {
+			config.Skip = cfg[0].Skip
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if contains(*config.Skip, r.URL.Path) {
+				next(w, r)
+				return
+			}
+
+			var token []byte
+			if c, err := r.Cookie(*config.CookieName); err == nil {
+				token = decodeCSRFToken(c.Value)
+			}
+			if len(token) != csrfTokenLength {
+				token = make([]byte, csrfTokenLength)
+				rand.Read(token)
+				http.SetCookie(w, &http.Cookie{
+					Name:     *config.CookieName,
+					Value:    base64.RawURLEncoding.EncodeToString(token),
+					Path:     *config.CookiePath,
+					Domain:   *config.CookieDomain,
+					MaxAge:   int(config.MaxAge.Seconds()),
+					Secure:   *config.Secure,
+					HttpOnly: *config.HTTPOnly,
+					SameSite: *config.SameSite,
+				})
+				w.Header().Add("Vary", "Cookie")
+			}
+
+			if !isSafeMethod(r.Method) {
+				submitted := r.Header.Get(*config.HeaderName)
+				if submitted == "" {
+					submitted = r.PostFormValue(*config.FormField)
+				}
+				if !validCSRFToken(token, submitted) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), csrfKey, token)
+			ctx = context.WithValue(ctx, csrfFormFieldKey, *config.FormField)
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+func isSafeMethod(m string) bool {
+	return m == http.MethodGet || m == http.MethodHead || m == http.MethodOptions || m == http.MethodTrace
+}
+
+func decodeCSRFToken(s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// validCSRFToken reports whether submitted matches token. Both the raw token, as
+// read from the cookie by scripts, and masked tokens from GetCSRFToken are accepted.
+func validCSRFToken(token []byte, submitted string) bool {
+	b := decodeCSRFToken(submitted)
+	switch len(b) {
+	case csrfTokenLength:
+	case 2 * csrfTokenLength:
+		b = unmaskCSRFToken(b)
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(token, b) == 1
+}
+
+// maskCSRFToken returns a random one-time pad followed by token XORed with the pad.
+func maskCSRFToken(token []byte) []byte {
+	masked := make([]byte, 2*len(token))
+	pad := masked[:len(token)]
+	rand.Read(pad)
+	for i, b := range token {
+		masked[len(token)+i] = b ^ pad[i]
+	}
+	return masked
+}
+
+func unmaskCSRFToken(masked []byte) []byte {
+	n := len(masked) / 2
+	token := make([]byte, n)
+	for i := range token {
+		token[i] = masked[i] ^ masked[n+i]
+	}
+	return token
+}
+
+// GetCSRFToken returns a masked CSRF token for the request, to be submitted in a
+// form field or header. It returns an empty string if the CSRF middleware did not run.
+func GetCSRFToken(r *http.Request) string {
+	token, ok := r.Context().Value(csrfKey).([]byte)
+	if !ok {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(maskCSRFToken(token))
+}
+
+// CSRFField returns a hidden input carrying the CSRF token, for use in HTML templates.
+//
+// Example:
+//
+//	tmpl.Execute(w, map[string]any{"csrfField": middleware.CSRFField(r)})
+//	// <form method="post">{{ .csrfField }}...</form>
+func CSRFField(r *http.Request) template.HTML {
+	token := GetCSRFToken(r)
+	if token == "" {
+		return ""
+	}
+	field, _ := r.Context().Value(csrfFormFieldKey).(string)
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(field) + `" value="` + token + `">`)
+}
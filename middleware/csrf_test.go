@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestCSRF(t *testing.T) {
+	var token string
+	handler := middleware.CSRF(middleware.CSRFConfig{
+		Skip: &[]string{"/webhook"},
+	})(func(w http.ResponseWriter, r *http.Request) {
+		token = middleware.GetCSRFToken(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected GET to pass, got %d", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" {
+		t.Fatalf("expected _csrf cookie, got %v", cookies)
+	}
+	cookie := cookies[0]
+	if cookie.SameSite != http.SameSiteLaxMode || !cookie.Secure {
+		t.Errorf("expected Secure SameSite=Lax cookie, got %v", cookie)
+	}
+	if token == "" || token == cookie.Value {
+		t.Fatalf("expected masked token, got %q", token)
+	}
+
+	post := func(path, header, field string, withCookie bool) int {
+		form := url.Values{}
+		if field != "" {
+			form.Set("_csrf", field)
+		}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if withCookie {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		header, field  string
+		withCookie     bool
+		expectedStatus int
+	}{
+		{"missing token", "/form", "", "", true, http.StatusForbidden},
+		{"missing cookie", "/form", token, "", false, http.StatusForbidden},
+		{"masked form field", "/form", "", token, true, http.StatusOK},
+		{"raw cookie value in header", "/form", cookie.Value, "", true, http.StatusOK},
+		{"wrong token", "/form", "AAAA" + cookie.Value[4:], "", true, http.StatusForbidden},
+		{"skipped path", "/webhook", "", "", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(tt.path, tt.header, tt.field, tt.withCookie); got != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, got)
+			}
+		})
+	}
+}
+
+func TestCSRFField(t *testing.T) {
+	var field string
+	handler := middleware.CSRF()(func(w http.ResponseWriter, r *http.Request) {
+		field = string(middleware.CSRFField(r))
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.HasPrefix(field, `<input type="hidden" name="_csrf" value="`) {
+		t.Errorf("unexpected field %q", field)
+	}
+	if got := middleware.CSRFField(httptest.NewRequest(http.MethodGet, "/", nil)); got != "" {
+		t.Errorf("expected empty field without middleware, got %q", got)
+	}
+}
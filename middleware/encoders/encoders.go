@@ -0,0 +1,47 @@
+// Package encoders provides brotli and zstd encoders for the Compress middleware.
+// They live in their own package so their dependencies are only linked into
+// programs that use them.
+//
+// Example:
+//
+//	app.Use(middleware.Compress(middleware.CompressConfig{
+//	    Encoders: &[]middleware.Encoder{
+//	        encoders.Zstd(zstd.SpeedDefault),
+//	        encoders.Brotli(brotli.DefaultCompression),
+//	        middleware.GzipEncoder(gzip.DefaultCompression),
+//	    },
+//	}))
+package encoders
+
+import (
+	"io"
+
+	"github.com/Juanfec4/velocity/middleware"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Brotli returns an Encoder for the br content coding at the given level, from
+// brotli.BestSpeed (0) to brotli.BestCompression (11).
+func Brotli(level int) middleware.Encoder {
+	return middleware.Encoder{Name: "br", New: func(w io.Writer) middleware.EncoderWriter {
+		return brotli.NewWriterLevel(w, level)
+	}}
+}
+
+// Zstd returns an Encoder for the zstd content coding at the given level, e.g.
+// zstd.SpeedDefault. The encoder runs synchronously without a window larger than
+// 8 MB, as RFC 9659 requires for HTTP.
+func Zstd(level zstd.EncoderLevel) middleware.Encoder {
+	return middleware.Encoder{Name: "zstd", New: func(w io.Writer) middleware.EncoderWriter {
+		zw, err := zstd.NewWriter(w,
+			zstd.WithEncoderLevel(level),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithWindowSize(8<<20),
+		)
+		if err != nil {
+			zw, _ = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		}
+		return zw
+	}}
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bufio"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETagConfig configures the ETag middleware.
+type ETagConfig struct {
+	// Weak makes generated ETags weak validators (W/"..."), for responses that are
+	// semantically equivalent but not byte-identical, e.g. after compression
+	Weak *bool
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultETagWeak = false
+var defaultETagConfig = ETagConfig{
+	Weak: &defaultETagWeak,
+}
+
+// ETag returns a middleware that buffers successful GET and HEAD responses, sets an
+// ETag header derived from a hash of the body and answers requests whose
+// If-None-Match matches it with 304 Not Modified and no body. ETags set by the
+// handler are kept and checked the same way. Responses that are flushed are sent
+// without an ETag, as they are streamed.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.ETag())
+//	// or with config
+//	router := app.Router("/api", middleware.ETag(middleware.ETagConfig{
+//	    Weak: boolPtr(true),
+//	}))
+func ETag(cfg ...ETagConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultETagConfig
+	if len(cfg) > 0 {
+		if cfg[0].Weak != nil {
+			config.Weak = cfg[0].Weak
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w}
+			next(ew, r)
+			if ew.passthrough {
+				return
+			}
+			if ew.status == 0 {
+				if len(ew.buf) == 0 {
+					return
+				}
+				ew.status = http.StatusOK
+			}
+
+			h := w.Header()
+			if ew.status == http.StatusOK {
+				etag := h.Get("ETag")
+				if etag == "" {
+					etag = computeETag(ew.buf, *config.Weak)
+					h.Set("ETag", etag)
+				}
+				if etagMatches(r.Header.Get("If-None-Match"), etag) {
+					h.Del("Content-Type")
+					h.Del("Content-Length")
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+			w.WriteHeader(ew.status)
+			w.Write(ew.buf)
+		}
+	}
+}
+
+// computeETag returns a quoted validator made of the body length and its FNV-1a hash.
+func computeETag(body []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(body)
+	etag := `"` + strconv.FormatInt(int64(len(body)), 16) + "-" + strconv.FormatUint(h.Sum64(), 16) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag using
+// the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter buffers a response until the handler returns, or passes it through
+// once the handler flushes.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         []byte
+	passthrough bool
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.passthrough {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code < 200 {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if ew.status == 0 {
+		ew.status = code
+	}
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(p)
+	}
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+	ew.buf = append(ew.buf, p...)
+	return len(p), nil
+}
+
+// Flush sends the buffered response and switches to passing writes through.
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		ew.passthrough = true
+		if ew.status != 0 {
+			ew.ResponseWriter.WriteHeader(ew.status)
+		}
+		if len(ew.buf) > 0 {
+			ew.ResponseWriter.Write(ew.buf)
+			ew.buf = nil
+		}
+	}
+	http.NewResponseController(ew.ResponseWriter).Flush()
+}
+
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	ew.passthrough = true
+	return http.NewResponseController(ew.ResponseWriter).Hijack()
+}
+
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
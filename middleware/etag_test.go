@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestETag(t *testing.T) {
+	handler := middleware.ETag()(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		io.WriteString(w, `{"id":1}`)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Fatalf("expected strong ETag, got %q", etag)
+	}
+	if rec.Body.String() != `{"id":1}` {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		ifNoneMatch    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"matching ETag", http.MethodGet, "/", etag, http.StatusNotModified, ""},
+		{"weak match", http.MethodGet, "/", `"other", W/` + etag, http.StatusNotModified, ""},
+		{"wildcard", http.MethodGet, "/", "*", http.StatusNotModified, ""},
+		{"stale ETag", http.MethodGet, "/", `"stale"`, http.StatusOK, `{"id":1}`},
+		{"error response", http.MethodGet, "/missing", etag, http.StatusNotFound, `{"id":1}`},
+		{"unsafe method", http.MethodPost, "/", etag, http.StatusOK, `{"id":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestETagConfig(t *testing.T) {
+	weak := true
+	handler := middleware.ETag(middleware.ETagConfig{Weak: &weak})(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if etag := rec.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("expected weak ETag, got %q", etag)
+	}
+
+	handler = middleware.ETag()(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		io.WriteString(w, "hello")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"v2"`)
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected handler ETag to be honored, got %d", rec.Code)
+	}
+}
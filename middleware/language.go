@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LanguageConfig configures the Language middleware.
+type LanguageConfig struct {
+	// QueryParam is a query parameter overriding Accept-Language, e.g. "lang".
+	// Empty disables the override.
+	QueryParam *string
+
+	// CookieName is a cookie overriding Accept-Language, e.g. one set by a language
+	// picker. Empty disables the override. The query parameter takes precedence.
+	CookieName *string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultLanguageQueryParam = ""
+var defaultLanguageCookieName = ""
+var defaultLanguageConfig = LanguageConfig{
+	QueryParam: &defaultLanguageQueryParam,
+	CookieName: &defaultLanguageCookieName,
+}
+
+var languageKey = struct {
+	name string
+}{name: "language"}
+
+// Language returns a middleware that picks the response language from supported
+// and stores it in the request context for GetLanguage. A supported query parameter
+// or cookie override wins; otherwise the languages in Accept-Language are tried in
+// order of their quality values, matching tags case-insensitively and falling back
+// from a regional tag to its base language and back, so "en-US" matches "en" and
+// "en" matches "en-GB". Without a match def is used. The chosen language is sent in
+// Content-Language, along with Vary: Accept-Language.
+//
+// Example:
+//
+//	app.Use(middleware.Language([]string{"en", "fr", "pt-BR"}, "en"))
+//	// or with overrides
+//	app.Use(middleware.Language([]string{"en", "fr", "pt-BR"}, "en", middleware.LanguageConfig{
+//	    QueryParam: stringPtr("lang"),
+//	    CookieName: stringPtr("lang"),
+//	}))
+//
+//	router.Get("/").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    fmt.Fprint(w, greetings[middleware.GetLanguage(r)])
+//	})
+func Language(supported []string, def string, cfg ...LanguageConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultLanguageConfig
+	if len(cfg) > 0 {
+		if cfg[0].QueryParam != nil {
+			config.QueryParam = cfg[0].QueryParam
+		}
+		if cfg[0].CookieName != nil {
+			config.CookieName = cfg[0].CookieName
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			lang := ""
+			if *config.QueryParam != "" {
+				lang = matchLanguage(supported, r.URL.Query().Get(*config.QueryParam))
+			}
+			if lang == "" && *config.CookieName != "" {
+				if c, err := r.Cookie(*config.CookieName); err == nil {
+					lang = matchLanguage(supported, c.Value)
+				}
+			}
+			if lang == "" {
+				lang = negotiateLanguage(supported, r.Header.Values("Accept-Language"))
+			}
+			if lang == "" {
+				lang = def
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Accept-Language")
+			h.Set("Content-Language", lang)
+			ctx := context.WithValue(r.Context(), languageKey, lang)
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// negotiateLanguage returns the supported language best matching the
+// Accept-Language header values, or an empty string if none matches.
+func negotiateLanguage(supported []string, values []string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(strings.Join(values, ","), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	// Stable, so equally weighted languages keep the client's order
+	slices.SortStableFunc(ranges, func(a, b weighted) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	for _, rng := range ranges {
+		if rng.tag == "*" {
+			continue
+		}
+		if lang := matchLanguage(supported, rng.tag); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// matchLanguage returns the supported language matching tag exactly, or else the
+// first one sharing its base language, or an empty string.
+func matchLanguage(supported []string, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	for _, s := range supported {
+		if strings.EqualFold(s, tag) {
+			return s
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, s := range supported {
+		if b, _, _ := strings.Cut(s, "-"); strings.EqualFold(b, base) {
+			return s
+		}
+	}
+	return ""
+}
+
+// GetLanguage returns the language chosen by the Language middleware, or an empty
+// string if the middleware did not run.
+func GetLanguage(r *http.Request) string {
+	lang, ok := r.Context().Value(languageKey).(string)
+	if !ok {
+		return ""
+	}
+	return lang
+}
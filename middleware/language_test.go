@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestLanguage(t *testing.T) {
+	supported := []string{"en", "fr", "pt-BR", "en-GB"}
+	lang := "lang"
+	withOverrides := middleware.Language(supported, "en", middleware.LanguageConfig{
+		QueryParam: &lang,
+		CookieName: &lang,
+	})
+
+	tests := []struct {
+		name     string
+		mw       func(http.HandlerFunc) http.HandlerFunc
+		target   string
+		accept   string
+		cookie   string
+		expected string
+	}{
+		{"no header", middleware.Language(supported, "en"), "/", "", "", "en"},
+		{"exact match", middleware.Language(supported, "en"), "/", "fr", "", "fr"},
+		{"case insensitive", middleware.Language(supported, "en"), "/", "PT-br", "", "pt-BR"},
+		{"quality order", middleware.Language(supported, "en"), "/", "de, fr;q=0.9, en;q=0.5", "", "fr"},
+		{"client order on ties", middleware.Language(supported, "fr"), "/", "pt-BR;q=0.8, en;q=0.8", "", "pt-BR"},
+		{"region falls back to base", middleware.Language(supported, "fr"), "/", "en-US", "", "en"},
+		{"base matches region", middleware.Language(supported, "en"), "/", "pt", "", "pt-BR"},
+		{"zero quality excluded", middleware.Language(supported, "en"), "/", "fr;q=0, de", "", "en"},
+		{"wildcard uses default", middleware.Language(supported, "fr"), "/", "*", "", "fr"},
+		{"query override", withOverrides, "/?lang=fr", "en", "pt-BR", "fr"},
+		{"cookie override", withOverrides, "/", "en", "pt-BR", "pt-BR"},
+		{"unsupported override ignored", withOverrides, "/?lang=de", "fr", "", "fr"},
+		{"overrides disabled by default", middleware.Language(supported, "en"), "/?lang=fr", "", "fr", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			var got string
+			tt.mw(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.GetLanguage(r)
+			})(rec, req)
+
+			if got != tt.expected {
+				t.Errorf("expected language %q, got %q", tt.expected, got)
+			}
+			if cl := rec.Header().Get("Content-Language"); cl != tt.expected {
+				t.Errorf("expected Content-Language %q, got %q", tt.expected, cl)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Language" {
+				t.Errorf("expected Vary %q, got %q", "Accept-Language", vary)
+			}
+		})
+	}
+
+	if got := middleware.GetLanguage(httptest.NewRequest(http.MethodGet, "/", nil)); got != "" {
+		t.Errorf("expected empty language without middleware, got %q", got)
+	}
+}
@@ -1,11 +1,21 @@
 package middleware
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Juanfec4/velocity"
 )
 
 // LoggerConfig configures the Logger middleware.
@@ -22,6 +32,26 @@ type LoggerConfig struct {
 
 	// Colors enables colored output
 	Colors *bool
+
+	// SampleRate is the fraction of successful requests that are logged, from 0 to 1.
+	// Requests with 4xx and 5xx responses and slow requests are always logged.
+	SampleRate *float64
+
+	// SlowThreshold marks requests taking at least this long as slow: they are always
+	// logged, tagged with slow=true and, with Slog, logged at warn level or above.
+	// Zero disables it.
+	SlowThreshold *time.Duration
+
+	// Output selects the line format. FormatText uses Format; FormatJSON,
+	// FormatCombined and FormatCommon write fixed formats without colors.
+	Output *LogFormat
+
+	// Slog switches to structured logging: each request is logged as a record on this
+	// logger instead of a formatted line, and Format, Logger and Colors are ignored
+	Slog *slog.Logger
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
 }
 
 const (
@@ -36,6 +66,23 @@ const (
 	Gray   = "\033[37m"
 )
 
+// LogFormat is an output format of the Logger middleware.
+type LogFormat int
+
+const (
+	// FormatText writes lines using the Format string
+	FormatText LogFormat = iota
+
+	// FormatJSON writes one JSON object per request
+	FormatJSON
+
+	// FormatCombined writes the Apache/NGINX Combined Log Format
+	FormatCombined
+
+	// FormatCommon writes the Apache Common Log Format
+	FormatCommon
+)
+
 var supportsColors = false
 
 func init() {
@@ -47,11 +94,17 @@ func init() {
 }
 
 var defaultLoggerFormat = "[%s] %s %s %s %s %v"
+var defaultLoggerOutput = FormatText
+var defaultLoggerSampleRate = 1.0
+var defaultLoggerSlowThreshold = time.Duration(0)
 var defaultLoggerConfig = LoggerConfig{
-	Format: &defaultLoggerFormat,
-	Skip:   &[]string{},
-	Logger: nil,
-	Colors: &supportsColors,
+	Format:        &defaultLoggerFormat,
+	Skip:          &[]string{},
+	Logger:        nil,
+	Colors:        &supportsColors,
+	Output:        &defaultLoggerOutput,
+	SampleRate:    &defaultLoggerSampleRate,
+	SlowThreshold: &defaultLoggerSlowThreshold,
 }
 
 // Logger returns a middleware that logs HTTP requests.
@@ -64,6 +117,10 @@ var defaultLoggerConfig = LoggerConfig{
 //	    Colors: boolPtr(true),
 //	    Skip: &[]string{"/health"},
 //	}))
+//	// or structured, with request_id and client_ip when RequestID and ClientIP run first
+//	router := app.Router("/api", middleware.RequestID(), middleware.Logger(middleware.LoggerConfig{
+//	    Slog: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+//	}))
 func Logger(cfg ...LoggerConfig) func(next http.HandlerFunc) http.HandlerFunc {
 	config := defaultLoggerConfig
 	if len(cfg) > 0 {
@@ -79,10 +136,34 @@ func Logger(cfg ...LoggerConfig) func(next http.HandlerFunc) http.HandlerFunc {
 		if cfg[0].Colors != nil {
 			config.Colors = cfg[0].Colors
 		}
+		if cfg[0].Slog != nil {
+			config.Slog = cfg[0].Slog
+		}
+		if cfg[0].Output != nil {
+			config.Output = cfg[0].Output
+		}
+		if cfg[0].SampleRate != nil {
+			config.SampleRate = cfg[0].SampleRate
+		}
+		if cfg[0].SlowThreshold != nil {
+			config.SlowThreshold = cfg[0].SlowThreshold
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	plainLogger := config.Logger
+	if plainLogger == nil {
+		// Fixed formats must not be prefixed with the standard logger's timestamp
+		plainLogger = log.New(os.Stdout, "", 0)
 	}
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
 			if contains(*config.Skip, r.URL.Path) {
 				next(w, r)
 				return
@@ -90,15 +171,41 @@ func Logger(cfg ...LoggerConfig) func(next http.HandlerFunc) http.HandlerFunc {
 
 			start := time.Now()
 			rw := &responseWriter{ResponseWriter: w}
+			if r.Body != nil && r.Body != http.NoBody {
+				rw.body = &countingReader{ReadCloser: r.Body}
+				r.Body = rw.body
+			}
 			next(rw, r)
 			duration := time.Since(start)
 
+			slow := *config.SlowThreshold > 0 && duration >= *config.SlowThreshold
+			if !slow && statusOrOK(rw.status) < 400 && *config.SampleRate < 1 && rand.Float64() >= *config.SampleRate {
+				return
+			}
+
+			if config.Slog != nil {
+				logRecord(config.Slog, r, rw, duration, slow)
+				return
+			}
+
+			switch *config.Output {
+			case FormatJSON:
+				plainLogger.Print(jsonLine(r, rw, start, duration, slow))
+				return
+			case FormatCombined:
+				plainLogger.Print(commonLine(r, rw, start) + ` "` + escapeQuotes(r.Referer()) + `" "` + escapeQuotes(r.UserAgent()) + `"`)
+				return
+			case FormatCommon:
+				plainLogger.Print(commonLine(r, rw, start))
+				return
+			}
+
 			logger := config.Logger
 			if logger == nil {
 				logger = log.Default()
 			}
 
-			logger.Printf(*config.Format,
+			line := fmt.Sprintf(*config.Format,
 				formatString(Gray, time.Now().Format(time.RFC3339), *config.Colors),
 				colorMethod(r.Method, *config.Colors),
 				formatString(Bold, r.URL.Path, *config.Colors),
@@ -106,13 +213,123 @@ func Logger(cfg ...LoggerConfig) func(next http.HandlerFunc) http.HandlerFunc {
 				colorStatus(rw.status, *config.Colors),
 				formatString(Gray, duration.String(), *config.Colors),
 			)
+			fields := velocity.LogFields(r)
+			if slow {
+				fields = append(fields, velocity.LogAttr{Key: "slow", Value: true})
+			}
+			logger.Print(line + formatFields(fields, *config.Colors))
 		}
 	}
 }
 
+// logRecord logs a request as a structured record, at error level for 5xx
+// responses, warn level for 4xx responses and slow requests and info level otherwise.
+func logRecord(logger *slog.Logger, r *http.Request, rw *responseWriter, duration time.Duration, slow bool) {
+	status := statusOrOK(rw.status)
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400 || slow:
+		level = slog.LevelWarn
+	}
+
+	clientIP := GetClientIP(r)
+	if clientIP == "" {
+		clientIP, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("route", velocity.GetRoutePattern(r)),
+		slog.Int("status", status),
+		slog.Int64("bytes", rw.bytes),
+		slog.Int64("request_bytes", rw.requestBytes(r)),
+		slog.Duration("duration", duration),
+		slog.String("client_ip", clientIP),
+	}
+	if id := GetRequestID(r); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	for _, f := range velocity.LogFields(r) {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	logger.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+// commonLine formats a request in the Common Log Format:
+// host ident authuser [date] "request line" status bytes
+func commonLine(r *http.Request, rw *responseWriter, start time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if u := GetBasicAuthUser(r); u != "" {
+		user = escapeQuotes(u)
+	}
+	size := "-"
+	if rw.bytes > 0 {
+		size = strconv.FormatInt(rw.bytes, 10)
+	}
+	return host + " - " + user +
+		" [" + start.Format("02/Jan/2006:15:04:05 -0700") + `] "` +
+		escapeQuotes(r.Method+" "+r.RequestURI+" "+r.Proto) + `" ` +
+		strconv.Itoa(statusOrOK(rw.status)) + " " + size
+}
+
+// jsonLine formats a request as a JSON object, including the request's log fields.
+func jsonLine(r *http.Request, rw *responseWriter, start time.Time, duration time.Duration, slow bool) string {
+	entry := map[string]any{
+		"time":          start.Format(time.RFC3339),
+		"method":        r.Method,
+		"path":          r.URL.Path,
+		"route":         velocity.GetRoutePattern(r),
+		"status":        statusOrOK(rw.status),
+		"bytes":         rw.bytes,
+		"request_bytes": rw.requestBytes(r),
+		"duration_ms":   float64(duration) / float64(time.Millisecond),
+		"remote_addr":   r.RemoteAddr,
+		"referer":       r.Referer(),
+		"user_agent":    r.UserAgent(),
+	}
+	if id := GetRequestID(r); id != "" {
+		entry["request_id"] = id
+	}
+	if slow {
+		entry["slow"] = true
+	}
+	for _, f := range velocity.LogFields(r) {
+		entry[f.Key] = f.Value
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(b)
+}
+
+func escapeQuotes(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+func statusOrOK(status int) int {
+	if status == 0 {
+		return http.StatusOK
+	}
+	return status
+}
+
+// responseWriter records the status and size of a response and, through body,
+// the size of the request body read by the handler.
 type responseWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
+	body   *countingReader
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -124,7 +341,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	if rw.status == 0 {
 		rw.status = http.StatusOK
 	}
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
 }
 
 func colorStatus(code int, useColors bool) string {
@@ -163,9 +382,67 @@ func colorMethod(method string, useColors bool) string {
 	}
 }
 
+func formatFields(fields []velocity.LogAttr, useColors bool) string {
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(formatString(Cyan, f.Key, useColors))
+		b.WriteByte('=')
+		b.WriteString(fmt.Sprint(f.Value))
+	}
+	return b.String()
+}
+
 func formatString(color, s string, useColors bool) string {
 	if !useColors {
 		return s
 	}
 	return color + s + Reset
 }
+
+func (rw *responseWriter) Flush() {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && rw.status == 0 {
+		// The handler takes over the connection, e.g. for a WebSocket upgrade
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// requestBytes returns the number of request body bytes read by the handler, or the
+// declared Content-Length if the body was not read.
+func (rw *responseWriter) requestBytes(r *http.Request) int64 {
+	if rw.body != nil && rw.body.n > 0 {
+		return rw.body.n
+	}
+	return max(r.ContentLength, 0)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,265 @@
+package middleware_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestLoggerFields(t *testing.T) {
+	var buf bytes.Buffer
+	colors := false
+
+	app := velocity.New()
+	// Registered outside the logger, so it runs before the logger does
+	app.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			velocity.LogField(r, "region", "eu")
+			next(w, r)
+		}
+	})
+	router := app.Router("/",
+		middleware.Logger(middleware.LoggerConfig{
+			Logger: log.New(&buf, "", 0),
+			Colors: &colors,
+		}),
+		func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				velocity.LogField(r, "tenant", "acme")
+				next(w, r)
+			}
+		},
+		func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				velocity.LogField(r, "user", 42)
+				next(w, r)
+			}
+		},
+	)
+	router.Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	for _, field := range []string{"region=eu", "tenant=acme", "user=42"} {
+		if !strings.Contains(line, field) {
+			t.Errorf("expected log line to contain %q, got %q", field, line)
+		}
+	}
+}
+
+func TestLoggerSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	app := velocity.New()
+	router := app.Router("/",
+		middleware.RequestID(),
+		middleware.Logger(middleware.LoggerConfig{Slog: logger}),
+		func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				velocity.LogField(r, "tenant", "acme")
+				next(w, r)
+			}
+		},
+	)
+	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.RemoteAddr = "192.0.2.1:1234"
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]any{
+		"level":      "WARN",
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/users/7",
+		"route":      "/users/:id",
+		"status":     float64(404),
+		"bytes":      float64(9),
+		"client_ip":  "192.0.2.1",
+		"request_id": "req-1",
+		"tenant":     "acme",
+	}
+	for key, want := range expected {
+		if record[key] != want {
+			t.Errorf("expected %s=%v, got %v", key, want, record[key])
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("expected duration attribute")
+	}
+}
+
+func TestLoggerOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   middleware.LogFormat
+		expected string
+	}{
+		{"common", middleware.FormatCommon, `192.0.2.1 - - [`},
+		{"combined", middleware.FormatCombined, `] "GET /users?page=2 HTTP/1.1" 201 5 "https://example.com/" "test-agent"`},
+		{"json", middleware.FormatJSON, `"user_agent":"test-agent"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := middleware.Logger(middleware.LoggerConfig{
+				Logger: log.New(&buf, "", 0),
+				Output: &tt.output,
+			})(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("hello"))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users?page=2", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("Referer", "https://example.com/")
+			req.Header.Set("User-Agent", "test-agent")
+			handler(httptest.NewRecorder(), req)
+
+			line := buf.String()
+			if !strings.Contains(line, tt.expected) {
+				t.Errorf("expected log line to contain %q, got %q", tt.expected, line)
+			}
+			if tt.output == middleware.FormatJSON {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("expected JSON line, got %q: %v", line, err)
+				}
+				if entry["status"] != float64(201) || entry["bytes"] != float64(5) || entry["referer"] != "https://example.com/" {
+					t.Errorf("unexpected entry %v", entry)
+				}
+			}
+		})
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c1, c2 := net.Pipe()
+	c2.Close()
+	return c1, bufio.NewReadWriter(bufio.NewReader(c1), bufio.NewWriter(c1)), nil
+}
+
+func TestLoggerSizesAndWriter(t *testing.T) {
+	var buf bytes.Buffer
+	output := middleware.FormatJSON
+	mw := middleware.Logger(middleware.LoggerConfig{
+		Logger: log.New(&buf, "", 0),
+		Output: &output,
+	})
+
+	entry := func() map[string]any {
+		t.Helper()
+		var e map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+			t.Fatalf("expected JSON line, got %q: %v", buf.String(), err)
+		}
+		buf.Reset()
+		return e
+	}
+
+	// Request and response sizes
+	handler := mw(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body[:4])
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello world")))
+	if e := entry(); e["request_bytes"] != float64(11) || e["bytes"] != float64(4) {
+		t.Errorf("expected request_bytes 11 and bytes 4, got %v and %v", e["request_bytes"], e["bytes"])
+	}
+
+	// Flush is forwarded
+	rec := httptest.NewRecorder()
+	mw(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+	})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	entry()
+	if !rec.Flushed {
+		t.Error("expected Flush to reach the underlying writer")
+	}
+
+	// Hijack is forwarded and logged as a protocol switch
+	mw(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Fatalf("expected Hijack to be forwarded, got %v", err)
+		}
+		conn.Close()
+	})(hijackRecorder{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if e := entry(); e["status"] != float64(http.StatusSwitchingProtocols) {
+		t.Errorf("expected status 101 for hijacked connection, got %v", e["status"])
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	colors := false
+	rate := 0.0
+	threshold := 20 * time.Millisecond
+	handler := middleware.Logger(middleware.LoggerConfig{
+		Logger:        log.New(&buf, "", 0),
+		Colors:        &colors,
+		SampleRate:    &rate,
+		SlowThreshold: &threshold,
+	})(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		path       string
+		logged     bool
+		taggedSlow bool
+	}{
+		{"/ok", false, false},
+		{"/error", true, false},
+		{"/slow", true, true},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		line := buf.String()
+		if logged := line != ""; logged != tt.logged {
+			t.Errorf("%s: expected logged=%v, got %q", tt.path, tt.logged, line)
+		}
+		if slow := strings.Contains(line, "slow=true"); slow != tt.taggedSlow {
+			t.Errorf("%s: expected slow tag=%v, got %q", tt.path, tt.taggedSlow, line)
+		}
+	}
+}
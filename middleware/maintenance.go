@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+// MaintenanceConfig configures the Maintenance middleware.
+type MaintenanceConfig struct {
+	// Allow defines paths served during maintenance, such as health checks or the
+	// admin endpoint flipping the toggle. Entries ending in "*" match a prefix.
+	Allow *[]string
+
+	// RetryAfter is sent in the Retry-After header
+	RetryAfter *time.Duration
+
+	// Body is the maintenance page
+	Body *string
+
+	// ContentType is the Content-Type of the maintenance page
+	ContentType *string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultMaintenanceRetryAfter = 5 * time.Minute
+var defaultMaintenanceBody = "Service temporarily unavailable for maintenance"
+var defaultMaintenanceContentType = "text/plain; charset=utf-8"
+var defaultMaintenanceConfig = MaintenanceConfig{
+	Allow:       &[]string{},
+	RetryAfter:  &defaultMaintenanceRetryAfter,
+	Body:        &defaultMaintenanceBody,
+	ContentType: &defaultMaintenanceContentType,
+}
+
+// Maintenance returns a middleware that answers requests with 503 Service
+// Unavailable, a Retry-After header and the maintenance page while toggle is
+// enabled. Paths in Allow are served normally. The toggle can be flipped at runtime,
+// e.g. from an admin endpoint or with Toggle.FlipOnSignal, without redeploying.
+//
+// Example:
+//
+//	var maintenance velocity.Toggle
+//	app.Use(middleware.Maintenance(&maintenance, middleware.MaintenanceConfig{
+//	    Allow: &[]string{"/health", "/admin/*"},
+//	}))
+//	admin.Put("/admin/maintenance").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    maintenance.Enable()
+//	})
+func Maintenance(toggle *velocity.Toggle, cfg ...MaintenanceConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultMaintenanceConfig
+	if len(cfg) > 0 {
+		if cfg[0].Allow != nil {
+			config.Allow = cfg[0].Allow
+		}
+		if cfg[0].RetryAfter != nil {
+			config.RetryAfter = cfg[0].RetryAfter
+		}
+		if cfg[0].Body != nil {
+			config.Body = cfg[0].Body
+		}
+		if cfg[0].ContentType != nil {
+			config.ContentType = cfg[0].ContentType
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	retryAfter := strconv.Itoa(int(config.RetryAfter.Seconds()))
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			if !toggle.Enabled() || pathAllowed(*config.Allow, r.URL.Path) {
+				next(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("Content-Type", *config.ContentType)
+			h.Set("Retry-After", retryAfter)
+			h.Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(*config.Body))
+		}
+	}
+}
+
+// pathAllowed reports whether path matches one of allow, where entries ending in
+// "*" match any path with that prefix.
+func pathAllowed(allow []string, path string) bool {
+	for _, a := range allow {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if a == path {
+			return true
+		}
+	}
+	return false
+}
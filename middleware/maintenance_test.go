@@ -0,0 +1,52 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestMaintenance(t *testing.T) {
+	var toggle velocity.Toggle
+	handler := middleware.Maintenance(&toggle, middleware.MaintenanceConfig{
+		Allow: &[]string{"/health", "/admin/*"},
+	})(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		path           string
+		expectedStatus int
+	}{
+		{"disabled", false, "/users", http.StatusOK},
+		{"enabled", true, "/users", http.StatusServiceUnavailable},
+		{"allowed path", true, "/health", http.StatusOK},
+		{"allowed prefix", true, "/admin/maintenance", http.StatusOK},
+		{"toggled back off", false, "/users", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toggle.Set(tt.enabled)
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusServiceUnavailable {
+				if rec.Header().Get("Retry-After") != "300" {
+					t.Errorf("expected Retry-After 300, got %q", rec.Header().Get("Retry-After"))
+				}
+				if rec.Body.String() != "Service temporarily unavailable for maintenance" {
+					t.Errorf("unexpected body %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MaxInFlightConfig configures the MaxInFlight middleware.
+type MaxInFlightConfig struct {
+	// Skipper skips the middleware for requests it returns true for, e.g. health
+	// checks that must be answered under load
+	Skipper Skipper
+}
+
+// MaxInFlight returns a middleware that runs at most n handlers at a time. Requests
+// over the limit wait in a queue of up to queueLen requests for at most queueTimeout;
+// requests that find the queue full or time out are rejected with 503 Service
+// Unavailable and a Retry-After header, shedding load instead of piling it onto
+// downstream services. Requests whose client disconnects while queued are dropped.
+// n must be positive, MaxInFlight panics otherwise; a queueLen of 0 rejects requests
+// over the limit right away.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.MaxInFlight(100, 50, 500*time.Millisecond))
+//	// or with config
+//	app.Use(middleware.MaxInFlight(100, 50, 500*time.Millisecond, middleware.MaxInFlightConfig{
+//	    Skipper: middleware.SkipPaths("/health"),
+//	}))
+func MaxInFlight(n, queueLen int, queueTimeout time.Duration, cfg ...MaxInFlightConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	if n <= 0 {
+		panic("velocity: MaxInFlight limit must be positive, got " + strconv.Itoa(n))
+	}
+	var config MaxInFlightConfig
+	if len(cfg) > 0 {
+		config.Skipper = cfg[0].Skipper
+	}
+	sem := make(chan struct{}, n)
+	var queued atomic.Int64
+	retryAfter := strconv.Itoa(max(int((queueTimeout+time.Second-1)/time.Second), 1))
+
+	reject := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", retryAfter)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			default:
+				if queued.Add(1) > int64(queueLen) {
+					queued.Add(-1)
+					reject(w)
+					return
+				}
+				timer := time.NewTimer(queueTimeout)
+				select {
+				case sem <- struct{}{}:
+					timer.Stop()
+					queued.Add(-1)
+				case <-timer.C:
+					queued.Add(-1)
+					reject(w)
+					return
+				case <-r.Context().Done():
+					timer.Stop()
+					queued.Add(-1)
+					return
+				}
+			}
+			defer func() { <-sem }()
+			next(w, r)
+		}
+	}
+}
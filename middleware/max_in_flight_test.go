@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	config := middleware.MaxInFlightConfig{Skipper: middleware.SkipPaths("/health")}
+	handler := middleware.MaxInFlight(1, 1, 50*time.Millisecond, config)(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			return
+		}
+		started <- struct{}{}
+		<-release
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	// The first request occupies the only slot
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve()
+	}()
+	<-started
+
+	// The second request waits in the queue, the third finds it full
+	queuedResult := make(chan int, 1)
+	go func() { queuedResult <- serve().Code }()
+	time.Sleep(10 * time.Millisecond)
+
+	rec := serve()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when the queue is full, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Skipped requests bypass the limit
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected skipped request to be served, got %d", rec.Code)
+	}
+
+	// The queued request times out while the slot stays busy
+	if code := <-queuedResult; code != http.StatusServiceUnavailable {
+		t.Errorf("expected queued request to time out with 503, got %d", code)
+	}
+
+	// Once the slot is free, a queued request gets it
+	go func() { queuedResult <- serve().Code }()
+	time.Sleep(10 * time.Millisecond)
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	if code := <-queuedResult; code != http.StatusOK {
+		t.Errorf("expected queued request to run once a slot frees up, got %d", code)
+	}
+	wg.Wait()
+}
+
+func TestMaxInFlightInvalidLimit(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for limit %d", n)
+				}
+			}()
+			middleware.MaxInFlight(n, 1, time.Second)
+		}()
+	}
+}
@@ -7,6 +7,21 @@ Available Middlewares:
   - RequestID: Request ID tracking
   - ClientIP: Client IP detection
   - ErrRecover: Panic recovery
+  - ContentTypeBodyLimit: Request body size limits per Content-Type
+  - CanonicalHost: Redirect to a canonical host
+  - Compress: Response compression with pluggable encoders (see package encoders for brotli and zstd)
+  - BasicAuth: HTTP Basic authentication
+  - CSRF: Cross-site request forgery protection
+  - Timeout: Per-request deadlines with a timeout response
+  - ETag: ETag generation and If-None-Match handling
+  - Cache: Response caching with pluggable stores
+  - MaxInFlight: Concurrency limiting with a bounded wait queue
+  - Maintenance: Maintenance mode controlled by a runtime toggle
+  - AllowContentType, RequireAccept: Content-Type and Accept enforcement
+  - Language: Accept-Language negotiation with query and cookie overrides
+  - RequireRole, RequirePermission: Role-based access control with pluggable authorizers
+  - When, Unless: Apply a middleware conditionally
+  - RateLimit: Fixed-window rate limiting with pluggable stores (see package redisstore)
 
 Usage:
 
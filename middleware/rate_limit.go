@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitStore counts requests per key in fixed windows. Implementations must be
+// safe for concurrent use; a store shared between replicas, such as the one in
+// package redisstore, enforces a limit across all of them.
+type RateLimitStore interface {
+	// Increment records a request for key and returns the number of requests in the
+	// current window, including this one, and the time the window resets. A new
+	// window of length window starts with the first request after the last one ended.
+	Increment(ctx context.Context, key string, window time.Duration) (count int, reset time.Time, err error)
+}
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	// Max is the number of requests allowed per key in each window
+	Max *int
+
+	// Window is the length of a rate limit window
+	Window *time.Duration
+
+	// Store counts requests. Defaults to an in-memory store local to the process.
+	Store RateLimitStore
+
+	// KeyFunc returns the key requests are counted under. Defaults to the client IP
+	// set by the ClientIP middleware, or the remote address.
+	KeyFunc func(r *http.Request) string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultRateLimitMax = 100
+var defaultRateLimitWindow = time.Minute
+var defaultRateLimitConfig = RateLimitConfig{
+	Max:     &defaultRateLimitMax,
+	Window:  &defaultRateLimitWindow,
+	KeyFunc: rateLimitKey,
+}
+
+// RateLimit returns a middleware that allows at most Max requests per key in each
+// Window and rejects the rest with 429 Too Many Requests and a Retry-After header.
+// Every response carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers. If the store fails, e.g. because Redis is unreachable, the request is
+// allowed rather than failing the whole service.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.RateLimit())
+//	// or with config
+//	router := app.Router("/api", middleware.RateLimit(middleware.RateLimitConfig{
+//	    Max:    intPtr(10),
+//	    Window: durationPtr(time.Second),
+//	    Store:  redisstore.NewRateLimitStore(client, "ratelimit:"),
+//	}))
+func RateLimit(cfg ...RateLimitConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultRateLimitConfig
+	if len(cfg) > 0 {
+		if cfg[0].Max != nil {
+			config.Max = cfg[0].Max
+		}
+		if cfg[0].Window != nil {
+			config.Window = cfg[0].Window
+		}
+		if cfg[0].Store != nil {
+			config.Store = cfg[0].Store
+		}
+		if cfg[0].KeyFunc != nil {
+			config.KeyFunc = cfg[0].KeyFunc
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	limit := strconv.Itoa(*config.Max)
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			count, reset, err := config.Store.Increment(r.Context(), config.KeyFunc(r), *config.Window)
+			if err != nil {
+				next(w, r)
+				return
+			}
+
+			resetIn := max(int(time.Until(reset).Round(time.Second)/time.Second), 0)
+			h := w.Header()
+			h.Set("RateLimit-Limit", limit)
+			h.Set("RateLimit-Remaining", strconv.Itoa(max(*config.Max-count, 0)))
+			h.Set("RateLimit-Reset", strconv.Itoa(resetIn))
+
+			if count > *config.Max {
+				h.Set("Retry-After", strconv.Itoa(resetIn))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+func rateLimitKey(r *http.Request) string {
+	if ip := GetClientIP(r); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// MemoryRateLimitStore is a RateLimitStore that keeps counters in memory. Limits
+// are enforced per process; use a shared store when running several replicas.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*rateLimitWindow
+	nextSweep time.Time
+}
+
+type rateLimitWindow struct {
+	count int
+	reset time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateLimitWindow)}
+}
+
+// Increment implements RateLimitStore. Expired windows are removed at most once
+// per window length, so idle keys do not accumulate.
+func (s *MemoryRateLimitStore) Increment(_ context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, win := range s.windows {
+			if !now.Before(win.reset) {
+				delete(s.windows, k)
+			}
+		}
+		s.nextSweep = now.Add(window)
+	}
+
+	win, ok := s.windows[key]
+	if !ok || !now.Before(win.reset) {
+		win = &rateLimitWindow{reset: now.Add(window)}
+		s.windows[key] = win
+	}
+	win.count++
+	return win.count, win.reset, nil
+}
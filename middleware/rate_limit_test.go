@@ -0,0 +1,87 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := 2
+	window := time.Hour
+	mw := middleware.RateLimit(middleware.RateLimitConfig{Max: &limit, Window: &window})
+	handler := mw(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		remoteAddr        string
+		expectedStatus    int
+		expectedRemaining string
+	}{
+		{"192.0.2.1:1234", http.StatusOK, "1"},
+		{"192.0.2.1:1235", http.StatusOK, "0"},
+		{"192.0.2.1:1236", http.StatusTooManyRequests, "0"},
+		{"192.0.2.2:1234", http.StatusOK, "1"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != tt.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.remoteAddr, tt.expectedStatus, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != tt.expectedRemaining {
+			t.Errorf("%s: expected RateLimit-Remaining %q, got %q", tt.remoteAddr, tt.expectedRemaining, got)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("%s: expected RateLimit-Limit 2, got %q", tt.remoteAddr, got)
+		}
+		if tt.expectedStatus == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "3600" {
+			t.Errorf("%s: expected Retry-After 3600, got %q", tt.remoteAddr, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	store.Increment(ctx, "k", 20*time.Millisecond)
+	if count, _, _ := store.Increment(ctx, "k", 20*time.Millisecond); count != 2 {
+		t.Fatalf("expected count 2, got %d", count)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if count, _, _ := store.Increment(ctx, "k", 20*time.Millisecond); count != 1 {
+		t.Errorf("expected count to restart at 1 after the window, got %d", count)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, time.Duration) (int, time.Time, error) {
+	return 0, time.Time{}, errors.New("unavailable")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	mw := middleware.RateLimit(middleware.RateLimitConfig{
+		Store:   failingStore{},
+		KeyFunc: func(r *http.Request) string { return r.Header.Get("X-API-Key") },
+	})
+	rec := httptest.NewRecorder()
+	mw(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected request to be allowed when the store fails, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Juanfec4/velocity"
+)
+
+// Principal is an authenticated user or client along with what it may do. It is
+// stored in the request context by authentication middleware with WithPrincipal.
+type Principal struct {
+	// ID identifies the principal, e.g. a user ID or API key name
+	ID string
+
+	// Roles are the roles granted to the principal, e.g. "admin"
+	Roles []string
+
+	// Permissions are the permissions granted to the principal, e.g. "users:write"
+	Permissions []string
+}
+
+// Authorizer decides whether the principal of a request holds a role or permission.
+// Implementations typically read the principal from the request context, e.g. from
+// verified token claims.
+type Authorizer interface {
+	HasRole(r *http.Request, role string) bool
+	HasPermission(r *http.Request, permission string) bool
+}
+
+// PrincipalAuthorizer is the Authorizer checking the Principal stored with
+// WithPrincipal. Requests without a principal hold no roles or permissions.
+type PrincipalAuthorizer struct{}
+
+// HasRole reports whether the request's principal has role.
+func (PrincipalAuthorizer) HasRole(r *http.Request, role string) bool {
+	p := GetPrincipal(r)
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasPermission reports whether the request's principal has permission.
+func (PrincipalAuthorizer) HasPermission(r *http.Request, permission string) bool {
+	p := GetPrincipal(r)
+	return p != nil && slices.Contains(p.Permissions, permission)
+}
+
+var principalKey = struct {
+	name string
+}{name: "principal"}
+
+// WithPrincipal returns a shallow copy of r carrying p, for authentication
+// middleware to pass the principal on to RequireRole and RequirePermission.
+//
+// Example:
+//
+//	func Auth(next http.HandlerFunc) http.HandlerFunc {
+//	    return func(w http.ResponseWriter, r *http.Request) {
+//	        claims, err := verify(r.Header.Get("Authorization"))
+//	        if err != nil {
+//	            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+//	            return
+//	        }
+//	        next(w, middleware.WithPrincipal(r, &middleware.Principal{ID: claims.Subject, Roles: claims.Roles}))
+//	    }
+//	}
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey, p))
+}
+
+// GetPrincipal returns the principal stored with WithPrincipal, or nil.
+func GetPrincipal(r *http.Request) *Principal {
+	p, _ := r.Context().Value(principalKey).(*Principal)
+	return p
+}
+
+// RBACConfig configures the middleware created by an RBAC.
+type RBACConfig struct {
+	// Skipper skips the role and permission checks for requests it returns true for
+	Skipper Skipper
+}
+
+// RBAC creates role and permission middleware backed by an Authorizer.
+type RBAC struct {
+	authorizer Authorizer
+	config     RBACConfig
+}
+
+// NewRBAC returns an RBAC checking requests with authorizer.
+//
+// Example:
+//
+//	rbac := middleware.NewRBAC(claimsAuthorizer{})
+//	router.Delete("/users/:id", rbac.RequirePermission("users:delete")).Handle(deleteUser)
+//	// or with config
+//	rbac := middleware.NewRBAC(middleware.PrincipalAuthorizer{}, middleware.RBACConfig{
+//	    Skipper: middleware.SkipPaths("/admin/health"),
+//	})
+func NewRBAC(authorizer Authorizer, cfg ...RBACConfig) *RBAC {
+	rb := &RBAC{authorizer: authorizer}
+	if len(cfg) > 0 {
+		rb.config.Skipper = cfg[0].Skipper
+	}
+	return rb
+}
+
+func (rb *RBAC) skip(r *http.Request) bool {
+	return rb.config.Skipper != nil && rb.config.Skipper(r)
+}
+
+var defaultRBAC = NewRBAC(PrincipalAuthorizer{})
+
+// RequireRole returns a middleware that lets requests through whose principal has
+// at least one of roles, and rejects others with 403 Forbidden as
+// application/problem+json.
+func (rb *RBAC) RequireRole(roles ...string) func(next http.HandlerFunc) http.HandlerFunc {
+	detail := "Requires role " + strings.Join(roles, " or ")
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if rb.skip(r) {
+				next(w, r)
+				return
+			}
+			for _, role := range roles {
+				if rb.authorizer.HasRole(r, role) {
+					next(w, r)
+					return
+				}
+			}
+			forbidden(w, r, detail)
+		}
+	}
+}
+
+// RequirePermission returns a middleware that lets requests through whose principal
+// has all of permissions, and rejects others with 403 Forbidden as
+// application/problem+json.
+func (rb *RBAC) RequirePermission(permissions ...string) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if rb.skip(r) {
+				next(w, r)
+				return
+			}
+			for _, permission := range permissions {
+				if !rb.authorizer.HasPermission(r, permission) {
+					forbidden(w, r, "Requires permission "+permission)
+					return
+				}
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireRole returns a middleware that lets requests through whose Principal, set
+// with WithPrincipal, has at least one of roles. Others are rejected with 403
+// Forbidden as application/problem+json. Use NewRBAC for a custom Authorizer or a
+// Skipper. Passed at registration, it declares the route's policy next to the route.
+//
+// Example:
+//
+//	router := app.Router("/admin", auth, middleware.RequireRole("admin"))
+//	// or per route
+//	router.Get("/reports", middleware.RequireRole("admin", "auditor")).Handle(listReports)
+func RequireRole(roles ...string) func(next http.HandlerFunc) http.HandlerFunc {
+	return defaultRBAC.RequireRole(roles...)
+}
+
+// RequirePermission returns a middleware that lets requests through whose
+// Principal, set with WithPrincipal, has all of permissions. Others are rejected
+// with 403 Forbidden as application/problem+json. Use NewRBAC for a custom
+// Authorizer or a Skipper.
+//
+// Example:
+//
+//	router.Post("/users", middleware.RequirePermission("users:write")).Handle(createUser)
+func RequirePermission(permissions ...string) func(next http.HandlerFunc) http.HandlerFunc {
+	return defaultRBAC.RequirePermission(permissions...)
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request, detail string) {
+	velocity.WriteProblem(w, velocity.Problem{
+		Status:   http.StatusForbidden,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
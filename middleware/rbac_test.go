@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+type headerAuthorizer struct{}
+
+func (headerAuthorizer) HasRole(r *http.Request, role string) bool {
+	return r.Header.Get("X-Role") == role
+}
+
+func (headerAuthorizer) HasPermission(r *http.Request, permission string) bool {
+	return r.Header.Get("X-Permission") == permission
+}
+
+func TestRBAC(t *testing.T) {
+	admin := &middleware.Principal{ID: "1", Roles: []string{"admin"}, Permissions: []string{"users:read", "users:write"}}
+	auditor := &middleware.Principal{ID: "2", Roles: []string{"auditor"}, Permissions: []string{"users:read"}}
+
+	tests := []struct {
+		name           string
+		mw             func(http.HandlerFunc) http.HandlerFunc
+		principal      *middleware.Principal
+		header         map[string]string
+		expectedStatus int
+		expectedDetail string
+	}{
+		{"role granted", middleware.RequireRole("admin"), admin, nil, http.StatusOK, ""},
+		{"any role", middleware.RequireRole("admin", "auditor"), auditor, nil, http.StatusOK, ""},
+		{"role missing", middleware.RequireRole("admin"), auditor, nil, http.StatusForbidden, "Requires role admin"},
+		{"no principal", middleware.RequireRole("admin"), nil, nil, http.StatusForbidden, "Requires role admin"},
+		{"all permissions", middleware.RequirePermission("users:read", "users:write"), admin, nil, http.StatusOK, ""},
+		{"permission missing", middleware.RequirePermission("users:read", "users:write"), auditor, nil, http.StatusForbidden, "Requires permission users:write"},
+		{"custom authorizer", middleware.NewRBAC(headerAuthorizer{}).RequireRole("ops"), nil, map[string]string{"X-Role": "ops"}, http.StatusOK, ""},
+		{"skipped", middleware.NewRBAC(middleware.PrincipalAuthorizer{}, middleware.RBACConfig{Skipper: middleware.SkipPaths("/users")}).RequireRole("admin"), nil, nil, http.StatusOK, ""},
+		{"custom authorizer denies", middleware.NewRBAC(headerAuthorizer{}).RequirePermission("deploy"), admin, nil, http.StatusForbidden, "Requires permission deploy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if tt.principal != nil {
+				req = middleware.WithPrincipal(req, tt.principal)
+			}
+			rec := httptest.NewRecorder()
+			tt.mw(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus != http.StatusForbidden {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("expected problem+json, got %q", ct)
+			}
+			var p velocity.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decoding problem: %v", err)
+			}
+			if p.Status != http.StatusForbidden || p.Detail != tt.expectedDetail || p.Instance != "/users" {
+				t.Errorf("unexpected problem %+v", p)
+			}
+		})
+	}
+}
+
+func TestGetPrincipal(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if p := middleware.GetPrincipal(req); p != nil {
+		t.Errorf("expected no principal, got %+v", p)
+	}
+	p := &middleware.Principal{ID: "42"}
+	if got := middleware.GetPrincipal(middleware.WithPrincipal(req, p)); got != p {
+		t.Errorf("expected principal %+v, got %+v", p, got)
+	}
+}
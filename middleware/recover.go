@@ -1,21 +1,88 @@
 package middleware
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"runtime/debug"
+
+	"github.com/Juanfec4/velocity"
 )
 
 // ErrRecoverConfig configures the ErrRecover middleware.
 type ErrRecoverConfig struct {
 	// Cb is the callback function called on panic
 	Cb func(v any)
+
+	// OnPanic is called on panic with the stack trace of the panicking goroutine.
+	// It takes precedence over Cb.
+	OnPanic func(v any, stack []byte)
+
+	// Status is the status code of the response sent after a panic
+	Status *int
+
+	// Body is the body of the response sent after a panic
+	Body *string
+
+	// Reporter receives panics as *PanicError, e.g. to forward them to an error
+	// tracker, in addition to OnPanic
+	Reporter Reporter
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
 }
 
+// Reporter reports recovered panics, e.g. to Sentry, Bugsnag or OpenTelemetry.
+// ctx is the request context, so tracing spans and other request-scoped values
+// are available.
+type Reporter interface {
+	Report(ctx context.Context, err error, stack []byte)
+}
+
+// PanicError is the error passed to a Reporter for a recovered panic. It carries
+// the panic value and metadata about the request that caused it.
+type PanicError struct {
+	// Value is the value passed to panic
+	Value any
+
+	// Method and Path are the request method and URL path
+	Method string
+	Path   string
+
+	// Route is the pattern of the matched route, e.g. "/users/:id"
+	Route string
+
+	// RequestID is the ID set by the RequestID middleware, if it ran first
+	RequestID string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+var defaultErrRecoverStatus = http.StatusInternalServerError
+var defaultErrRecoverBody = ""
 var defaultErrRecoverConfig = ErrRecoverConfig{
-	Cb: defaultCb,
+	OnPanic: defaultOnPanic,
+	Status:  &defaultErrRecoverStatus,
+	Body:    &defaultErrRecoverBody,
 }
 
-// ErrRecover returns a middleware that recovers from panics.
+// ErrRecover returns a middleware that recovers from panics, logs them with their
+// stack trace, passes them to Reporter if set and responds with Status and Body.
+// If the handler already started the response, it is left as is, since its status
+// can no longer change. Panics with http.ErrAbortHandler, which net/http uses to
+// abort a response silently, are re-raised.
 //
 // Example:
 //
@@ -24,25 +91,114 @@ var defaultErrRecoverConfig = ErrRecoverConfig{
 //	router := app.Router("/api", middleware.ErrRecover(middleware.ErrRecoverConfig{
 //	    Cb: func(v any) { log.Printf("Panic: %v", v) },
 //	}))
+//	// or with a stack trace and custom response
+//	router := app.Router("/api", middleware.ErrRecover(middleware.ErrRecoverConfig{
+//	    OnPanic: func(v any, stack []byte) { slog.Error("panic", "value", v, "stack", string(stack)) },
+//	    Body: stringPtr("Something went wrong"),
+//	}))
+//	// or reporting to an error tracker
+//	router := app.Router("/api", middleware.RequestID(), middleware.ErrRecover(middleware.ErrRecoverConfig{
+//	    Reporter: sentryReporter{},
+//	}))
 func ErrRecover(cfg ...ErrRecoverConfig) func(next http.HandlerFunc) http.HandlerFunc {
-	cb := defaultErrRecoverConfig.Cb
+	config := defaultErrRecoverConfig
 	if len(cfg) > 0 {
-		cb = cfg[0].Cb
+		if cfg[0].OnPanic != nil {
+			config.OnPanic = cfg[0].OnPanic
+		} else if cb := cfg[0].Cb; cb != nil {
+			config.OnPanic = func(v any, _ []byte) { cb(v) }
+		}
+		if cfg[0].Status != nil {
+			config.Status = cfg[0].Status
+		}
+		if cfg[0].Body != nil {
+			config.Body = cfg[0].Body
+		}
+		config.Reporter = cfg[0].Reporter
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
 	}
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			rw := &recoverWriter{ResponseWriter: w}
 			defer func() {
-				if v := recover(); v != nil {
-					cb(v)
-					w.WriteHeader(http.StatusInternalServerError)
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(v)
+				}
+				stack := debug.Stack()
+				config.OnPanic(v, stack)
+				if config.Reporter != nil {
+					config.Reporter.Report(r.Context(), &PanicError{
+						Value:     v,
+						Method:    r.Method,
+						Path:      r.URL.Path,
+						Route:     velocity.GetRoutePattern(r),
+						RequestID: GetRequestID(r),
+					}, stack)
+				}
+				if rw.started {
+					return
+				}
+				if *config.Body == "" {
+					w.WriteHeader(*config.Status)
 					return
 				}
+				http.Error(w, *config.Body, *config.Status)
 			}()
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(rw, r)
 		}
 	}
 }
 
-func defaultCb(v any) {
-	log.Printf("Recovered from panic: %v\n", v)
+func defaultOnPanic(v any, stack []byte) {
+	log.Printf("Recovered from panic: %v\n%s", v, stack)
+}
+
+// recoverWriter records whether the response was started, so no second status
+// is written after a panic.
+type recoverWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *recoverWriter) WriteHeader(code int) {
+	if code >= 200 {
+		rw.started = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoverWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoverWriter) Flush() {
+	rw.started = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.started = true
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+func (rw *recoverWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (rw *recoverWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
@@ -0,0 +1,203 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestErrRecover(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	body := "Something went wrong"
+
+	tests := []struct {
+		name           string
+		config         []middleware.ErrRecoverConfig
+		handler        http.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "default response",
+			handler:        func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "custom response",
+			config:         []middleware.ErrRecoverConfig{{Status: &status, Body: &body}},
+			handler:        func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "Something went wrong\n",
+		},
+		{
+			name: "response already started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "partial",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotValue any
+			var gotStack string
+			config := middleware.ErrRecoverConfig{OnPanic: func(v any, stack []byte) {
+				gotValue, gotStack = v, string(stack)
+			}}
+			if len(tt.config) > 0 {
+				config.Status, config.Body = tt.config[0].Status, tt.config[0].Body
+			}
+
+			rec := httptest.NewRecorder()
+			middleware.ErrRecover(config)(tt.handler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if gotValue != "boom" {
+				t.Errorf("expected panic value boom, got %v", gotValue)
+			}
+			if !strings.Contains(gotStack, "recover_test.go") {
+				t.Errorf("expected stack trace of the panic, got %q", gotStack)
+			}
+		})
+	}
+}
+
+func TestErrRecoverCb(t *testing.T) {
+	var got any
+	handler := middleware.ErrRecover(middleware.ErrRecoverConfig{Cb: func(v any) { got = v }})(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "boom" {
+		t.Errorf("expected Cb to be called with boom, got %v", got)
+	}
+}
+
+func TestErrRecoverAbortHandler(t *testing.T) {
+	handler := middleware.ErrRecover(middleware.ErrRecoverConfig{OnPanic: func(any, []byte) {
+		t.Error("expected ErrAbortHandler not to be reported")
+	}})(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected ErrAbortHandler to be re-raised, got %v", v)
+		}
+	}()
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+type reporterFunc func(ctx context.Context, err error, stack []byte)
+
+func (f reporterFunc) Report(ctx context.Context, err error, stack []byte) {
+	f(ctx, err, stack)
+}
+
+func TestErrRecoverReporter(t *testing.T) {
+	var reported *middleware.PanicError
+	var stack []byte
+	app := velocity.New()
+	router := app.Router("/",
+		middleware.RequestID(),
+		middleware.ErrRecover(middleware.ErrRecoverConfig{
+			OnPanic: func(any, []byte) {},
+			Reporter: reporterFunc(func(ctx context.Context, err error, s []byte) {
+				if !errors.As(err, &reported) {
+					t.Fatalf("expected *PanicError, got %T", err)
+				}
+				stack = s
+			}),
+		}),
+	)
+	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+		panic(io.ErrUnexpectedEOF)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	if reported == nil {
+		t.Fatal("expected panic to be reported")
+	}
+	if reported.Route != "/users/:id" || reported.Path != "/users/7" || reported.Method != http.MethodGet || reported.RequestID != "req-1" {
+		t.Errorf("unexpected metadata %+v", reported)
+	}
+	if !errors.Is(reported, io.ErrUnexpectedEOF) {
+		t.Error("expected PanicError to unwrap to the panic value")
+	}
+	if reported.Error() != "panic: unexpected EOF" {
+		t.Errorf("unexpected message %q", reported.Error())
+	}
+	if len(stack) == 0 {
+		t.Error("expected stack trace")
+	}
+}
+
+// pushRecorder is a ResponseRecorder supporting server push and write deadlines.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed   []string
+	deadline time.Time
+}
+
+func (pr *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	pr.pushed = append(pr.pushed, target)
+	return nil
+}
+
+func (pr *pushRecorder) SetWriteDeadline(t time.Time) error {
+	pr.deadline = t
+	return nil
+}
+
+func TestErrRecoverWriterInterfaces(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	handler := middleware.ErrRecover()(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := w.(http.Pusher)
+		if !ok {
+			t.Fatal("expected the writer to implement http.Pusher")
+		}
+		if err := p.Push("/app.css", nil); err != nil {
+			t.Errorf("unexpected push error: %v", err)
+		}
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			t.Errorf("expected ResponseController to reach the underlying writer: %v", err)
+		}
+	})
+
+	rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.pushed) != 1 || rec.pushed[0] != "/app.css" {
+		t.Errorf("expected /app.css to be pushed, got %v", rec.pushed)
+	}
+	if !rec.deadline.Equal(deadline) {
+		t.Errorf("expected write deadline %v, got %v", deadline, rec.deadline)
+	}
+
+	handler = middleware.ErrRecover()(func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/app.css", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected ErrNotSupported without push support, got %v", err)
+		}
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,147 @@
+// Package redisstore provides Redis-backed stores for the velocity middleware, so
+// state such as rate limit counters, cached responses and sessions is shared between
+// replicas of a service. It lives in its own package so the Redis client is only
+// linked into programs that use it.
+//
+// Example:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	router := app.Router("/api", middleware.RateLimit(middleware.RateLimitConfig{
+//	    Store: redisstore.NewRateLimitStore(client, "ratelimit:"),
+//	}))
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+	"github.com/Juanfec4/velocity/session"
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementScript increments the counter in KEYS[1], starting a window of ARGV[1]
+// milliseconds when the key is new or has lost its expiry, and returns the count and
+// the milliseconds left in the window. Running it as a script makes the increment and
+// expiry atomic, so concurrent replicas never leave a counter without a TTL.
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if count == 1 or ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RateLimitStore is a middleware.RateLimitStore that keeps counters in Redis.
+type RateLimitStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+var _ middleware.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore returns a store using client, which may be a *redis.Client,
+// *redis.ClusterClient or any other redis.UniversalClient. Keys are stored with
+// prefix prepended, e.g. "ratelimit:".
+func NewRateLimitStore(client redis.Scripter, prefix string) *RateLimitStore {
+	return &RateLimitStore{client: client, prefix: prefix}
+}
+
+// Increment implements middleware.RateLimitStore.
+func (s *RateLimitStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	res, err := incrementScript.Run(ctx, s.client, []string{s.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("redisstore: increment %q: %w", key, err)
+	}
+	if len(res) != 2 {
+		return 0, time.Time{}, fmt.Errorf("redisstore: increment %q: unexpected reply %v", key, res)
+	}
+	return int(res[0]), time.Now().Add(time.Duration(res[1]) * time.Millisecond), nil
+}
+
+// CacheStore is a middleware.CacheStore that keeps cached responses in Redis.
+type CacheStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ middleware.CacheStore = (*CacheStore)(nil)
+
+// NewCacheStore returns a store using client, which may be a *redis.Client,
+// *redis.ClusterClient or any other redis.UniversalClient. Keys are stored with
+// prefix prepended, e.g. "cache:".
+func NewCacheStore(client redis.Cmdable, prefix string) *CacheStore {
+	return &CacheStore{client: client, prefix: prefix}
+}
+
+// Get implements middleware.CacheStore.
+func (s *CacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redisstore: get %q: %w", key, err)
+	}
+	return b, true, nil
+}
+
+// Set implements middleware.CacheStore.
+func (s *CacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redisstore: set %q: %w", key, err)
+	}
+	return nil
+}
+
+// SessionStore is a session.Store that keeps sessions in Redis, expiring them with
+// the key TTL.
+type SessionStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ session.Store = (*SessionStore)(nil)
+
+// NewSessionStore returns a store using client, which may be a *redis.Client,
+// *redis.ClusterClient or any other redis.UniversalClient. Keys are stored with
+// prefix prepended, e.g. "session:".
+func NewSessionStore(client redis.Cmdable, prefix string) *SessionStore {
+	return &SessionStore{client: client, prefix: prefix}
+}
+
+// Load implements session.Store.
+func (s *SessionStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	b, err := s.client.Get(ctx, s.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redisstore: load session: %w", err)
+	}
+	return b, true, nil
+}
+
+// Save implements session.Store.
+func (s *SessionStore) Save(ctx context.Context, id string, data []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.Delete(ctx, id)
+	}
+	if err := s.client.Set(ctx, s.prefix+id, data, ttl).Err(); err != nil {
+		return fmt.Errorf("redisstore: save session: %w", err)
+	}
+	return nil
+}
+
+// Delete implements session.Store.
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.prefix+id).Err(); err != nil {
+		return fmt.Errorf("redisstore: delete session: %w", err)
+	}
+	return nil
+}
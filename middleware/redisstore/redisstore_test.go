@@ -0,0 +1,112 @@
+package redisstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware/redisstore"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := redisstore.NewRateLimitStore(client, "rl:")
+	ctx := context.Background()
+
+	for want := 1; want <= 3; want++ {
+		count, reset, err := store.Increment(ctx, "client", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("expected count %d, got %d", want, count)
+		}
+		if until := time.Until(reset); until <= 0 || until > time.Minute {
+			t.Errorf("expected reset within a minute, got %v", until)
+		}
+	}
+	if ttl := mr.TTL("rl:client"); ttl != time.Minute {
+		t.Errorf("expected key TTL of 1m, got %v", ttl)
+	}
+
+	mr.FastForward(time.Minute)
+	count, _, err := store.Increment(ctx, "client", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected count to restart at 1 after the window, got %d", count)
+	}
+}
+
+func TestRateLimitStoreUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	mr.Close()
+
+	store := redisstore.NewRateLimitStore(client, "rl:")
+	if _, _, err := store.Increment(context.Background(), "client", time.Minute); err == nil {
+		t.Error("expected error when Redis is unreachable")
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := redisstore.NewCacheStore(client, "cache:")
+	ctx := context.Background()
+
+	if _, ok, err := store.Get(ctx, "/users"); ok || err != nil {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+	if err := store.Set(ctx, "/users", []byte("response"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := store.Get(ctx, "/users")
+	if err != nil || !ok || string(value) != "response" {
+		t.Fatalf("expected stored value, got %q ok=%v err=%v", value, ok, err)
+	}
+	if ttl := mr.TTL("cache:/users"); ttl != time.Minute {
+		t.Errorf("expected key TTL of 1m, got %v", ttl)
+	}
+
+	mr.FastForward(time.Minute)
+	if _, ok, _ := store.Get(ctx, "/users"); ok {
+		t.Error("expected entry to expire")
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := redisstore.NewSessionStore(client, "session:")
+	ctx := context.Background()
+
+	if err := store.Save(ctx, "abc", []byte("data"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := store.Load(ctx, "abc")
+	if err != nil || !ok || string(data) != "data" {
+		t.Fatalf("expected stored session, got %q ok=%v err=%v", data, ok, err)
+	}
+	if ttl := mr.TTL("session:abc"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected key TTL of about 1h, got %v", ttl)
+	}
+
+	if err := store.Delete(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Load(ctx, "abc"); ok {
+		t.Error("expected session to be deleted")
+	}
+}
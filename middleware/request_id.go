@@ -14,6 +14,9 @@ type RequestIDConfig struct {
 
 	// Generator is a function that generates request IDs
 	Generator func() string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
 }
 
 var defaultReqIDHeader = "X-Request-ID"
@@ -41,10 +44,17 @@ func RequestID(cfg ...RequestIDConfig) func(next http.HandlerFunc) http.HandlerF
 		if cfg[0].Header != nil {
 			config.Header = cfg[0].Header
 		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
 	}
 
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
 			requestID := r.Header.Get(*config.Header)
 			if requestID == "" {
 				requestID = config.Generator()
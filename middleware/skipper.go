@@ -0,0 +1,55 @@
+package middleware
+
+import "net/http"
+
+// Skipper reports whether a middleware should pass a request on to the next handler
+// untouched. Every middleware configured with a config struct accepts one as its
+// Skipper option, so health checks and internal routes can bypass them consistently.
+type Skipper func(r *http.Request) bool
+
+// SkipPaths returns a Skipper for requests to paths. Entries ending in "*" match a
+// prefix, e.g. "/internal/*".
+//
+// Example:
+//
+//	skipHealth := middleware.SkipPaths("/health", "/metrics")
+//	app.Use(
+//	    middleware.Logger(middleware.LoggerConfig{Skipper: skipHealth}),
+//	    middleware.RequestID(middleware.RequestIDConfig{Skipper: skipHealth}),
+//	)
+func SkipPaths(paths ...string) Skipper {
+	return func(r *http.Request) bool {
+		return pathAllowed(paths, r.URL.Path)
+	}
+}
+
+// When returns a middleware applying mw only to requests pred returns true for;
+// other requests go straight to the next handler. It adds conditional behavior to
+// any middleware, including ones without a Skipper option.
+//
+// Example:
+//
+//	streaming := func(r *http.Request) bool {
+//	    return r.Header.Get("Accept") == "text/event-stream"
+//	}
+//	app.Use(middleware.Unless(streaming, middleware.Compress()))
+//	// or
+//	app.Use(middleware.When(middleware.SkipPaths("/admin/*"), middleware.RequireRole("admin")))
+func When(pred func(r *http.Request) bool, mw func(next http.HandlerFunc) http.HandlerFunc) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		wrapped := mw(next)
+		return func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				wrapped(w, r)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// Unless returns a middleware applying mw to all requests except those pred returns
+// true for, the inverse of When.
+func Unless(pred func(r *http.Request) bool, mw func(next http.HandlerFunc) http.HandlerFunc) func(next http.HandlerFunc) http.HandlerFunc {
+	return When(func(r *http.Request) bool { return !pred(r) }, mw)
+}
@@ -0,0 +1,130 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestSkipper(t *testing.T) {
+	skip := middleware.SkipPaths("/health")
+	var maintenance velocity.Toggle
+	maintenance.Enable()
+	limit := 0
+
+	tests := []struct {
+		name string
+		mw   func(http.HandlerFunc) http.HandlerFunc
+	}{
+		{"BasicAuth", middleware.BasicAuth(middleware.BasicAuthConfig{Users: map[string]string{"a": "b"}, Skipper: skip})},
+		{"Cache", middleware.Cache(middleware.CacheConfig{Skipper: skip})},
+		{"CanonicalHost", middleware.CanonicalHost("example.org", 0, middleware.CanonicalHostConfig{Skipper: skip})},
+		{"ClientIP", middleware.ClientIP(middleware.ClientIPConfig{Skipper: skip})},
+		{"Compress", middleware.Compress(middleware.CompressConfig{Skipper: skip})},
+		{"ContentTypeBodyLimit", middleware.ContentTypeBodyLimit(nil, middleware.ContentTypeBodyLimitConfig{Skipper: skip})},
+		{"CORS", middleware.CORS(middleware.CorsConfig{Skipper: skip})},
+		{"CSRF", middleware.CSRF(middleware.CSRFConfig{Skipper: skip})},
+		{"ErrRecover", middleware.ErrRecover(middleware.ErrRecoverConfig{Skipper: skip})},
+		{"ETag", middleware.ETag(middleware.ETagConfig{Skipper: skip})},
+		{"Language", middleware.Language([]string{"en"}, "en", middleware.LanguageConfig{Skipper: skip})},
+		{"Logger", middleware.Logger(middleware.LoggerConfig{Skipper: skip})},
+		{"Maintenance", middleware.Maintenance(&maintenance, middleware.MaintenanceConfig{Skipper: skip})},
+		{"RateLimit", middleware.RateLimit(middleware.RateLimitConfig{Max: &limit, Skipper: skip})},
+		{"RequestID", middleware.RequestID(middleware.RequestIDConfig{Skipper: skip})},
+		{"Timeout", middleware.Timeout(time.Second, middleware.TimeoutConfig{Skipper: skip})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/health", strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Origin", "https://other.com")
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			called := false
+			tt.mw(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				if r != req {
+					t.Error("expected the original request")
+				}
+				w.Write([]byte("ok"))
+			})(rec, req)
+
+			if !called {
+				t.Fatal("expected handler to be called")
+			}
+			if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+				t.Errorf("expected untouched response, got %d %q", rec.Code, rec.Body.String())
+			}
+			if len(rec.Header()) != 1 {
+				t.Errorf("expected only Content-Type, got headers %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestSkipPaths(t *testing.T) {
+	skip := middleware.SkipPaths("/health", "/internal/*")
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/health", true},
+		{"/health/deep", false},
+		{"/internal/metrics", true},
+		{"/users", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := skip(httptest.NewRequest(http.MethodGet, tt.path, nil)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWhenUnless(t *testing.T) {
+	mark := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Applied", "true")
+			next(w, r)
+		}
+	}
+	admin := middleware.SkipPaths("/admin/*")
+
+	tests := []struct {
+		name     string
+		mw       func(http.HandlerFunc) http.HandlerFunc
+		path     string
+		expected string
+	}{
+		{"when matching", middleware.When(admin, mark), "/admin/users", "true"},
+		{"when not matching", middleware.When(admin, mark), "/users", ""},
+		{"unless matching", middleware.Unless(admin, mark), "/admin/users", ""},
+		{"unless not matching", middleware.Unless(admin, mark), "/users", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			called := false
+			tt.mw(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if !called {
+				t.Fatal("expected handler to be called")
+			}
+			if got := rec.Header().Get("X-Applied"); got != tt.expected {
+				t.Errorf("expected X-Applied %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
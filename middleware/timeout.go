@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig configures the Timeout middleware.
+type TimeoutConfig struct {
+	// Status is the status code written when the timeout is exceeded, e.g.
+	// http.StatusGatewayTimeout
+	Status *int
+
+	// Message is the response body written when the timeout is exceeded
+	Message *string
+
+	// Skipper skips the middleware for requests it returns true for
+	Skipper Skipper
+}
+
+var defaultTimeoutStatus = http.StatusServiceUnavailable
+var defaultTimeoutMessage = http.StatusText(http.StatusServiceUnavailable)
+var defaultTimeoutConfig = TimeoutConfig{
+	Status:  &defaultTimeoutStatus,
+	Message: &defaultTimeoutMessage,
+}
+
+// Timeout returns a middleware that gives handlers d to respond. The request context
+// gets a deadline of d; if the handler has not returned by then, the timeout response
+// is sent and later writes by the handler fail with http.ErrHandlerTimeout. Unlike
+// the server's WriteTimeout, the client receives a proper response and the connection
+// stays usable.
+//
+// Responses are buffered until the handler returns, so Timeout is not suited to
+// streaming. After sending the timeout response, Timeout still waits for the handler
+// to return, as request-scoped state is released afterwards; handlers doing long work
+// should stop once the request context is done. Panics in the handler are re-raised
+// in the calling goroutine, so ErrRecover placed before Timeout still sees them.
+//
+// Example:
+//
+//	router := app.Router("/api", middleware.Timeout(5*time.Second))
+//	// or with config
+//	router := app.Router("/api", middleware.Timeout(5*time.Second, middleware.TimeoutConfig{
+//	    Status: intPtr(http.StatusGatewayTimeout),
+//	}))
+func Timeout(d time.Duration, cfg ...TimeoutConfig) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultTimeoutConfig
+	if len(cfg) > 0 {
+		if cfg[0].Status != nil {
+			config.Status = cfg[0].Status
+		}
+		if cfg[0].Message != nil {
+			config.Message = cfg[0].Message
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, h: w.Header().Clone(), ctx: ctx}
+			done := make(chan struct{})
+			var panicVal any
+			go func() {
+				defer close(done)
+				defer func() {
+					panicVal = recover()
+				}()
+				next(tw, r.WithContext(ctx))
+			}()
+
+			select {
+			case <-done:
+				if panicVal != nil {
+					panic(panicVal)
+				}
+				tw.mu.Lock()
+				// The handler may have returned after its writes were rejected
+				if !tw.timedOut {
+					defer tw.mu.Unlock()
+					tw.flush()
+					return
+				}
+				tw.mu.Unlock()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+			}
+			if ctx.Err() == context.DeadlineExceeded {
+				http.Error(w, *config.Message, *config.Status)
+			}
+			http.NewResponseController(w).Flush()
+			<-done
+		}
+	}
+}
+
+// timeoutWriter buffers the handler's response so it can be discarded in favor of
+// the timeout response. It is locked as the handler runs in its own goroutine.
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	h   http.Header
+	ctx context.Context
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+// expired reports whether the response timed out, checking the context so writes
+// racing the middleware's own timeout handling are rejected too. It must be called
+// with mu held.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && tw.ctx.Err() != nil {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// flush copies the buffered response to the underlying writer.
+func (tw *timeoutWriter) flush() {
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.h[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	if !tw.wroteHeader {
+		if tw.buf.Len() == 0 {
+			return
+		}
+		tw.code = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.code)
+	tw.w.Write(tw.buf.Bytes())
+}
@@ -0,0 +1,76 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+func TestTimeout(t *testing.T) {
+	gatewayTimeout := http.StatusGatewayTimeout
+	tests := []struct {
+		name           string
+		config         []middleware.TimeoutConfig
+		delay          time.Duration
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"fast handler", nil, 0, http.StatusCreated, "done"},
+		{"slow handler", nil, time.Second, http.StatusServiceUnavailable, "Service Unavailable\n"},
+		{"custom status", []middleware.TimeoutConfig{{Status: &gatewayTimeout}}, time.Second, http.StatusGatewayTimeout, "Service Unavailable\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeErr := make(chan error, 1)
+			handler := middleware.Timeout(20*time.Millisecond, tt.config...)(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+				}
+				w.Header().Set("X-Handler", "1")
+				w.WriteHeader(http.StatusCreated)
+				_, err := w.Write([]byte("done"))
+				writeErr <- err
+			})
+
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			err := <-writeErr
+			if tt.delay > 0 {
+				if !errors.Is(err, http.ErrHandlerTimeout) {
+					t.Errorf("expected ErrHandlerTimeout for late write, got %v", err)
+				}
+				if rec.Header().Get("X-Handler") != "" {
+					t.Error("expected headers set after the timeout to be discarded")
+				}
+			} else if rec.Header().Get("X-Handler") != "1" {
+				t.Error("expected handler header to be copied")
+			}
+		})
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	handler := middleware.Timeout(time.Second)(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Errorf("expected panic to be re-raised, got %v", v)
+		}
+	}()
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
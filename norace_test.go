@@ -0,0 +1,5 @@
+//go:build !race
+
+package velocity_test
+
+const raceEnabled = false
@@ -0,0 +1,223 @@
+package velocity
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// routeParams holds the URL parameters of the matched route as key/value pairs in
+// route order. It is part of the pooled request state, so capturing params does not
+// allocate.
+type routeParams struct {
+	pairs  []pathParam
+	typed  map[string]any
+	values []string // scratch buffer for the values captured while matching
+}
+
+type pathParam struct {
+	key   string
+	value string
+}
+
+// get returns the value of the param key. rp may be nil.
+func (rp *routeParams) get(key string) (string, bool) {
+	if rp == nil {
+		return "", false
+	}
+	for _, p := range rp.pairs {
+		if p.key == key {
+			return p.value, true
+		}
+	}
+	return "", false
+}
+
+// set sets the param key, adding it if it is not captured yet.
+func (rp *routeParams) set(key, value string) {
+	for i := range rp.pairs {
+		if rp.pairs[i].key == key {
+			rp.pairs[i].value = value
+			return
+		}
+	}
+	rp.pairs = append(rp.pairs, pathParam{key: key, value: value})
+}
+
+// toMap returns the params as a new map.
+func (rp *routeParams) toMap() map[string]string {
+	m := make(map[string]string, len(rp.pairs))
+	for _, p := range rp.pairs {
+		m[p.key] = p.value
+	}
+	return m
+}
+
+// requestParams returns the params of the route matched for r, or nil.
+func requestParams(r *http.Request) *routeParams {
+	st := requestState(r)
+	if st == nil {
+		return nil
+	}
+	return &st.params
+}
+
+// reset clears the params so the buffers can be reused. Params must not be read
+// after the handler returns, since the pairs are reused.
+func (rp *routeParams) reset() {
+	clear(rp.pairs)
+	clear(rp.values[:cap(rp.values)])
+	rp.pairs = rp.pairs[:0]
+	rp.typed = nil
+}
+
+// paramConverters holds the supported types for typed path parameters such as ":id<int>".
+var paramConverters = map[string]func(string) (any, error){
+	"string": func(s string) (any, error) { return s, nil },
+	"int":    func(s string) (any, error) { return strconv.ParseInt(s, 10, 64) },
+	"uint":   func(s string) (any, error) { return strconv.ParseUint(s, 10, 64) },
+	"float":  func(s string) (any, error) { return strconv.ParseFloat(s, 64) },
+	"bool":   func(s string) (any, error) { return strconv.ParseBool(s) },
+	"uuid":   func(s string) (any, error) { return uuid.Parse(s) },
+}
+
+// GetTypedParams retrieves URL parameters converted according to the types declared
+// in the route, e.g. ":id<int>" yields an int64 and ":ref<uuid>" a uuid.UUID.
+// Supported types are string, int (int64), uint (uint64), float (float64), bool and
+// uuid (uuid.UUID). Untyped params are returned as strings. Requests whose params
+// fail conversion are answered with 400 Bad Request before reaching the handler.
+//
+// Example:
+//
+//	router.Get("/users/:id<int>").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    id := velocity.GetTypedParams(r)["id"].(int64)
+//	})
+func GetTypedParams(r *http.Request) map[string]any {
+	if rp := requestParams(r); rp != nil && rp.typed != nil {
+		return rp.typed
+	}
+	p := GetParams(r)
+	tp := make(map[string]any, len(p))
+	for k, v := range p {
+		tp[k] = v
+	}
+	return tp
+}
+
+func (e *endpoint) convertParams(rp *routeParams) (map[string]any, error) {
+	tp := make(map[string]any, len(rp.pairs))
+	for _, p := range rp.pairs {
+		tp[p.key] = p.value
+	}
+	for i, k := range e.pKeys {
+		typ := e.pTypes[i]
+		if typ == "" {
+			continue
+		}
+		v := rp.pairs[i].value
+		converted, err := paramConverters[typ](v)
+		if err != nil {
+			return nil, fmt.Errorf("velocity: param %q: cannot convert %q to %s: %w", k, v, typ, err)
+		}
+		tp[k] = converted
+	}
+	return tp, nil
+}
+
+// Param returns the URL parameter key, or an empty string if it is not set. Unlike
+// GetParams, it never allocates.
+//
+// Example:
+//
+//	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    id := velocity.Param(r, "id")
+//	})
+func Param(r *http.Request, key string) string {
+	v, _ := requestParams(r).get(key)
+	return v
+}
+
+// ParamInt retrieves the URL parameter key and parses it as a base 10 int.
+//
+// Example:
+//
+//	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    id, err := velocity.ParamInt(r, "id")
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	})
+func ParamInt(r *http.Request, key string) (int, error) {
+	v, err := lookupParam(r, key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("velocity: param %q: cannot convert %q to int: %w", key, v, err)
+	}
+	return n, nil
+}
+
+// ParamUUID retrieves the URL parameter key and parses it as a uuid.UUID.
+//
+// Example:
+//
+//	router.Get("/orders/:ref").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    ref, err := velocity.ParamUUID(r, "ref")
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	})
+func ParamUUID(r *http.Request, key string) (uuid.UUID, error) {
+	v, err := lookupParam(r, key)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("velocity: param %q: cannot convert %q to uuid: %w", key, v, err)
+	}
+	return id, nil
+}
+
+// lookupParam returns the URL parameter key, failing if it is not set.
+func lookupParam(r *http.Request, key string) (string, error) {
+	v, ok := requestParams(r).get(key)
+	if !ok {
+		return "", fmt.Errorf("velocity: param %q not found", key)
+	}
+	return v, nil
+}
+
+// ParamTime retrieves the URL parameter key and parses it as a time.Time using layout.
+// An empty layout defaults to time.RFC3339.
+//
+// Example:
+//
+//	router.Get("/reports/:date").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    date, err := velocity.ParamTime(r, "date", "2006-01-02")
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	})
+func ParamTime(r *http.Request, key, layout string) (time.Time, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	v, err := lookupParam(r, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("velocity: param %q: cannot parse %q as time with layout %q: %w", key, v, layout, err)
+	}
+	return t, nil
+}
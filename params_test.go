@@ -0,0 +1,232 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/google/uuid"
+)
+
+func TestParamTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		layout      string
+		expected    time.Time
+		expectedErr string
+	}{
+		{
+			name:     "RFC3339 default layout",
+			path:     "/reports/2024-05-01T10:30:00Z",
+			expected: time.Date(2024, time.May, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "custom layout",
+			path:     "/reports/2024-05-01",
+			layout:   "2006-01-02",
+			expected: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "unparseable value",
+			path:        "/reports/yesterday",
+			layout:      "2006-01-02",
+			expectedErr: `param "date": cannot parse "yesterday"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := velocity.New()
+			router := app.Router("/")
+
+			var got time.Time
+			var err error
+			router.Get("/reports/:date").Handle(func(w http.ResponseWriter, r *http.Request) {
+				got, err = velocity.ParamTime(r, "date", tt.layout)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParamGetters(t *testing.T) {
+	ref := uuid.New()
+	str := func(r *http.Request, key string) (any, error) { return velocity.Param(r, key), nil }
+	num := func(r *http.Request, key string) (any, error) { return velocity.ParamInt(r, key) }
+	id := func(r *http.Request, key string) (any, error) { return velocity.ParamUUID(r, key) }
+
+	tests := []struct {
+		name        string
+		path        string
+		key         string
+		get         func(r *http.Request, key string) (any, error)
+		expected    any
+		expectedErr string
+	}{
+		{name: "string", path: "/items/42/refs/" + ref.String(), key: "id", get: str, expected: "42"},
+		{name: "missing string", path: "/items/42/refs/" + ref.String(), key: "missing", get: str, expected: ""},
+		{name: "int", path: "/items/42/refs/" + ref.String(), key: "id", get: num, expected: 42},
+		{name: "invalid int", path: "/items/abc/refs/" + ref.String(), key: "id", get: num, expectedErr: `param "id": cannot convert "abc" to int`},
+		{name: "missing int", path: "/items/42/refs/" + ref.String(), key: "missing", get: num, expectedErr: `param "missing" not found`},
+		{name: "uuid", path: "/items/42/refs/" + ref.String(), key: "ref", get: id, expected: ref},
+		{name: "invalid uuid", path: "/items/42/refs/nope", key: "ref", get: id, expectedErr: `param "ref": cannot convert "nope" to uuid`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := velocity.New()
+			router := app.Router("/")
+
+			var got any
+			var err error
+			router.Get("/items/:id/refs/:ref").Handle(func(w http.ResponseWriter, r *http.Request) {
+				got, err = tt.get(r, tt.key)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetTypedParams(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+
+	var params map[string]any
+	router.Get("/users/:id<int>/orders/:ref<uuid>").Handle(func(w http.ResponseWriter, r *http.Request) {
+		params = velocity.GetTypedParams(r)
+	})
+	router.Get("/flags/:name/state/:enabled<bool>").Handle(func(w http.ResponseWriter, r *http.Request) {
+		params = velocity.GetTypedParams(r)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expected       map[string]any
+	}{
+		{
+			name:           "int and uuid",
+			path:           "/users/42/orders/0b8e4f2c-1d2a-4f6e-9c1b-2a3b4c5d6e7f",
+			expectedStatus: http.StatusOK,
+			expected: map[string]any{
+				"id":  int64(42),
+				"ref": uuid.MustParse("0b8e4f2c-1d2a-4f6e-9c1b-2a3b4c5d6e7f"),
+			},
+		},
+		{
+			name:           "untyped param stays string",
+			path:           "/flags/beta/state/true",
+			expectedStatus: http.StatusOK,
+			expected:       map[string]any{"name": "beta", "enabled": true},
+		},
+		{
+			name:           "invalid int",
+			path:           "/users/abc/orders/0b8e4f2c-1d2a-4f6e-9c1b-2a3b4c5d6e7f",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid uuid",
+			path:           "/users/42/orders/not-a-uuid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params = nil
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expected == nil {
+				if params != nil {
+					t.Error("expected handler not to be called")
+				}
+				return
+			}
+			for k, exp := range tt.expected {
+				if got := params[k]; got != exp {
+					t.Errorf("expected %s = %#v, got %#v", k, exp, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPathParamDecoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		raw            bool
+		path           string
+		rawPath        string
+		expectedStatus int
+		expectedID     string
+	}{
+		{"decoded space", false, "/users/john%20doe", "", http.StatusOK, "john doe"},
+		{"decoded slash stays in param", false, "/users/a%2Fb", "", http.StatusOK, "a/b"},
+		{"invalid encoding", false, "/users/x", "/users/%zz", http.StatusBadRequest, ""},
+		{"raw params", true, "/users/john%20doe", "", http.StatusOK, "john%20doe"},
+		{"raw params with slash", true, "/users/a%2Fb", "", http.StatusOK, "a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := velocity.New(velocity.AppConfig{RawPathParams: tt.raw})
+			app.Router("/").Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(velocity.GetParams(r)["id"]))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.rawPath != "" {
+				req.URL.RawPath = tt.rawPath
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && rec.Body.String() != tt.expectedID {
+				t.Errorf("expected id %q, got %q", tt.expectedID, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package velocity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Problem is an RFC 7807 problem details object. Handlers registered with HandleE
+// or HandleCtx can return a *Problem, which the default error handler writes as
+// application/problem+json regardless of the ProblemJSON option.
+//
+// Example:
+//
+//	return &velocity.Problem{
+//	    Type:   "https://example.com/problems/out-of-credit",
+//	    Title:  "You do not have enough credit.",
+//	    Status: http.StatusForbidden,
+//	    Detail: "Your current balance is 30, but that costs 50.",
+//	}
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// Error returns the detail, or the title if the detail is empty.
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Detail
+}
+
+// WriteProblem writes p as an application/problem+json response. An empty Type
+// defaults to "about:blank", a zero Status to 500 and an empty Title to the
+// status text of Status.
+//
+// Example:
+//
+//	velocity.WriteProblem(w, velocity.Problem{
+//	    Status:   http.StatusConflict,
+//	    Detail:   "email already registered",
+//	    Instance: r.URL.Path,
+//	})
+func WriteProblem(w http.ResponseWriter, p Problem) error {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return write(w, p.Status, "application/problem+json", append(b, '\n'))
+}
+
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "json")
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	WriteProblem(w, Problem{Status: status, Detail: detail})
+}
+
+func problemNotFound(fallback http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsJSON(r) {
+			fallback(w, r)
+			return
+		}
+		writeProblem(w, http.StatusNotFound, "No route matches "+r.URL.Path)
+	}
+}
+
+func problemNotAllowed(fallback http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsJSON(r) {
+			fallback(w, r)
+			return
+		}
+		detail := "Method " + r.Method + " is not allowed for " + r.URL.Path
+		// The router sets Allow before answering 405
+		if allow := w.Header().Get("Allow"); allow != "" {
+			detail += "; allowed methods: " + allow
+		}
+		writeProblem(w, http.StatusMethodNotAllowed, detail)
+	}
+}
+
+func problemBadRequest(fallback http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsJSON(r) {
+			fallback(w, r)
+			return
+		}
+		writeProblem(w, http.StatusBadRequest, "Invalid path parameters for "+r.URL.Path)
+	}
+}
+
+func problemInternalError(fallback ErrorHandler) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		// The fallback writes returned problems as they are
+		var p *Problem
+		if !acceptsJSON(r) || errors.As(err, &p) {
+			fallback(w, r, err)
+			return
+		}
+		status, msg := errorStatus(err)
+		if status >= http.StatusInternalServerError {
+			// Do not expose internal error details
+			msg = ""
+		}
+		writeProblem(w, status, msg)
+	}
+}
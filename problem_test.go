@@ -0,0 +1,135 @@
+package velocity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestProblemJSON(t *testing.T) {
+	app := velocity.New(velocity.AppConfig{ProblemJSON: true})
+	router := app.Router("/")
+	router.Get("/fail").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+	router.Get("/gone").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.HTTPError{Code: http.StatusGone, Message: "resource removed"}
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		accept         string
+		expectedStatus int
+		expectedType   string
+		expectedDetail string
+	}{
+		{"404 accepting JSON", http.MethodGet, "/missing", "application/json", http.StatusNotFound, "application/problem+json", "No route matches /missing"},
+		{"405 accepting JSON", http.MethodTrace, "/fail", "application/json", http.StatusMethodNotAllowed, "application/problem+json", "Method TRACE is not allowed for /fail; allowed methods: GET, HEAD, OPTIONS"},
+		{"500 accepting JSON", http.MethodGet, "/fail", "application/problem+json", http.StatusInternalServerError, "application/problem+json", ""},
+		{"HTTPError accepting JSON", http.MethodGet, "/gone", "application/json", http.StatusGone, "application/problem+json", "resource removed"},
+		{"404 plain text", http.MethodGet, "/missing", "text/html", http.StatusNotFound, "", ""},
+		{"405 plain text", http.MethodTrace, "/fail", "", http.StatusMethodNotAllowed, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); tt.expectedType != "" && ct != tt.expectedType {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, ct)
+			}
+			if tt.expectedType == "" {
+				if ct := rec.Header().Get("Content-Type"); ct == "application/problem+json" {
+					t.Errorf("expected plain text response, got %q", ct)
+				}
+				return
+			}
+
+			var body struct {
+				Type   string `json:"type"`
+				Title  string `json:"title"`
+				Status int    `json:"status"`
+				Detail string `json:"detail"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode problem body: %v", err)
+			}
+			if body.Status != tt.expectedStatus {
+				t.Errorf("expected problem status %d, got %d", tt.expectedStatus, body.Status)
+			}
+			if body.Title != http.StatusText(tt.expectedStatus) {
+				t.Errorf("expected problem title %q, got %q", http.StatusText(tt.expectedStatus), body.Title)
+			}
+			if body.Type != "about:blank" {
+				t.Errorf("expected problem type %q, got %q", "about:blank", body.Type)
+			}
+			if body.Detail != tt.expectedDetail {
+				t.Errorf("expected problem detail %q, got %q", tt.expectedDetail, body.Detail)
+			}
+		})
+	}
+}
+
+func TestReturnedProblem(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Post("/orders").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return &velocity.Problem{
+			Type:     "https://example.com/problems/out-of-credit",
+			Title:    "You do not have enough credit.",
+			Status:   http.StatusForbidden,
+			Detail:   "Your current balance is 30, but that costs 50.",
+			Instance: r.URL.Path,
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected content type %q, got %q", "application/problem+json", ct)
+	}
+	var body velocity.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode problem body: %v", err)
+	}
+	expected := velocity.Problem{
+		Type:     "https://example.com/problems/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   http.StatusForbidden,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/orders",
+	}
+	if body != expected {
+		t.Errorf("expected %+v, got %+v", expected, body)
+	}
+}
+
+func TestWriteProblemDefaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := velocity.WriteProblem(rec, velocity.Problem{Status: http.StatusConflict, Detail: "email taken"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body velocity.Problem
+	json.NewDecoder(rec.Body).Decode(&body)
+	expected := velocity.Problem{Type: "about:blank", Title: "Conflict", Status: http.StatusConflict, Detail: "email taken"}
+	if rec.Code != http.StatusConflict || body != expected {
+		t.Errorf("expected %d %+v, got %d %+v", http.StatusConflict, expected, rec.Code, body)
+	}
+}
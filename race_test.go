@@ -0,0 +1,7 @@
+//go:build race
+
+package velocity_test
+
+// raceEnabled skips allocation checks, since sync.Pool drops items at random under
+// the race detector.
+const raceEnabled = true
@@ -0,0 +1,50 @@
+package velocity
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect registers a route that redirects requests for path p to target with the
+// given status code, for GET, POST, PUT, PATCH and DELETE. A code of 0 defaults to
+// 301 Moved Permanently. Params of p are substituted into ":name" and "*" segments
+// of target, and the request query is preserved unless target sets its own.
+//
+// Example:
+//
+//	router.Redirect("/old-path", "/new-path", http.StatusMovedPermanently)
+//	router.Redirect("/users/:id/profile", "/profiles/:id", http.StatusPermanentRedirect)
+func (r *Router) Redirect(p string, target string, code int, mws ...Middleware) error {
+	if code == 0 {
+		code = http.StatusMovedPermanently
+	}
+	return r.Any(p, mws...).Handle(func(w http.ResponseWriter, req *http.Request) {
+		u := expandTarget(target, GetParams(req))
+		if req.URL.RawQuery != "" && !strings.Contains(u, "?") {
+			u += "?" + req.URL.RawQuery
+		}
+		http.Redirect(w, req, u, code)
+	})
+}
+
+// expandTarget substitutes params into the ":name" and "*" segments of target.
+func expandTarget(target string, params map[string]string) string {
+	if len(params) == 0 {
+		return target
+	}
+	segments := strings.Split(target, "/")
+	for i, seg := range segments {
+		switch getSegmentType(seg) {
+		case param:
+			if v, ok := params[seg[1:]]; ok {
+				segments[i] = url.PathEscape(v)
+			}
+		case catchAll:
+			if v, ok := params["*"]; ok && seg == "*" {
+				segments[i] = v
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
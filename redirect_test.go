@@ -0,0 +1,50 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestRedirect(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Redirect("/old-path", "/new-path", 0)
+	router.Redirect("/users/:id/profile", "/profiles/:id", http.StatusPermanentRedirect)
+	router.Redirect("/docs/*", "https://docs.example.com/*", http.StatusFound)
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"default code", http.MethodGet, "/old-path", http.StatusMovedPermanently, "/new-path"},
+		{"query preserved", http.MethodGet, "/old-path?page=2", http.StatusMovedPermanently, "/new-path?page=2"},
+		{"params substituted", http.MethodPost, "/users/42/profile", http.StatusPermanentRedirect, "/profiles/42"},
+		{"catch all substituted", http.MethodGet, "/docs/guide/intro", http.StatusFound, "https://docs.example.com/guide/intro"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.expectedLocation {
+				t.Errorf("expected location %q, got %q", tt.expectedLocation, got)
+			}
+		})
+	}
+
+	if !slices.Contains(app.Routes(), "GET /old-path") {
+		t.Errorf("expected redirect in routes, got %v", app.Routes())
+	}
+}
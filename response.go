@@ -0,0 +1,73 @@
+package velocity
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+)
+
+// JSON writes v as a JSON response with the given status. v is encoded before
+// anything is written, so an encoding error is returned with the response still
+// untouched and can be handled, e.g. by returning it from a HandleE handler.
+//
+// Example:
+//
+//	router.Post("/users").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    user, err := createUser(r)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return velocity.JSON(w, http.StatusCreated, user)
+//	})
+func JSON(w http.ResponseWriter, status int, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return write(w, status, "application/json", append(b, '\n'))
+}
+
+// XML writes v as an XML response with the given status, preceded by the standard
+// XML header. Like JSON, v is encoded before anything is written.
+//
+// Example:
+//
+//	velocity.XML(w, http.StatusOK, feed)
+func XML(w http.ResponseWriter, status int, v any) error {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return write(w, status, "application/xml; charset=utf-8", append([]byte(xml.Header), b...))
+}
+
+// Text writes s as a plain text response with the given status.
+//
+// Example:
+//
+//	velocity.Text(w, http.StatusOK, "pong")
+func Text(w http.ResponseWriter, status int, s string) error {
+	return write(w, status, "text/plain; charset=utf-8", []byte(s))
+}
+
+// NoContent writes an empty 204 No Content response.
+//
+// Example:
+//
+//	router.Delete("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    deleteUser(velocity.Param(r, "id"))
+//	    velocity.NoContent(w)
+//	})
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func write(w http.ResponseWriter, status int, contentType string, b []byte) error {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(status)
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,78 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestResponseHelpers(t *testing.T) {
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	tests := []struct {
+		name                string
+		write               func(w http.ResponseWriter) error
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "json",
+			write:               func(w http.ResponseWriter) error { return velocity.JSON(w, http.StatusCreated, item{Name: "ada"}) },
+			expectedStatus:      http.StatusCreated,
+			expectedContentType: "application/json",
+			expectedBody:        `{"name":"ada"}` + "\n",
+		},
+		{
+			name:                "xml",
+			write:               func(w http.ResponseWriter) error { return velocity.XML(w, http.StatusOK, item{Name: "ada"}) },
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<item><name>ada</name></item>`,
+		},
+		{
+			name:                "text",
+			write:               func(w http.ResponseWriter) error { return velocity.Text(w, http.StatusAccepted, "queued") },
+			expectedStatus:      http.StatusAccepted,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "queued",
+		},
+		{
+			name:           "no content",
+			write:          func(w http.ResponseWriter) error { velocity.NoContent(w); return nil },
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := tt.write(rec); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("expected Content-Type %q, got %q", tt.expectedContentType, got)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestJSONEncodingError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := velocity.JSON(rec, http.StatusOK, func() {}); err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Error("expected nothing to be written when encoding fails")
+	}
+}
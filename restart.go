@@ -0,0 +1,84 @@
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+)
+
+// listenerEnv tells a process started by Restart which address the listener passed
+// as file descriptor 3 is bound to, as network://addr.
+const listenerEnv = "VELOCITY_LISTENER"
+
+// Restart starts a new instance of the running binary, with the same arguments and
+// environment, that inherits the server's listening socket, so a new build can be
+// deployed without refusing connections. Once Restart returns, stop this process
+// with Shutdown; the new instance accepts connections on the socket in the meantime.
+// ListenAndWait does both on SIGHUP.
+//
+// Restart only works for servers started with Listen, ListenUnix, ListenWithContext
+// or ListenAndWait; the new instance must call the same method with the same port or
+// path to pick up the socket. The HTTPRedirectAddr listener is not inherited.
+// It is not supported on Windows.
+//
+// Example:
+//
+//	if err := app.Restart(); err != nil {
+//	    log.Printf("restart failed: %v", err)
+//	    return
+//	}
+//	app.Shutdown(ctx)
+func (a *App) Restart() error {
+	a.srvMu.Lock()
+	l, key := a.listener, a.listenKey
+	a.srvMu.Unlock()
+	if l == nil {
+		return errors.New("velocity: restart: server is not listening")
+	}
+	fl, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("velocity: restart: cannot pass %T to a new process", l)
+	}
+	if ul, ok := l.(*net.UnixListener); ok {
+		// The new instance keeps serving on the socket file after this one stops
+		ul.SetUnlinkOnClose(false)
+	}
+	f, err := fl.File()
+	if err != nil {
+		return fmt.Errorf("velocity: restart: %w", err)
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("velocity: restart: %w", err)
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), listenerEnv+"="+key)
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("velocity: restart: %w", err)
+	}
+	return nil
+}
+
+// inheritedListener returns the listener passed by a parent's Restart if it is bound
+// to network and addr, or nil if there is none. It is only used once.
+func inheritedListener(network, addr string) (net.Listener, error) {
+	if os.Getenv(listenerEnv) != network+"://"+addr {
+		return nil, nil
+	}
+	os.Unsetenv(listenerEnv)
+	f := os.NewFile(3, "velocity-listener")
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("velocity: inheriting listener: %w", err)
+	}
+	return l, nil
+}
@@ -0,0 +1,216 @@
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// routeTable holds the routing state of an App. Requests read it through an atomic
+// pointer, so runtime changes made with AddRoute and RemoveRoute copy the affected
+// tree, modify the copy and publish a new table instead of mutating the live one.
+type routeTable struct {
+	trees       map[method]*tree
+	hosts       map[string]map[method]*tree
+	methods     map[string]method
+	methodNames map[method]string
+}
+
+func newRouteTable() *routeTable {
+	rt := &routeTable{
+		trees:       newTrees(),
+		hosts:       make(map[string]map[method]*tree),
+		methods:     make(map[string]method),
+		methodNames: make(map[method]string),
+	}
+	for name, m := range methodLookup {
+		rt.methods[name] = m
+	}
+	rt.methods[http.MethodTrace] = mTRACE
+	for m, name := range reverseMethodLookup {
+		rt.methodNames[m] = name
+	}
+	return rt
+}
+
+// AddRoute registers h for the given method and path, with optional middleware, while
+// the app may already be serving requests. The affected tree is copied and swapped in
+// atomically, so in-flight requests keep using the routes they started with.
+// Routes added this way go through the same registration as Route.Handle, but they
+// belong to no router: they are not scoped to a host, router middleware is not
+// applied and errors returned by HandlerE handlers go to App.ErrorHandler.
+//
+// Example:
+//
+//	err := app.AddRoute(http.MethodGet, "/plugins/:name", pluginHandler)
+func (a *App) AddRoute(name, p string, h http.HandlerFunc, mws ...Middleware) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rt := a.table.Load().clone()
+	m, ok := rt.method(name)
+	if !ok {
+		return fmt.Errorf("velocity: invalid method %q", name)
+	}
+	if err := a.register(rt, []method{m}, p, chainMws(mws, h), &routeMeta{mws: len(mws)}, true); err != nil {
+		return err
+	}
+	a.table.Store(rt)
+	return nil
+}
+
+// register inserts fn at p for each of ms in the trees of meta.host and records the
+// route name once the route is registered for at least one method. With cow set the
+// trees are copied before they are modified, so rt can be published while requests
+// read the current table. The caller must hold a.mu.
+func (a *App) register(rt *routeTable, ms []method, p string, fn http.HandlerFunc, meta *routeMeta, cow bool) error {
+	errs := []error{}
+	for _, m := range ms {
+		t := rt.tree(meta.host, m)
+		if cow {
+			t = t.clone()
+		}
+		if err := t.insert(p, fn, meta, a.cfg.StrictRoutes); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rt.methodNames[m], err))
+			continue
+		}
+		if cow {
+			rt.setTree(meta.host, m, t)
+		}
+	}
+	if meta.name != "" && len(errs) < len(ms) {
+		a.names[meta.name] = p
+	}
+	return errors.Join(errs...)
+}
+
+// RemoveRoute unregisters the route for the given method and path while the app may
+// already be serving requests. The path must be written as it was registered, e.g.
+// "/users/:id". Like AddRoute, it only covers routes registered without a host;
+// routes of Host routers cannot be removed. It returns an error if no such route
+// exists.
+//
+// Example:
+//
+//	err := app.RemoveRoute(http.MethodGet, "/plugins/:name")
+func (a *App) RemoveRoute(name, p string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rt := a.table.Load()
+	m, ok := rt.methods[name]
+	if !ok {
+		return fmt.Errorf("velocity: no %s route %q", name, p)
+	}
+	t, ok := rt.trees[m]
+	if !ok {
+		return fmt.Errorf("velocity: no %s route %q", name, p)
+	}
+	t = t.clone()
+	e := t.remove(p)
+	if e == nil {
+		return fmt.Errorf("velocity: no %s route %q", name, p)
+	}
+	rt = rt.clone()
+	rt.trees[m] = t
+	a.table.Store(rt)
+	// Keep the name while the route is still registered for other methods
+	if routeName := e.meta.name; routeName != "" && !rt.hasName(routeName) {
+		delete(a.names, routeName)
+	}
+	return nil
+}
+
+// hasName reports whether a route registered under name is left in rt.
+func (rt *routeTable) hasName(name string) bool {
+	trees := []*tree{}
+	for _, t := range rt.trees {
+		trees = append(trees, t)
+	}
+	for _, hostTrees := range rt.hosts {
+		for _, t := range hostTrees {
+			trees = append(trees, t)
+		}
+	}
+	for _, t := range trees {
+		for _, e := range t.captureEndpoints() {
+			if e.meta.name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clone returns a copy of rt whose maps can be modified without affecting rt.
+// Trees are shared and must be cloned before they are modified.
+func (rt *routeTable) clone() *routeTable {
+	c := &routeTable{
+		trees:       make(map[method]*tree, len(rt.trees)),
+		hosts:       make(map[string]map[method]*tree, len(rt.hosts)),
+		methods:     make(map[string]method, len(rt.methods)),
+		methodNames: make(map[method]string, len(rt.methodNames)),
+	}
+	for m, t := range rt.trees {
+		c.trees[m] = t
+	}
+	for host, trees := range rt.hosts {
+		c.hosts[host] = make(map[method]*tree, len(trees))
+		for m, t := range trees {
+			c.hosts[host][m] = t
+		}
+	}
+	for name, m := range rt.methods {
+		c.methods[name] = m
+	}
+	for m, name := range rt.methodNames {
+		c.methodNames[m] = name
+	}
+	return c
+}
+
+// method returns the method for name, allocating a new one for valid custom methods.
+func (rt *routeTable) method(name string) (method, bool) {
+	if m, ok := rt.methods[name]; ok {
+		return m, true
+	}
+	if !methodRegex.MatchString(name) {
+		return 0, false
+	}
+	m := method(len(rt.methodNames))
+	rt.methods[name] = m
+	rt.methodNames[m] = name
+	return m, true
+}
+
+// tree returns the tree for host and m, creating it if needed.
+func (rt *routeTable) tree(host string, m method) *tree {
+	if host == "" {
+		t, ok := rt.trees[m]
+		if !ok {
+			t = newTree()
+			rt.trees[m] = t
+		}
+		return t
+	}
+	trees, ok := rt.hosts[host]
+	if !ok {
+		trees = newTrees()
+		rt.hosts[host] = trees
+	}
+	t, ok := trees[m]
+	if !ok {
+		t = newTree()
+		trees[m] = t
+	}
+	return t
+}
+
+// setTree replaces the tree for host and m.
+func (rt *routeTable) setTree(host string, m method, t *tree) {
+	if host == "" {
+		rt.trees[m] = t
+		return
+	}
+	rt.hosts[host][m] = t
+}
@@ -49,8 +49,10 @@ Available HTTP Methods:
   - PUT
   - PATCH
   - DELETE
-  - WebSocket (automatic upgrade detection)
-  - OPTIONS (automatic handling)
+  - TRACE (automatic reflection when AllowTrace is enabled)
+  - WebSocket (automatic upgrade detection, RFC 6455 handshake via route.HandleWS)
+  - OPTIONS (automatic handling, or explicit routes via Router.Options)
+  - Custom methods such as PROPFIND or MKCOL via Router.Method
 
 Features:
   - Fast routing with radix tree
@@ -66,12 +68,18 @@ package velocity
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
 	"slices"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -80,27 +88,78 @@ type (
 	// additional functionality before and/or after the handler execution.
 	Middleware func(next http.HandlerFunc) http.HandlerFunc
 
+	// ErrorHandler handles an error returned by a handler registered with HandleE.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
 	// App is the main router instance that implements http.Handler.
 	App struct {
 		cfg        AppConfig
 		notAllowed http.HandlerFunc
 		notFound   http.HandlerFunc
+		badRequest http.HandlerFunc
 		options    http.HandlerFunc
-		trees      map[method]tree
-		rootRouter *Router
+		onError    ErrorHandler
+		names      map[string]string
+		table      atomic.Pointer[routeTable]
+		mu         sync.Mutex
+		routers    []*Router
+		groups     []*Router
+		mounts     []mount
+		mws        []Middleware
+		handler    http.HandlerFunc
+		prepare    sync.Once
+		server     *http.Server
+		listener   net.Listener
+		listenKey  string
+		srvMu      sync.Mutex
+		health     health
+
+		onStart       []func(addr string)
+		onShutdown    []func()
+		onServerError []func(error)
 	}
 
 	// AppConfig holds configuration options for the App.
 	AppConfig struct {
 		// AllowTrace enables automatic handling of TRACE requests
 		AllowTrace bool
+
+		// RedirectTrailingSlash redirects requests for non-canonical paths, such as
+		// "/users/" or "/users//42", to their canonical form: 301 for GET and HEAD
+		// requests, 308 otherwise. Catch-all routes are never redirected.
+		RedirectTrailingSlash bool
+
+		// StrictRoutes makes route registration fail with an error when a route
+		// overrides an existing one or reuses a param position under a different
+		// name, e.g. "/users/:id" and "/users/:name", instead of last-write-wins
+		StrictRoutes bool
+
+		// ProblemJSON makes the default 404, 405 and 500 handlers respond with
+		// application/problem+json bodies (RFC 7807) when the client accepts JSON
+		ProblemJSON bool
+
+		// RawPathParams delivers path params exactly as they appear in the escaped
+		// request path, e.g. "john%20doe", instead of percent-decoding them
+		RawPathParams bool
+
+		// Logger receives the framework's own output: startup messages, internal
+		// errors such as failed certificate reloads, and panics and connection
+		// errors logged by the underlying http.Server unless ServerConfig.ErrorLog
+		// is set. If nil, slog.Default() is used.
+		Logger *slog.Logger
 	}
 
 	// Router represents a group of routes with a common path prefix and middleware.
 	Router struct {
-		path string
-		app  *App
-		mws  []Middleware
+		path       string
+		host       string
+		app        *App
+		parent     *Router
+		mws        []Middleware
+		onError    ErrorHandler
+		notFound   http.HandlerFunc
+		notAllowed http.HandlerFunc
+		fallback   fallbacks
 	}
 
 	// ServerConfig provides TLS and server address configuration.
@@ -108,10 +167,13 @@ type (
 		// Addr specifies the TCP address for the server to listen on
 		Addr string
 
-		// TLSConfig provides configuration for TLS connections
+		// TLSConfig provides configuration for TLS connections.
+		// If it sets Certificates or GetCertificate, the server uses TLS without CertFile and KeyFile.
 		TLSConfig *tls.Config
 
-		// CertFile and KeyFile are paths to TLS certificate and key files
+		// CertFile and KeyFile are paths to TLS certificate and key files.
+		// The files are checked for changes at most once per second, so a rotated
+		// certificate is served to new connections without restarting the server.
 		CertFile string
 		KeyFile  string
 
@@ -130,13 +192,76 @@ type (
 		// If both are zero, there is no timeout.
 		// Default: 0 (no timeout)
 		IdleTimeout time.Duration
+
+		// ReadHeaderTimeout is the amount of time allowed to read request headers.
+		// If ReadHeaderTimeout is zero, the value of ReadTimeout is used.
+		// Default: 0 (no timeout)
+		ReadHeaderTimeout time.Duration
+
+		// MaxHeaderBytes is the maximum number of bytes the server reads parsing
+		// the request headers, including the request line.
+		// Default: 0 (http.DefaultMaxHeaderBytes, 1 MB)
+		MaxHeaderBytes int
+
+		// ErrorLog logs errors accepting connections, unexpected handler behavior
+		// and underlying file system errors.
+		// Default: the app Logger (AppConfig.Logger), at error level
+		ErrorLog *log.Logger
+
+		// BaseContext returns the base context for incoming requests on l.
+		// Default: nil (context.Background())
+		BaseContext func(l net.Listener) context.Context
+
+		// ConnContext modifies the context used for a new connection c.
+		// Default: nil
+		ConnContext func(ctx context.Context, c net.Conn) context.Context
+
+		// ShutdownTimeout is the maximum duration ListenWithContext and ListenAndWait
+		// wait for in-flight requests to finish once shutdown begins.
+		// Default: 0 (wait for all requests)
+		ShutdownTimeout time.Duration
+
+		// H2C enables HTTP/2 over cleartext TCP, e.g. behind a load balancer that
+		// terminates TLS. It has no effect on TLS connections, which negotiate
+		// HTTP/2 on their own.
+		// Default: false
+		H2C bool
+
+		// HTTPRedirectAddr is the address of an additional plain HTTP listener,
+		// e.g. ":80", that permanently redirects every request to the HTTPS server.
+		// ACME HTTP-01 challenge paths are not redirected but served by the app.
+		// It is only used when the server uses TLS.
+		// Default: "" (no redirect listener)
+		HTTPRedirectAddr string
 	}
 
-	method uint8
+	method uint16
 	route  struct {
-		t    *tree
-		path string
-		mws  []Middleware
+		r          *Router
+		ms         []method
+		path       string
+		host       string
+		name       string
+		mws        []Middleware
+		deprecated *deprecation
+		// internal routes, such as those registered by Mount, are not listed by
+		// App.Routes and App.RouteList
+		internal bool
+	}
+	deprecation struct {
+		sunset time.Time
+		link   string
+	}
+	mount struct {
+		prefix string
+		app    *App
+	}
+	// fallbacks are the app's 404, 405 and automatic OPTIONS handlers wrapped in
+	// the middleware of a top-level router.
+	fallbacks struct {
+		notFound   http.HandlerFunc
+		notAllowed http.HandlerFunc
+		options    http.HandlerFunc
 	}
 )
 
@@ -146,17 +271,20 @@ const (
 	mPUT
 	mPATCH
 	mDELETE
+	mTRACE
+	mOPTIONS
 	mWEBSOCKET
 )
 
 var methodLookup = map[string]method{
-	http.MethodGet:    mGET,
-	http.MethodHead:   mGET,
-	http.MethodPost:   mPOST,
-	http.MethodPut:    mPUT,
-	http.MethodPatch:  mPATCH,
-	http.MethodDelete: mDELETE,
-	"WS":              mWEBSOCKET,
+	http.MethodGet:     mGET,
+	http.MethodHead:    mGET,
+	http.MethodPost:    mPOST,
+	http.MethodPut:     mPUT,
+	http.MethodPatch:   mPATCH,
+	http.MethodDelete:  mDELETE,
+	http.MethodOptions: mOPTIONS,
+	"WS":               mWEBSOCKET,
 }
 
 var reverseMethodLookup = map[method]string{
@@ -165,17 +293,23 @@ var reverseMethodLookup = map[method]string{
 	mPUT:       http.MethodPut,
 	mPATCH:     http.MethodPatch,
 	mDELETE:    http.MethodDelete,
+	mTRACE:     http.MethodTrace,
+	mOPTIONS:   http.MethodOptions,
 	mWEBSOCKET: "WS",
 }
 
-const maxTrees = mWEBSOCKET + 1
+var allMethods = []method{mGET, mPOST, mPUT, mPATCH, mDELETE, mTRACE, mOPTIONS, mWEBSOCKET}
 
-var paramKey = struct {
-	name string
-}{name: "reqParams"}
+// methodRegex matches valid HTTP method tokens (RFC 9110)
+var methodRegex = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
 
 var defaultAppConfig = AppConfig{
-	AllowTrace: false,
+	AllowTrace:            false,
+	RedirectTrailingSlash: false,
+	StrictRoutes:          false,
+	ProblemJSON:           false,
+	RawPathParams:         false,
+	Logger:                nil,
 }
 
 // New creates a new App instance with optional configuration.
@@ -191,110 +325,181 @@ func New(cfg ...AppConfig) *App {
 		config = cfg[0]
 	}
 	a := &App{
-		trees:      make(map[method]node),
+		names:      make(map[string]string),
 		cfg:        config,
 		options:    options,
 		notAllowed: notAllowed,
 		notFound:   notFound,
+		badRequest: badRequest,
+		onError:    internalError,
 	}
-	for i := method(0); i < maxTrees; i++ {
-		a.trees[i] = *newTree()
+	a.table.Store(newRouteTable())
+	if config.ProblemJSON {
+		a.notFound = problemNotFound(a.notFound)
+		a.notAllowed = problemNotAllowed(a.notAllowed)
+		a.badRequest = problemBadRequest(a.badRequest)
+		a.onError = problemInternalError(a.onError)
 	}
+	a.onError = a.logErrors(a.onError)
 	return a
 }
 
-// Listen starts the HTTP server on the specified port with optional configuration.
-// The server will use the following defaults if not specified:
-//   - ReadTimeout: 0 (no timeout)
-//   - WriteTimeout: 0 (no timeout)
-//   - IdleTimeout: 0 (no timeout)
-//
-// Example:
-//
-//	// Basic usage
-//	app.Listen(8080)
-//
-//	// With timeouts
-//	app.Listen(8080, ServerConfig{
-//	    ReadTimeout: 5 * time.Second,
-//	    WriteTimeout: 10 * time.Second,
-//	    IdleTimeout: 120 * time.Second,
-//	})
-//
-//	// With TLS
-//	app.Listen(443, ServerConfig{
-//	    CertFile: "cert.pem",
-//	    KeyFile: "key.pem",
-//	    ReadTimeout: 5 * time.Second,
-//	    WriteTimeout: 10 * time.Second,
-//	    IdleTimeout: 120 * time.Second,
-//	})
-func (a *App) Listen(port int, cfg ...ServerConfig) error {
-	server := &http.Server{
-		Addr:    ":" + strconv.Itoa(port),
-		Handler: a,
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.prepare.Do(a.build)
+	// Attach the locals and params store once, for middleware and handlers alike
+	r, st := attachState(a, r)
+	// Discard HEAD response bodies while keeping the headers of the GET response
+	if r.Method == http.MethodHead {
+		hw := &headWriter{ResponseWriter: w}
+		a.handler(hw, r)
+		hw.commit()
+		releaseState(st)
+		return
 	}
+	a.handler(w, r)
+	releaseState(st)
+}
 
-	// chain middlewares for global handlers
-	a.notAllowed = chainMws(a.rootRouter.mws, a.notAllowed)
-	a.notFound = chainMws(a.rootRouter.mws, a.notFound)
-	a.options = chainMws(a.rootRouter.mws, a.options)
-
-	if len(cfg) > 0 {
-		if cfg[0].ReadTimeout > 0 {
-			server.ReadTimeout = cfg[0].ReadTimeout
+// build chains the app middleware and each top-level router's middleware around
+// the fallback handlers, using the nearest 404 and 405 handlers set on a router or
+// group. It runs once, before the first request is served.
+func (a *App) build() {
+	a.handler = chainMws(a.mws, a.internalHandler)
+	for _, rt := range slices.Concat(a.routers, a.groups) {
+		root := rt
+		for root.parent != nil {
+			root = root.parent
 		}
-		if cfg[0].WriteTimeout > 0 {
-			server.WriteTimeout = cfg[0].WriteTimeout
-		}
-		if cfg[0].IdleTimeout > 0 {
-			server.IdleTimeout = cfg[0].IdleTimeout
-		}
-		if cfg[0].TLSConfig != nil {
-			server.TLSConfig = cfg[0].TLSConfig
-		}
-		if cfg[0].CertFile != "" && cfg[0].KeyFile != "" {
-			if server.TLSConfig == nil {
-				server.TLSConfig = &tls.Config{
-					MinVersion: tls.VersionTLS12,
-					NextProtos: []string{"h2", "http/1.1"},
-				}
+		notFound, notAllowed := rt.notFoundHandler(), a.notAllowed
+		for cur := rt; cur != nil; cur = cur.parent {
+			if cur.notAllowed != nil {
+				notAllowed = cur.notAllowed
+				break
 			}
-			log.Printf("server listening on port :%d", port)
-			return server.ListenAndServeTLS(cfg[0].CertFile, cfg[0].KeyFile)
+		}
+		rt.fallback = fallbacks{
+			notFound:   chainMws(root.mws, notFound),
+			notAllowed: chainMws(root.mws, notAllowed),
+			options:    chainMws(root.mws, a.options),
 		}
 	}
+}
 
-	log.Printf("server listening on port :%d", port)
-	return server.ListenAndServe()
+// Use appends app-level middleware, which runs for every request before routing,
+// including requests answered by the 404, 405, OPTIONS and TRACE handlers and by
+// mounted apps. Route params and the route pattern are not available to it yet.
+// It must be called before the app starts serving.
+//
+// Example:
+//
+//	app := velocity.New()
+//	app.Use(middleware.RequestID(), middleware.Logger())
+func (a *App) Use(mws ...Middleware) {
+	a.mws = append(a.mws, mws...)
 }
 
-func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.internalHandler(w, r)
+// Mount composes sub into the app under the given path prefix, with optional middleware.
+// Requests under the prefix are delegated to sub with the prefix stripped, so sub keeps
+// its own routes, middleware, error handlers and 404/405 responses. Custom methods
+// registered on sub before the call are delegated as well. Routes and RouteList list
+// the routes of sub under the prefix. It returns an error if the prefix is invalid or,
+// with AppConfig.StrictRoutes, conflicts with an existing route.
+//
+// Example:
+//
+//	billing := velocity.New()
+//	billing.Router("/", billingAuth).Get("/invoices").Handle(listInvoices)
+//
+//	err := app.Mount("/billing", billing)
+func (a *App) Mount(p string, sub *App, mws ...Middleware) error {
+	prefix := cleanPath(p)
+	r := &Router{path: "/", app: a}
+	errs := []error{r.Mount(prefix, sub, mws...)}
+	subTable := sub.table.Load()
+	fn := func(w http.ResponseWriter, req *http.Request) {
+		sub.ServeHTTP(w, stripPrefix(req))
+	}
+	for m := method(len(allMethods)); int(m) < len(subTable.methodNames); m++ {
+		for _, mp := range []string{prefix, prefix + "/*"} {
+			mr := r.Method(subTable.methodNames[m], mp, mws...)
+			mr.internal = true
+			errs = append(errs, mr.Handle(fn))
+		}
+	}
+	a.mounts = append(a.mounts, mount{prefix: prefix, app: sub})
+	return errors.Join(errs...)
 }
 
 // Router creates a new router group with the given path prefix and optional middleware.
+// An app may have several top-level routers; requests that match no route are passed
+// through the middleware of the router with the longest matching prefix before
+// reaching the NotFound, NotAllowed or automatic OPTIONS handlers.
 //
 // Example:
 //
-//	router := app.Router("/api", authMiddleware)
+//	api := app.Router("/api", authMiddleware)
+//	admin := app.Router("/admin", adminMiddleware)
 func (a *App) Router(path string, mws ...Middleware) *Router {
 	r := &Router{
-		path: path,
+		path: cleanPath(path),
 		app:  a,
 		mws:  mws,
 	}
-	a.rootRouter = r
+	a.routers = append(a.routers, r)
+	return r
+}
+
+// Host creates a new router whose routes only match requests for the given host,
+// with optional middleware. Ports are ignored when matching and host routes take
+// precedence over routes registered without a host. A leading "*" label matches any
+// single subdomain, which handlers receive as the "subdomain" param; exact hosts take
+// precedence over wildcard hosts.
+//
+// Example:
+//
+//	api := app.Host("api.example.com")
+//	api.Get("/users").Handle(handler)
+//
+//	tenants := app.Host("*.internal.example.com")
+//	tenants.Get("/").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    tenant := velocity.GetParams(r)["subdomain"]
+//	})
+func (a *App) Host(host string, mws ...Middleware) *Router {
+	r := &Router{
+		path: "/",
+		host: strings.ToLower(host),
+		app:  a,
+		mws:  mws,
+	}
+	a.routers = append(a.routers, r)
 	return r
 }
 
 // Routes returns all registered routes. If print is true, routes are also printed to stdout.
+// Routes registered for a host are prefixed with it, e.g. "GET api.example.com/users", and
+// routes of apps attached with App.Mount are listed under their mount prefix.
 func (a *App) Routes(print ...bool) []string {
 	r := []string{}
-	for l, t := range a.trees {
-		m := reverseMethodLookup[l]
+	rt := a.table.Load()
+	for l, t := range rt.trees {
+		m := rt.methodNames[l]
 		r = append(r, t.captureRoutes(m)...)
 	}
+	for host, trees := range rt.hosts {
+		for l, t := range trees {
+			m := rt.methodNames[l]
+			for _, route := range t.captureRoutes(m) {
+				r = append(r, m+" "+host+strings.TrimPrefix(route, m+" "))
+			}
+		}
+	}
+	for _, mt := range a.mounts {
+		for _, route := range mt.app.Routes() {
+			m, rest, _ := strings.Cut(route, " ")
+			host, path, _ := strings.Cut(rest, "/")
+			r = append(r, m+" "+host+cleanPath(mt.prefix+"/"+path))
+		}
+	}
 	slices.Sort(r)
 	if len(print) > 0 && print[0] {
 		for _, r := range r {
@@ -305,15 +510,86 @@ func (a *App) Routes(print ...bool) []string {
 }
 
 // NotAllowed sets a custom handler for method not allowed responses (405).
+// It must be set before the app starts serving requests.
 func (a *App) NotAllowed(h http.HandlerFunc) {
 	a.notAllowed = h
 }
 
 // NotFound sets a custom handler for not found responses (404).
+// It must be set before the app starts serving requests.
 func (a *App) NotFound(h http.HandlerFunc) {
 	a.notFound = h
 }
 
+// ErrorHandler sets the app-level handler for errors returned by routes registered
+// with HandleE. It is used when no group in the route's hierarchy sets its own handler.
+// It replaces the default handler, which answers with the status and message of an
+// HTTPError or BindError, and with 500 Internal Server Error for other errors, which
+// are also logged with the app logger.
+func (a *App) ErrorHandler(h ErrorHandler) {
+	a.onError = h
+}
+
+// OnError sets the error handler for routes registered on this router and its groups.
+// The nearest router with an error handler wins, falling back to the app-level handler.
+//
+// Example:
+//
+//	api := router.Group("/api")
+//	api.OnError(func(w http.ResponseWriter, r *http.Request, err error) {
+//	    w.WriteHeader(http.StatusInternalServerError)
+//	    json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+//	})
+func (r *Router) OnError(h ErrorHandler) {
+	r.onError = h
+}
+
+// NotFound sets the handler for 404 responses to requests under the router's path
+// and host, so e.g. "/api" can answer with JSON while "/web" renders an HTML page.
+// The most specific router or group with a handler wins, falling back to the
+// app-level handler. Like app-level handlers, it is wrapped in the middleware of
+// the matching top-level router. It must be set before the app starts serving.
+//
+// Example:
+//
+//	api := router.Group("/api")
+//	api.NotFound(func(w http.ResponseWriter, r *http.Request) {
+//	    velocity.WriteProblem(w, velocity.Problem{Status: http.StatusNotFound})
+//	})
+func (r *Router) NotFound(h http.HandlerFunc) {
+	r.notFound = h
+	r.track()
+}
+
+// NotAllowed sets the handler for 405 responses to requests under the router's path
+// and host, resolved like Router.NotFound. The Allow header is set before it runs.
+// It must be set before the app starts serving.
+func (r *Router) NotAllowed(h http.HandlerFunc) {
+	r.notAllowed = h
+	r.track()
+}
+
+// track registers a group with its own fallback handlers with the app, so requests
+// under its path can be matched to it.
+func (r *Router) track() {
+	if r.parent != nil && !slices.Contains(r.app.groups, r) {
+		r.app.groups = append(r.app.groups, r)
+	}
+}
+
+// Use appends middleware to the router. It only applies to routes registered after
+// the call, so it should be called before any routes are added.
+//
+// Example:
+//
+//	router := app.Router("/api")
+//	if production {
+//	    router.Use(middleware.Logger())
+//	}
+func (r *Router) Use(mws ...Middleware) {
+	r.mws = append(r.mws, mws...)
+}
+
 // Group creates a new router group with additional path prefix and optional middleware.
 //
 // Example:
@@ -321,54 +597,214 @@ func (a *App) NotFound(h http.HandlerFunc) {
 //	api := router.Group("/v1", authMiddleware)
 func (r *Router) Group(path string, mws ...Middleware) *Router {
 	return &Router{
-		path: cleanPath(r.path + path),
-		app:  r.app,
-		mws:  mws,
+		path:   cleanPath(r.path + path),
+		host:   r.host,
+		app:    r.app,
+		parent: r,
+		mws:    mws,
 	}
 }
 
 // Get registers a new GET route with the given path and optional middleware.
 func (r *Router) Get(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mGET), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mGET)
 }
 
 // Post registers a new POST route with the given path and optional middleware.
 func (r *Router) Post(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mPOST), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mPOST)
 }
 
 // Put registers a new PUT route with the given path and optional middleware.
 func (r *Router) Put(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mPUT), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mPUT)
 }
 
 // Patch registers a new PATCH route with the given path and optional middleware.
 func (r *Router) Patch(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mPATCH), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mPATCH)
 }
 
 // Delete registers a new DELETE route with the given path and optional middleware.
 func (r *Router) Delete(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mDELETE), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mDELETE)
+}
+
+// Any registers a new route for GET, POST, PUT, PATCH and DELETE with the given
+// path and optional middleware.
+//
+// Example:
+//
+//	router.Any("/webhook").Handle(handler)
+func (r *Router) Any(p string, mws ...Middleware) route {
+	return r.newRoute(cleanPath(r.path+p), mws, mGET, mPOST, mPUT, mPATCH, mDELETE)
+}
+
+// Match registers a new route for the given HTTP methods with the given path and
+// optional middleware. Unsupported methods are ignored.
+//
+// Example:
+//
+//	router.Match([]string{http.MethodGet, http.MethodPost}, "/form").Handle(handler)
+func (r *Router) Match(methods []string, p string, mws ...Middleware) route {
+	ms := []method{}
+	for _, name := range methods {
+		if m, ok := r.app.method(strings.ToUpper(name)); ok && !slices.Contains(ms, m) {
+			ms = append(ms, m)
+		}
+	}
+	return r.newRoute(cleanPath(r.path+p), mws, ms...)
+}
+
+// Method registers a new route for an arbitrary HTTP method, such as the WebDAV
+// PROPFIND or MKCOL methods, with the given path and optional middleware.
+// Method names are case-sensitive; invalid method names are ignored.
+//
+// Example:
+//
+//	router.Method("PROPFIND", "/files/*").Handle(handler)
+func (r *Router) Method(name string, p string, mws ...Middleware) route {
+	m, ok := r.app.method(name)
+	if !ok {
+		return r.newRoute(cleanPath(r.path+p), mws)
+	}
+	return r.newRoute(cleanPath(r.path+p), mws, m)
+}
+
+// Trace registers a new TRACE route with the given path and optional middleware.
+// Registered TRACE routes take precedence over the automatic reflection enabled
+// by AppConfig.AllowTrace.
+func (r *Router) Trace(p string, mws ...Middleware) route {
+	return r.newRoute(cleanPath(r.path+p), mws, mTRACE)
+}
+
+// Options registers a new OPTIONS route with the given path and optional middleware.
+// Registered OPTIONS routes take precedence over the automatic OPTIONS handling.
+func (r *Router) Options(p string, mws ...Middleware) route {
+	return r.newRoute(cleanPath(r.path+p), mws, mOPTIONS)
 }
 
 // Websocket registers a new WebSocket route with the given path and optional middleware.
+// It matches GET requests that ask for a WebSocket upgrade, which take precedence
+// over GET routes of the same path; the request method is left unchanged. The
+// route's middleware runs before the handler performs the handshake.
 func (r *Router) Websocket(p string, mws ...Middleware) route {
-	return route{t: r.getTree(mWEBSOCKET), path: cleanPath(r.path + p), mws: append(r.mws, mws...)}
+	return r.newRoute(cleanPath(r.path+p), mws, mWEBSOCKET)
+}
+
+// Mount delegates all requests under the given path prefix to h, for every method,
+// with optional middleware. The prefix is stripped from the request path before h
+// is called, so h sees paths relative to the mount point. The delegating routes are
+// not listed by App.Routes and App.RouteList. It returns an error if the prefix is
+// invalid or, with AppConfig.StrictRoutes, conflicts with an existing route.
+//
+// Example:
+//
+//	router.Mount("/metrics", promhttp.Handler())
+//	router.Mount("/legacy", legacyMux, authMiddleware)
+func (r *Router) Mount(p string, h http.Handler, mws ...Middleware) error {
+	prefix := cleanPath(r.path + p)
+	fn := func(w http.ResponseWriter, req *http.Request) {
+		h.ServeHTTP(w, stripPrefix(req))
+	}
+	errs := []error{}
+	for _, mp := range []string{prefix, cleanPath(prefix + "/*")} {
+		mr := r.newRoute(mp, mws, allMethods...)
+		mr.internal = true
+		errs = append(errs, mr.Handle(fn))
+	}
+	return errors.Join(errs...)
 }
 
-// Handle registers the handler function for the route.
+// Handle registers the handler function for the route. It returns an error if the
+// path is invalid, in which case the route is not registered, or if
+// AppConfig.StrictRoutes is set and the route conflicts with an existing one.
+// Routes should be registered before the app starts serving; use App.AddRoute to
+// add routes at runtime.
 //
 // Example:
 //
 //	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
 //	    // handler logic
 //	})
-func (r route) Handle(h http.HandlerFunc) {
-	r.t.insert(r.path, chainMws(r.mws, h))
+func (r route) Handle(h http.HandlerFunc) error {
+	return r.handle(h)
+}
+
+func (r route) handle(h http.HandlerFunc) error {
+	mws := r.mws
+	if r.deprecated != nil {
+		mws = append([]Middleware{r.deprecated.middleware()}, mws...)
+	}
+	fn := chainMws(mws, h)
+	app := r.r.app
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	meta := &routeMeta{router: r.r, host: r.host, name: r.name, mws: len(r.mws), deprecated: r.deprecated, internal: r.internal}
+	return app.register(app.table.Load(), r.ms, r.path, fn, meta, false)
+}
+
+// HandleE registers a handler function that may return an error. Returned errors are
+// passed to the nearest error handler set with Router.OnError, or App.ErrorHandler.
+//
+// Example:
+//
+//	router.Get("/users/:id").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    user, err := findUser(velocity.GetParams(r)["id"])
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return json.NewEncoder(w).Encode(user)
+//	})
+func (r route) HandleE(h func(w http.ResponseWriter, r *http.Request) error) error {
+	rt := r.r
+	return r.handle(func(w http.ResponseWriter, req *http.Request) {
+		if err := h(w, req); err != nil {
+			rt.errorHandler()(w, req, err)
+		}
+	})
+}
+
+// Name assigns a name to the route so its URL can be built with App.URL. The name
+// is recorded once the route is registered and dropped when App.RemoveRoute removes
+// the route.
+//
+// Example:
+//
+//	router.Get("/users/:id").Name("user.show").Handle(handler)
+//	url, err := app.URL("user.show", map[string]string{"id": "42"}) // "/users/42"
+func (r route) Name(name string) route {
+	r.name = name
+	return r
 }
 
-// GetParams retrieves URL parameters from the request context.
+// Host restricts the route to requests for the given host, overriding the host of its
+// router. Patterns follow the same rules as App.Host, including "*" subdomain wildcards.
+//
+// Example:
+//
+//	router.Get("/dashboard").Host("*.example.com").Handle(tenantDashboard)
+func (r route) Host(host string) route {
+	r.host = strings.ToLower(host)
+	return r
+}
+
+// Deprecated marks the route as deprecated. Responses include the Deprecation header,
+// a Sunset header (RFC 8594) when sunset is non-zero, and a Link header pointing to
+// migration docs when link is non-empty.
+//
+// Example:
+//
+//	router.Get("/v1/users").
+//	    Deprecated(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "https://example.com/migrate").
+//	    Handle(handler)
+func (r route) Deprecated(sunset time.Time, link string) route {
+	r.deprecated = &deprecation{sunset: sunset, link: link}
+	return r
+}
+
+// GetParams retrieves URL parameters from the request context as a new map. Use
+// Param to read single params without allocating.
 //
 // Example:
 //
@@ -377,66 +813,386 @@ func (r route) Handle(h http.HandlerFunc) {
 //	    userID := params["id"]
 //	})
 func GetParams(r *http.Request) map[string]string {
-	p, ok := r.Context().Value(paramKey).(map[string]string)
-	if !ok {
+	rp := requestParams(r)
+	if rp == nil {
 		return map[string]string{}
 	}
-	return p
+	return rp.toMap()
+}
+
+// GetRoutePattern returns the pattern of the route that matched the request, e.g.
+// "/users/:id" for a request to "/users/42", which is suited for metrics labels and
+// logging. It returns an empty string before a route has matched, e.g. in 404 handlers.
+//
+// Example:
+//
+//	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    pattern := velocity.GetRoutePattern(r) // "/users/:id"
+//	})
+func GetRoutePattern(r *http.Request) string {
+	return r.Pattern
+}
+
+// ClientGone reports whether the request context is done, either because the client
+// disconnected or a deadline was exceeded. Long-running handlers should check it
+// periodically, e.g. inside loops, and stop work once it returns true.
+//
+// Example:
+//
+//	for _, item := range items {
+//	    if velocity.ClientGone(r) {
+//	        return
+//	    }
+//	    process(item)
+//	}
+func ClientGone(r *http.Request) bool {
+	return r.Context().Err() != nil
 }
 
 func (a *App) internalHandler(w http.ResponseWriter, r *http.Request) {
-	// Handle TRACE method automatically if enabled
-	if r.Method == http.MethodTrace && a.cfg.AllowTrace {
-		w.Header().Set("Content-Type", "message/http")
-		w.Write([]byte(fmt.Sprintf("%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)))
-		for header, values := range r.Header {
-			w.Write([]byte(fmt.Sprintf("%s: %s\r\n", header, strings.Join(values, ", "))))
-		}
+	st := requestState(r)
+	if st == nil {
+		// Middleware replaced the request context with one not derived from it
+		r, st = attachState(a, r)
+		defer releaseState(st)
+	}
+	rp := &st.params
+	// Handle TRACE method, preferring registered routes over automatic reflection
+	if r.Method == http.MethodTrace {
+		a.trace(w, r, rp)
 		return
 	}
-	// Handle OPTIONS method automatically
+	// Handle OPTIONS method, falling back to automatic handling
 	if r.Method == http.MethodOptions {
-		a.options(w, r)
+		if e := a.lookup(mOPTIONS, r, rp); e != nil {
+			a.serve(w, r, e, rp)
+			return
+		}
+		if allowed := a.allowedMethods(r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			a.fallbacks(r).options(w, r)
+			return
+		}
+		a.fallbacks(r).notFound(w, r)
 		return
 	}
-	// Check for WebSocket upgrade
-	if connection := r.Header.Get("Connection"); connection != "" {
-		if upgrade := r.Header.Get("Upgrade"); upgrade != "" {
-			if strings.EqualFold(upgrade, "websocket") {
-				r.Method = "WS"
+	// WebSocket upgrades are served by Websocket routes with the request untouched,
+	// falling back to GET routes
+	var e *endpoint
+	if IsWebSocketUpgrade(r) {
+		e = a.lookup(mWEBSOCKET, r, rp)
+	}
+	if e == nil {
+		// Get method from request; "WS" only names Websocket routes
+		m, ok := a.table.Load().methods[r.Method]
+		if !ok || m == mWEBSOCKET {
+			a.setAllow(w, r)
+			a.fallbacks(r).notAllowed(w, r)
+			return
+		}
+		// Find endpoint, answering 405 when the path exists under other methods
+		e = a.lookup(m, r, rp)
+		if e == nil {
+			if allowed := a.allowedMethods(r); len(allowed) > 0 {
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				a.fallbacks(r).notAllowed(w, r)
+				return
 			}
+			a.fallbacks(r).notFound(w, r)
+			return
 		}
 	}
-	// Get method from request
-	m, ok := methodLookup[r.Method]
-	if !ok {
-		a.notAllowed(w, r)
+	if a.cfg.RedirectTrailingSlash && !e.catchAll() {
+		if clean := cleanPath(r.URL.Path); clean != r.URL.Path {
+			redirectCanonical(w, r, clean)
+			return
+		}
+	}
+	a.serve(w, r, e, rp)
+}
+
+func (a *App) serve(w http.ResponseWriter, r *http.Request, e *endpoint, rp *routeParams) {
+	// Record the matched route like http.ServeMux does, without allocating
+	r.Pattern = e.fullPath
+	if st := requestState(r); st != nil {
+		st.router = e.meta.router
+	}
+	// Nothing to decode or convert for routes without params
+	if len(rp.pairs) == 0 {
+		e.fn(w, r)
 		return
 	}
-	// Get tree for method
-	t, ok := a.trees[m]
-	if !ok {
-		a.notFound(w, r)
+	if err := a.decodeParams(r, rp); err != nil {
+		a.badRequest(w, r)
 		return
 	}
-	// Find endpoint
-	e, p := t.find(r.URL.Path)
-	if e == nil {
-		a.notFound(w, r)
+	if e.typed {
+		tp, err := e.convertParams(rp)
+		if err != nil {
+			a.badRequest(w, r)
+			return
+		}
+		rp.typed = tp
+	}
+	e.fn(w, r)
+}
+
+func (a *App) trace(w http.ResponseWriter, r *http.Request, rp *routeParams) {
+	if e := a.lookup(mTRACE, r, rp); e != nil {
+		a.serve(w, r, e, rp)
+		return
+	}
+	if a.cfg.AllowTrace {
+		w.Header().Set("Content-Type", "message/http")
+		w.Write([]byte(fmt.Sprintf("%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)))
+		for header, values := range r.Header {
+			w.Write([]byte(fmt.Sprintf("%s: %s\r\n", header, strings.Join(values, ", "))))
+		}
 		return
 	}
-	ctx := context.WithValue(r.Context(), paramKey, p)
-	// Execute handler
-	e.fn(w, r.WithContext(ctx))
+	a.setAllow(w, r)
+	a.fallbacks(r).notAllowed(w, r)
+}
+
+// fallbacks returns the fallback handlers of the router or group with its own
+// handlers that best matches the request: host routers win over routers without a
+// host, then the longest path prefix wins. Without a matching router, the unwrapped
+// app handlers are returned.
+func (a *App) fallbacks(r *http.Request) fallbacks {
+	var best *Router
+	host := ""
+	if len(a.routers) > 0 {
+		host = requestHost(r)
+	}
+	match := func(rt *Router) {
+		if rt.host != "" && !matchHost(rt.host, host) {
+			return
+		}
+		if !hasPathPrefix(r.URL.Path, rt.path) {
+			return
+		}
+		if best == nil || rt.moreSpecific(best) {
+			best = rt
+		}
+	}
+	// Groups come first so they win ties with a router of the same path
+	for _, rt := range a.groups {
+		match(rt)
+	}
+	for _, rt := range a.routers {
+		match(rt)
+	}
+	if best == nil {
+		return fallbacks{notFound: a.notFound, notAllowed: a.notAllowed, options: a.options}
+	}
+	return best.fallback
 }
 
-func (r *Router) getTree(m method) *node {
-	if n, ok := r.app.trees[m]; ok {
-		return &n
+// lookup returns the endpoint matching the request, capturing its params into rp
+// unless rp is nil.
+func (a *App) lookup(m method, r *http.Request, rp *routeParams) *endpoint {
+	rt := a.table.Load()
+	p := a.routingPath(r)
+	if len(rt.hosts) > 0 {
+		host := requestHost(r)
+		if trees, ok := rt.hosts[host]; ok {
+			if t, ok := trees[m]; ok {
+				if e := t.find(p, rp); e != nil {
+					return e
+				}
+			}
+		}
+		if sub, wildcard, ok := splitSubdomain(host); ok {
+			if trees, ok := rt.hosts[wildcard]; ok {
+				if t, ok := trees[m]; ok {
+					if e := t.find(p, rp); e != nil {
+						if rp != nil {
+							if _, ok := rp.get(subdomainParam); !ok {
+								rp.set(subdomainParam, sub)
+							}
+						}
+						return e
+					}
+				}
+			}
+		}
+	}
+	if t, ok := rt.trees[m]; ok {
+		return t.find(p, rp)
 	}
 	return nil
 }
 
+// decodeParams percent-decodes params matched against the escaped request path.
+func (a *App) decodeParams(r *http.Request, rp *routeParams) error {
+	if a.cfg.RawPathParams || r.URL.RawPath == "" {
+		return nil
+	}
+	for i, p := range rp.pairs {
+		decoded, err := url.PathUnescape(p.value)
+		if err != nil {
+			return err
+		}
+		rp.pairs[i].value = decoded
+	}
+	return nil
+}
+
+// routingPath returns the path routes are matched against. Paths with encoded
+// characters that change their meaning when decoded, such as "%2F", are matched in
+// their escaped form so those characters stay within a single param.
+func (a *App) routingPath(r *http.Request) string {
+	if a.cfg.RawPathParams {
+		return r.URL.EscapedPath()
+	}
+	if r.URL.RawPath != "" {
+		return r.URL.RawPath
+	}
+	return r.URL.Path
+}
+
+// allowedMethods returns the methods with a route matching the request path, in
+// registration order of the methods. HEAD follows GET, and OPTIONS is always
+// included when any route matches since it is handled automatically.
+func (a *App) allowedMethods(r *http.Request) []string {
+	rt := a.table.Load()
+	allowed := []string{}
+	for m := method(0); int(m) < len(rt.methodNames); m++ {
+		if m == mWEBSOCKET {
+			continue
+		}
+		if a.lookup(m, r, nil) == nil {
+			continue
+		}
+		allowed = append(allowed, rt.methodNames[m])
+		if m == mGET {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+// setAllow sets the Allow header to the methods allowed for the request path.
+func (a *App) setAllow(w http.ResponseWriter, r *http.Request) {
+	if allowed := a.allowedMethods(r); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	}
+}
+
+// method returns the method for name, allocating a new one for valid custom methods.
+func (a *App) method(name string) (method, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.table.Load().method(name)
+}
+
+func (r *Router) newRoute(p string, mws []Middleware, ms ...method) route {
+	return route{r: r, ms: ms, path: p, host: r.host, mws: slices.Concat(r.mws, mws)}
+}
+
+// stripPrefix returns a shallow copy of r whose URL path is the catch-all
+// remainder of a mounted route.
+func stripPrefix(r *http.Request) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = "/" + GetParams(r)["*"]
+	r2.URL.RawPath = ""
+	return r2
+}
+
+// moreSpecific reports whether r should handle fallbacks instead of other.
+func (r *Router) moreSpecific(other *Router) bool {
+	if (r.host != "") != (other.host != "") {
+		return r.host != ""
+	}
+	if rw, ow := strings.HasPrefix(r.host, "*"), strings.HasPrefix(other.host, "*"); rw != ow {
+		return ow
+	}
+	return len(r.path) > len(other.path)
+}
+
+// hasPathPrefix reports whether p is prefix or lies below it, segment-wise.
+func hasPathPrefix(p, prefix string) bool {
+	if prefix == "/" || p == prefix {
+		return true
+	}
+	return strings.HasPrefix(p, prefix+"/")
+}
+
+func redirectCanonical(w http.ResponseWriter, r *http.Request, p string) {
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	u := *r.URL
+	u.Path = p
+	u.RawPath = ""
+	http.Redirect(w, r, u.RequestURI(), code)
+}
+
+func newTrees() map[method]*tree {
+	trees := make(map[method]*tree, len(allMethods))
+	for _, m := range allMethods {
+		trees[m] = newTree()
+	}
+	return trees
+}
+
+// subdomainParam is the param holding the subdomain matched by a wildcard host.
+const subdomainParam = "subdomain"
+
+// splitSubdomain splits host into its first label and the wildcard pattern matching
+// it, e.g. "acme.example.com" into "acme" and "*.example.com".
+func splitSubdomain(host string) (string, string, bool) {
+	i := strings.IndexByte(host, '.')
+	if i <= 0 {
+		return "", "", false
+	}
+	return host[:i], "*" + host[i:], true
+}
+
+// matchHost reports whether host matches pattern, which may start with a "*" label.
+func matchHost(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	_, wildcard, ok := splitSubdomain(host)
+	return ok && pattern == wildcard
+}
+
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// notFoundHandler returns the nearest 404 handler set on the router or its parents,
+// or the app-level handler.
+func (r *Router) notFoundHandler() http.HandlerFunc {
+	for cur := r; cur != nil; cur = cur.parent {
+		if cur.notFound != nil {
+			return cur.notFound
+		}
+	}
+	return r.app.notFound
+}
+
+func (r *Router) errorHandler() ErrorHandler {
+	for cur := r; cur != nil; cur = cur.parent {
+		if cur.onError != nil {
+			return cur.onError
+		}
+	}
+	return r.app.onError
+}
+
 func chainMws(mws []Middleware, fn http.HandlerFunc) http.HandlerFunc {
 	handler := fn
 	for i := len(mws) - 1; i >= 0; i-- {
@@ -449,15 +1205,49 @@ func chainMws(mws []Middleware, fn http.HandlerFunc) http.HandlerFunc {
 	return handler
 }
 
-// Empty as this is handled by CORS
-func options(w http.ResponseWriter, r *http.Request) {}
+func (d *deprecation) middleware() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if !d.sunset.IsZero() {
+				w.Header().Set("Sunset", d.sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.link != "" {
+				w.Header().Add("Link", "<"+d.link+">; rel=\"deprecation\"")
+			}
+			next(w, r)
+		}
+	}
+}
+
+// options answers automatic OPTIONS requests; the Allow header is already set and
+// CORS headers are added by the CORS middleware.
+func options(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
 
 func notFound(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte("Not found"))
 }
 
+func badRequest(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte("Bad request"))
+}
+
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	var p *Problem
+	if errors.As(err, &p) {
+		WriteProblem(w, *p)
+		return
+	}
+	status, msg := errorStatus(err)
+	w.WriteHeader(status)
+	w.Write([]byte(msg))
+}
+
 func notAllowed(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusMethodNotAllowed)
-	w.Write([]byte("Not found"))
+	w.Write([]byte("Method not allowed"))
 }
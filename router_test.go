@@ -1,12 +1,16 @@
 package velocity_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Juanfec4/velocity"
 )
@@ -373,6 +377,11 @@ func TestParamValidation(t *testing.T) {
 		{"starts with number", "/users/:1user", false},
 		{"contains special char", "/users/:user@id", false},
 		{"empty", "/users/:", false},
+		{"valid regex constraint", "/users/:id([0-9]+)", true},
+		{"invalid regex constraint", "/users/:id([0-9+)", false},
+		{"empty regex constraint", "/users/:id()", false},
+		{"valid type", "/users/:id<int>", true},
+		{"unknown type", "/users/:id<decimal>", false},
 		{"just letters", "/users/userid", true},
 	}
 
@@ -436,3 +445,1344 @@ func TestCatchAllValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestTraceRoute(t *testing.T) {
+	app := velocity.New(velocity.AppConfig{AllowTrace: true})
+	router := app.Router("/")
+
+	router.Trace("/diagnostics").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("custom trace"))
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedType string
+		expectedBody string
+	}{
+		{"registered route", "/diagnostics", "text/plain", "custom trace"},
+		{"automatic reflection", "/other", "message/http", "TRACE /other HTTP/1.1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodTrace, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if ct := rec.Header().Get("Content-Type"); ct != tt.expectedType {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, ct)
+			}
+			if !strings.HasPrefix(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected body to start with %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeprecatedRoute(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	router.Get("/v1/users").
+		Deprecated(sunset, "https://example.com/migrate").
+		Handle(func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/v2/users").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation header %q, got %q", "true", got)
+	}
+	if got, exp := rec.Header().Get("Sunset"), "Tue, 01 Jan 2030 00:00:00 GMT"; got != exp {
+		t.Errorf("expected Sunset header %q, got %q", exp, got)
+	}
+	if got, exp := rec.Header().Get("Link"), `<https://example.com/migrate>; rel="deprecation"`; got != exp {
+		t.Errorf("expected Link header %q, got %q", exp, got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/users", nil)
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	for _, h := range []string{"Deprecation", "Sunset", "Link"} {
+		if got := rec.Header().Get(h); got != "" {
+			t.Errorf("expected no %s header, got %q", h, got)
+		}
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if velocity.ClientGone(req) {
+		t.Error("expected ClientGone to be false for an active request")
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	if !velocity.ClientGone(req.WithContext(ctx)) {
+		t.Error("expected ClientGone to be true for a cancelled request")
+	}
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+
+	app.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("app: " + err.Error()))
+	})
+
+	api := router.Group("/api")
+	api.OnError(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	})
+
+	web := router.Group("/web")
+	web.OnError(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<p>" + err.Error() + "</p>"))
+	})
+
+	failing := func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	}
+	api.Group("/v1").Get("/users").HandleE(failing)
+	web.Get("/home").HandleE(failing)
+	router.Get("/plain").HandleE(failing)
+
+	tests := []struct {
+		path         string
+		expectedType string
+		expectedBody string
+	}{
+		{"/api/v1/users", "application/json", `{"error":"boom"}`},
+		{"/web/home", "text/html", "<p>boom</p>"},
+		{"/plain", "", "app: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); tt.expectedType != "" && ct != tt.expectedType {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, ct)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestGroupFallbackHandlers(t *testing.T) {
+	app := velocity.New()
+	app.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("app not found"))
+	})
+	app.NotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("app not allowed"))
+	})
+	router := app.Router("/", func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Router", "root")
+			next(w, r)
+		}
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/home").Handle(handler)
+
+	api := router.Group("/api")
+	api.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	})
+	api.NotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error":"not allowed"}`))
+	})
+	api.Group("/v1").Get("/users").Handle(handler)
+
+	web := router.Group("/web")
+	web.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<p>not found</p>"))
+	})
+	web.Get("/about").Handle(handler)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"group 404", http.MethodGet, "/api/missing", http.StatusNotFound, `{"error":"not found"}`},
+		{"inherited by nested group", http.MethodGet, "/api/v1/missing", http.StatusNotFound, `{"error":"not found"}`},
+		{"group 405", http.MethodPost, "/api/v1/users", http.StatusMethodNotAllowed, `{"error":"not allowed"}`},
+		{"other group 404", http.MethodGet, "/web/missing", http.StatusNotFound, "<p>not found</p>"},
+		{"app 405 without group handler", http.MethodPost, "/web/about", http.StatusMethodNotAllowed, "app not allowed"},
+		{"app 404", http.MethodGet, "/missing", http.StatusNotFound, "app not found"},
+		{"segment-wise prefix", http.MethodGet, "/apis", http.StatusNotFound, "app not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if body := rec.Body.String(); body != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+			if got := rec.Header().Get("X-Router"); got != "root" {
+				t.Errorf("expected top-level router middleware to run, got %q", got)
+			}
+		})
+	}
+}
+
+func TestFormatExtension(t *testing.T) {
+	tests := []struct {
+		path           string
+		expectedID     string
+		expectedFormat string
+	}{
+		{"/users/42.json", "42", "json"},
+		{"/users/42.xml", "42", "xml"},
+		{"/users/42", "42", ""},
+		{"/users/v1.2.json", "v1.2", "json"},
+	}
+
+	app := velocity.New()
+	router := app.Router("/")
+
+	var params map[string]string
+	router.Get("/users/:id.:format?").Handle(func(w http.ResponseWriter, r *http.Request) {
+		params = velocity.GetParams(r)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			params = nil
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if params["id"] != tt.expectedID {
+				t.Errorf("expected id %q, got %q", tt.expectedID, params["id"])
+			}
+			if format, ok := params["format"]; !ok || format != tt.expectedFormat {
+				t.Errorf("expected format %q, got %q (present: %v)", tt.expectedFormat, format, ok)
+			}
+		})
+	}
+
+	if routes := app.Routes(); len(routes) != 1 || routes[0] != "GET /users/:id.:format?" {
+		t.Errorf("expected registered route %q, got %v", "GET /users/:id.:format?", routes)
+	}
+}
+
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+func newBenchApp() *velocity.App {
+	app := velocity.New()
+	router := app.Router("/")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Get("/users/list/active").Handle(handler)
+	router.Get("/users/:id").Handle(handler)
+	router.Get("/users/:id/posts").Handle(handler)
+	return app
+}
+
+func TestStaticRouteAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not stable under the race detector")
+	}
+	app := newBenchApp()
+	req := httptest.NewRequest(http.MethodGet, "/users/list/active", nil)
+	w := &discardWriter{header: http.Header{}}
+
+	// Only the request carrying the locals and params store is allocated
+	allocs := testing.AllocsPerRun(100, func() {
+		app.ServeHTTP(w, req)
+	})
+	if allocs > 1 {
+		t.Errorf("expected at most 1 allocation per static request, got %v", allocs)
+	}
+}
+
+func TestRouteKeepsDispatchedRequest(t *testing.T) {
+	app := velocity.New()
+	var dispatched, got *http.Request
+	app.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			dispatched = r
+			next(w, r)
+		}
+	})
+	router := app.Router("/")
+	handler := func(w http.ResponseWriter, r *http.Request) { got = r }
+	router.Get("/users").Handle(handler)
+	router.Get("/users/:id").Handle(handler)
+	router.Get("/tenants").Host("*.example.com").Handle(handler)
+
+	// Params are captured into the store attached at dispatch, so matching a route
+	// never clones the request again
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"static route", "http://example.com/users"},
+		{"param route", "http://example.com/users/42"},
+		{"wildcard host captures subdomain", "http://acme.example.com/tenants"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+			if got != dispatched {
+				t.Error("expected the handler to receive the request passed to middleware")
+			}
+		})
+	}
+}
+
+func TestParamRouteAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not stable under the race detector")
+	}
+	app := velocity.New()
+	var id string
+	app.Router("/").Get("/users/:id/posts/:post").Handle(func(w http.ResponseWriter, r *http.Request) {
+		id = velocity.Param(r, "id")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/42/posts/7", nil)
+	w := &discardWriter{header: http.Header{}}
+
+	// Params are captured into the store attached at dispatch
+	allocs := testing.AllocsPerRun(100, func() {
+		app.ServeHTTP(w, req)
+	})
+	if allocs > 1 {
+		t.Errorf("expected at most 1 allocation per param request, got %v", allocs)
+	}
+	if id != "42" {
+		t.Errorf("expected id %q, got %q", "42", id)
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	app := newBenchApp()
+	req := httptest.NewRequest(http.MethodGet, "/users/list/active", nil)
+	w := &discardWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		app.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	app := newBenchApp()
+	req := httptest.NewRequest(http.MethodGet, "/users/42/posts", nil)
+	w := &discardWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		app.ServeHTTP(w, req)
+	}
+}
+
+func TestRegexConstraints(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+
+	router.Get("/users/:id([0-9]+)").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id:" + velocity.GetParams(r)["id"]))
+	})
+	router.Get("/users/:name([a-z]+)").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("name:" + velocity.GetParams(r)["name"]))
+	})
+	router.Get("/orders/:code([A-Z]{3})/items").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("code:" + velocity.GetParams(r)["code"]))
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/users/42", http.StatusOK, "id:42"},
+		{"/users/alice", http.StatusOK, "name:alice"},
+		{"/users/Alice42", http.StatusNotFound, ""},
+		{"/orders/ABC/items", http.StatusOK, "code:ABC"},
+		{"/orders/ABCD/items", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	routes := app.Routes()
+	if len(routes) != 3 {
+		t.Errorf("expected 3 registered routes, got %v", routes)
+	}
+}
+
+func TestHostRouting(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	api := app.Host("api.example.com")
+	admin := app.Host("admin.example.com")
+
+	router.Get("/").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default root"))
+	})
+	router.Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default users"))
+	})
+	api.Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api users"))
+	})
+	admin.Group("/v1").Get("/settings").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin settings"))
+	})
+
+	tests := []struct {
+		host           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"api.example.com", "/users", http.StatusOK, "api users"},
+		{"API.example.com:8080", "/users", http.StatusOK, "api users"},
+		{"www.example.com", "/users", http.StatusOK, "default users"},
+		{"api.example.com", "/", http.StatusOK, "default root"},
+		{"admin.example.com", "/v1/settings", http.StatusOK, "admin settings"},
+		{"api.example.com", "/v1/settings", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	routes := app.Routes()
+	expected := []string{
+		"GET /",
+		"GET /users",
+		"GET admin.example.com/v1/settings",
+		"GET api.example.com/users",
+	}
+	if !slices.Equal(routes, expected) {
+		t.Errorf("expected routes %v, got %v", expected, routes)
+	}
+}
+
+func TestMount(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/api")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+
+	called := false
+	err := router.Mount("/legacy", mux, func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			next(w, r)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if routes := app.Routes(); len(routes) != 0 {
+		t.Errorf("expected mount routes to be left out of routes, got %v", routes)
+	}
+
+	tests := []struct {
+		method       string
+		path         string
+		expectedBody string
+	}{
+		{http.MethodGet, "/api/legacy", "GET /"},
+		{http.MethodGet, "/api/legacy/users/42", "GET /users/42"},
+		{http.MethodPost, "/api/legacy/users", "POST /users"},
+		{http.MethodDelete, "/api/legacy/users/42/", "DELETE /users/42/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if !called {
+				t.Error("expected mount middleware to be called")
+			}
+		})
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	app := velocity.New(velocity.AppConfig{RedirectTrailingSlash: true})
+	router := app.Router("/")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Post("/users").Handle(handler)
+	router.Get("/files/*").Handle(handler)
+
+	tests := []struct {
+		method           string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{http.MethodGet, "/users", http.StatusOK, ""},
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{http.MethodPost, "/users/", http.StatusPermanentRedirect, "/users"},
+		{http.MethodGet, "//users", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/files/docs/", http.StatusOK, ""},
+		{http.MethodGet, "/missing/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.expectedLocation {
+				t.Errorf("expected location %q, got %q", tt.expectedLocation, got)
+			}
+		})
+	}
+}
+
+func TestAny(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Any("/webhook").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+
+	for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run(m, func(t *testing.T) {
+			req := httptest.NewRequest(m, "/webhook", nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != m {
+				t.Errorf("expected body %q, got %q", m, rec.Body.String())
+			}
+		})
+	}
+
+	if routes := app.Routes(); len(routes) != 5 {
+		t.Errorf("expected 5 registered routes, got %v", routes)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Match([]string{http.MethodGet, "post"}, "/form").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+
+	tests := []struct {
+		method         string
+		expectedStatus int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodHead, http.StatusOK},
+		{http.MethodPost, http.StatusOK},
+		{http.MethodPut, http.StatusMethodNotAllowed},
+		{http.MethodDelete, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/form", nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCustomMethods(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Method("PROPFIND", "/files/*").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte("propfind " + velocity.GetParams(r)["*"]))
+	})
+	router.Method("MKCOL", "/files/*").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	router.Match([]string{"REPORT", http.MethodGet}, "/reports").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"PROPFIND", "/files/docs/a.txt", http.StatusMultiStatus, "propfind docs/a.txt"},
+		{"MKCOL", "/files/new", http.StatusCreated, ""},
+		{"REPORT", "/reports", http.StatusOK, "REPORT"},
+		{http.MethodGet, "/reports", http.StatusOK, "GET"},
+		{"PROPFIND", "/other", http.StatusNotFound, ""},
+		{"UNKNOWN", "/files/a", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	routes := app.Routes()
+	for _, expected := range []string{"MKCOL /files/*", "PROPFIND /files/*", "REPORT /reports"} {
+		if !slices.Contains(routes, expected) {
+			t.Errorf("expected routes to contain %q, got %v", expected, routes)
+		}
+	}
+
+	router.Method("BAD METHOD", "/bad").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	if len(app.Routes()) != len(routes) {
+		t.Error("expected invalid method name to be ignored")
+	}
+}
+
+func TestOptionsRoute(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Options("/resource").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/resource", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, OPTIONS" {
+		t.Errorf("expected Allow header %q, got %q", "GET, OPTIONS", got)
+	}
+
+	req = httptest.NewRequest(http.MethodOptions, "/other", nil)
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected automatic OPTIONS status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "" {
+		t.Errorf("expected no Allow header from automatic handler, got %q", got)
+	}
+}
+
+func TestStrictRoutes(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name        string
+		strict      bool
+		first       string
+		second      string
+		expectError bool
+	}{
+		{"strict duplicate route", true, "/users/:id", "/users/:id", true},
+		{"strict conflicting param names", true, "/users/:id", "/users/:name", true},
+		{"strict conflicting nested param names", true, "/users/:id/posts", "/users/:name", true},
+		{"strict distinct routes", true, "/users/:id", "/users/:id/posts", false},
+		{"strict static and param", true, "/users/me", "/users/:id", false},
+		{"lenient duplicate route", false, "/users/:id", "/users/:id", false},
+		{"lenient conflicting param names", false, "/users/:id", "/users/:name", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := velocity.New(velocity.AppConfig{StrictRoutes: tt.strict})
+			router := app.Router("/")
+
+			if err := router.Get(tt.first).Handle(handler); err != nil {
+				t.Fatalf("unexpected error registering %s: %v", tt.first, err)
+			}
+			err := router.Get(tt.second).Handle(handler)
+			if tt.expectError && err == nil {
+				t.Errorf("expected error registering %s after %s", tt.second, tt.first)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	app := velocity.New()
+	if err := app.Router("/").Get("/users/:1id").Handle(handler); err == nil {
+		t.Error("expected error for invalid route path")
+	}
+}
+
+func TestRuntimeRoutes(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	app.Host("api.example.com").Get("/status").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			serve("/users")
+			serve("/plugins/a")
+		}
+	}()
+
+	err := app.AddRoute(http.MethodGet, "/plugins/:name", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(velocity.GetParams(r)["name"]))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-done
+
+	if code := serve("/plugins/a"); code != http.StatusOK {
+		t.Errorf("expected added route status %d, got %d", http.StatusOK, code)
+	}
+	if code := serve("/users"); code != http.StatusOK {
+		t.Errorf("expected existing route status %d, got %d", http.StatusOK, code)
+	}
+
+	if err := app.RemoveRoute(http.MethodGet, "/plugins/:name"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := serve("/plugins/a"); code != http.StatusNotFound {
+		t.Errorf("expected removed route status %d, got %d", http.StatusNotFound, code)
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/plugins/:name"); err == nil {
+		t.Error("expected error removing a missing route")
+	}
+	if err := app.RemoveRoute(http.MethodGet, "/status"); err == nil {
+		t.Error("expected error removing a host route")
+	}
+	if err := app.AddRoute("BAD METHOD", "/x", func(w http.ResponseWriter, r *http.Request) {}); err == nil {
+		t.Error("expected error for invalid method")
+	}
+	if err := app.AddRoute(http.MethodGet, "/users/:1id", func(w http.ResponseWriter, r *http.Request) {}); err == nil {
+		t.Error("expected error for invalid route path")
+	}
+	if code := serve("/users"); code != http.StatusOK {
+		t.Errorf("expected existing route status %d after a failed add, got %d", http.StatusOK, code)
+	}
+}
+
+func TestRouterUse(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+
+	header := func(key string) velocity.Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(key, "true")
+				next(w, r)
+			}
+		}
+	}
+
+	router.Get("/before").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	router.Use(header("X-First"), header("X-Second"))
+	router.Get("/after").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/before", ""},
+		{"/after", "true"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		for _, key := range []string{"X-First", "X-Second"} {
+			if got := rec.Header().Get(key); got != tt.expected {
+				t.Errorf("%s: expected %s header %q, got %q", tt.path, key, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestAppUse(t *testing.T) {
+	var order []string
+	mw := func(name string) velocity.Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+
+	// No routers at all: app middleware still runs for the 404
+	app := velocity.New()
+	app.Use(mw("app"))
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if len(order) != 1 || order[0] != "app" {
+		t.Errorf("expected app middleware to run, got %v", order)
+	}
+
+	app = velocity.New()
+	app.Use(mw("app"))
+	app.Router("/", mw("router")).Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+
+	tests := []struct {
+		method   string
+		path     string
+		expected []string
+	}{
+		{http.MethodGet, "/users", []string{"app", "router", "handler"}},
+		{http.MethodGet, "/missing", []string{"app", "router"}},
+		{http.MethodPost, "/users", []string{"app", "router"}},
+		{http.MethodOptions, "/users", []string{"app", "router"}},
+	}
+
+	for _, tt := range tests {
+		order = nil
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if !slices.Equal(order, tt.expected) {
+			t.Errorf("%s %s: expected %v, got %v", tt.method, tt.path, tt.expected, order)
+		}
+	}
+}
+
+func TestMultipleRouters(t *testing.T) {
+	app := velocity.New()
+
+	header := func(value string) velocity.Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Router", value)
+				next(w, r)
+			}
+		}
+	}
+
+	api := app.Router("/api", header("api"))
+	admin := app.Router("/admin", header("admin"))
+	api.Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	admin.Get("/settings").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedRouter string
+	}{
+		{"api route", http.MethodGet, "/api/users", http.StatusOK, "api"},
+		{"admin route", http.MethodGet, "/admin/settings", http.StatusOK, "admin"},
+		{"api not found", http.MethodGet, "/api/missing", http.StatusNotFound, "api"},
+		{"admin not found", http.MethodGet, "/admin/missing", http.StatusNotFound, "admin"},
+		{"admin not allowed", "PURGE", "/admin/settings", http.StatusMethodNotAllowed, "admin"},
+		{"prefix is segment-wise", http.MethodGet, "/administrator", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("X-Router"); got != tt.expectedRouter {
+				t.Errorf("expected router %q, got %q", tt.expectedRouter, got)
+			}
+		})
+	}
+}
+
+func TestOptionalParams(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Get("/posts/:year/:month?").Handle(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(velocity.GetParams(r))
+	})
+	router.Get("/archive/:page<int>?").Handle(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(velocity.GetParams(r))
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedParams map[string]string
+	}{
+		{"with optional param", "/posts/2024/05", http.StatusOK, map[string]string{"year": "2024", "month": "05"}},
+		{"without optional param", "/posts/2024", http.StatusOK, map[string]string{"year": "2024"}},
+		{"missing required param", "/posts", http.StatusNotFound, nil},
+		{"typed optional param", "/archive/2", http.StatusOK, map[string]string{"page": "2"}},
+		{"typed optional param omitted", "/archive", http.StatusOK, map[string]string{}},
+		{"typed optional param invalid", "/archive/two", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedParams == nil {
+				return
+			}
+			params := map[string]string{}
+			json.NewDecoder(rec.Body).Decode(&params)
+			if len(params) != len(tt.expectedParams) {
+				t.Errorf("expected params %v, got %v", tt.expectedParams, params)
+			}
+			for k, v := range tt.expectedParams {
+				if params[k] != v {
+					t.Errorf("expected param %s=%q, got %q", k, v, params[k])
+				}
+			}
+		})
+	}
+
+	routes := app.Routes()
+	if !slices.Equal(routes, []string{"GET /archive/:page<int>?", "GET /posts/:year/:month?"}) {
+		t.Errorf("expected optional routes to be listed once, got %v", routes)
+	}
+}
+
+func TestAllowHeader(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Post("/users").Handle(handler)
+	router.Method("PROPFIND", "/users").Handle(handler)
+	router.Delete("/users/:id").Handle(handler)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"automatic OPTIONS", http.MethodOptions, "/users", http.StatusNoContent, "GET, HEAD, POST, PROPFIND, OPTIONS"},
+		{"automatic OPTIONS with params", http.MethodOptions, "/users/42", http.StatusNoContent, "DELETE, OPTIONS"},
+		{"unknown method", "PURGE", "/users", http.StatusMethodNotAllowed, "GET, HEAD, POST, PROPFIND, OPTIONS"},
+		{"TRACE without route", http.MethodTrace, "/users/42", http.StatusMethodNotAllowed, "DELETE, OPTIONS"},
+		{"unknown path", http.MethodOptions, "/missing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("expected Allow header %q, got %q", tt.expectedAllow, got)
+			}
+		})
+	}
+}
+
+func TestNotFoundVsNotAllowed(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Get("/users/:id").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+		expectedBody   string
+	}{
+		{"registered method", http.MethodGet, "/users/42", http.StatusOK, "", ""},
+		{"other method on existing path", http.MethodPost, "/users/42", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS", "Method not allowed"},
+		{"missing path", http.MethodPost, "/posts/42", http.StatusNotFound, "", "Not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("expected Allow header %q, got %q", tt.expectedAllow, got)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestHeadRequest(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "2")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`["a","b"]`))
+	})
+
+	req := httptest.NewRequest(http.MethodHead, "/users", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "9" {
+		t.Errorf("expected Content-Length %q, got %q", "9", got)
+	}
+	if got := rec.Header().Get("X-Total"); got != "2" {
+		t.Errorf("expected X-Total header %q, got %q", "2", got)
+	}
+}
+
+func TestMountApp(t *testing.T) {
+	billing := velocity.New()
+	billing.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("billing not found"))
+	})
+	billingRouter := billing.Router("/", func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-App", "billing")
+			next(w, r)
+		}
+	})
+	billingRouter.Get("/invoices/:id").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + velocity.GetParams(r)["id"]))
+	})
+	billingRouter.Method("REPORT", "/invoices").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("report"))
+	})
+
+	app := velocity.New()
+	app.Router("/").Get("/users").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	if err := app.Mount("/billing", billing); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+		expectedApp    string
+	}{
+		{"sub app route", http.MethodGet, "/billing/invoices/7", http.StatusOK, "/invoices/7 7", "billing"},
+		{"sub app custom method", "REPORT", "/billing/invoices", http.StatusOK, "report", "billing"},
+		{"sub app not found", http.MethodGet, "/billing/missing", http.StatusNotFound, "billing not found", "billing"},
+		{"parent route", http.MethodGet, "/users", http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+			if got := rec.Header().Get("X-App"); got != tt.expectedApp {
+				t.Errorf("expected app %q, got %q", tt.expectedApp, got)
+			}
+		})
+	}
+
+	expectedRoutes := []string{"GET /billing/invoices/:id", "GET /users", "REPORT /billing/invoices"}
+	if routes := app.Routes(); !slices.Equal(routes, expectedRoutes) {
+		t.Errorf("expected routes %v without the mount routes, got %v", expectedRoutes, routes)
+	}
+	if list := app.RouteList(); len(list) != len(expectedRoutes) {
+		t.Errorf("expected %d routes in route list, got %v", len(expectedRoutes), list)
+	}
+}
+
+func TestMountErrors(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	app := velocity.New()
+	if err := app.Mount("/billing/:1bad", velocity.New()); err == nil {
+		t.Error("expected error for invalid app mount prefix")
+	}
+	if err := app.Router("/").Mount("/legacy/:1bad", http.NotFoundHandler()); err == nil {
+		t.Error("expected error for invalid mount prefix")
+	}
+
+	strict := velocity.New(velocity.AppConfig{StrictRoutes: true})
+	strict.Router("/").Get("/legacy").Handle(handler)
+	if err := strict.Router("/").Mount("/legacy", http.NotFoundHandler()); err == nil {
+		t.Error("expected error for mount conflicting with an existing route")
+	}
+}
+
+func TestGetRoutePattern(t *testing.T) {
+	app := velocity.New()
+	var pattern string
+	router := app.Router("/api", func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			pattern = velocity.GetRoutePattern(r)
+		}
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users").Handle(handler)
+	router.Get("/users/:id").Handle(handler)
+	router.Get("/files/*").Handle(handler)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/users", "/api/users"},
+		{"/api/users/42", "/api/users/:id"},
+		{"/api/files/a/b.txt", "/api/files/*"},
+		{"/api/missing", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pattern = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+
+			if pattern != tt.expected {
+				t.Errorf("expected pattern %q, got %q", tt.expected, pattern)
+			}
+		})
+	}
+}
+
+func TestWildcardHostRouting(t *testing.T) {
+	app := velocity.New()
+	tenants := app.Host("*.internal.example.com")
+	tenants.Get("/dashboard").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tenant " + velocity.GetParams(r)["subdomain"]))
+	})
+	app.Host("admin.internal.example.com").Get("/dashboard").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin"))
+	})
+	router := app.Router("/")
+	router.Get("/status").Host("*.example.com").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("status " + velocity.GetParams(r)["subdomain"]))
+	})
+
+	tests := []struct {
+		name           string
+		host           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"wildcard subdomain", "acme.internal.example.com", "/dashboard", http.StatusOK, "tenant acme"},
+		{"wildcard with port", "globex.internal.example.com:8080", "/dashboard", http.StatusOK, "tenant globex"},
+		{"exact host wins", "admin.internal.example.com", "/dashboard", http.StatusOK, "admin"},
+		{"nested subdomain does not match", "a.b.internal.example.com", "/dashboard", http.StatusNotFound, "Not found"},
+		{"bare domain does not match", "internal.example.com", "/dashboard", http.StatusNotFound, "Not found"},
+		{"per-route host", "api.example.com", "/status", http.StatusOK, "status api"},
+		{"per-route host mismatch", "example.org", "/status", http.StatusNotFound, "Not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBacktrackingLookup(t *testing.T) {
+	routes := []string{
+		"/users/me",
+		"/users/newest",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/:id/profile",
+		"/orders/:id([0-9]+)",
+		"/files/:name/raw",
+		"/files/*",
+	}
+
+	app := velocity.New()
+	router := app.Router("/")
+	for _, route := range routes {
+		router.Get(route).Handle(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(velocity.GetRoutePattern(r)))
+		})
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"static wins", "/users/me", "/users/me"},
+		{"partial static prefix backtracks to param", "/users/new", "/users/:id"},
+		{"longer static", "/users/newest", "/users/newest"},
+		{"dead-end static backtracks to param", "/users/me/posts", "/users/:id/posts"},
+		{"static prefix backtracks to param", "/users/newest/profile", "/users/:id/profile"},
+		{"constrained param", "/orders/42", "/orders/:id([0-9]+)"},
+		{"constraint mismatch", "/orders/abc", ""},
+		{"param branch", "/files/a/raw", "/files/:name/raw"},
+		{"param dead-end backtracks to catch-all", "/files/a/b/c", "/files/*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if tt.expected == "" {
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+				}
+				return
+			}
+			if rec.Body.String() != tt.expected {
+				t.Errorf("expected route %q, got %q (status %d)", tt.expected, rec.Body.String(), rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package velocity
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Listen starts the HTTP server on the specified port with optional configuration.
+// It blocks until the server fails or is stopped with Shutdown, in which case it
+// returns http.ErrServerClosed.
+// The server will use the following defaults if not specified:
+//   - ReadTimeout: 0 (no timeout)
+//   - WriteTimeout: 0 (no timeout)
+//   - IdleTimeout: 0 (no timeout)
+//
+// Example:
+//
+//	// Basic usage
+//	app.Listen(8080)
+//
+//	// With timeouts
+//	app.Listen(8080, ServerConfig{
+//	    ReadTimeout: 5 * time.Second,
+//	    WriteTimeout: 10 * time.Second,
+//	    IdleTimeout: 120 * time.Second,
+//	})
+//
+//	// With TLS
+//	app.Listen(443, ServerConfig{
+//	    CertFile: "cert.pem",
+//	    KeyFile: "key.pem",
+//	    ReadTimeout: 5 * time.Second,
+//	    WriteTimeout: 10 * time.Second,
+//	    IdleTimeout: 120 * time.Second,
+//	})
+func (a *App) Listen(port int, cfg ...ServerConfig) error {
+	server := a.newServer(":"+strconv.Itoa(port), cfg...)
+	l, err := a.listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return a.serveOn(server, l, cfg...)
+}
+
+// Serve accepts connections on l, e.g. a listener from systemd socket activation or
+// a test, with optional configuration. TLS is used when cfg provides certificates.
+// Like Listen, it blocks until the server fails or is stopped with Shutdown.
+//
+// Example:
+//
+//	l, _ := net.Listen("tcp", "127.0.0.1:0")
+//	go app.Serve(l)
+func (a *App) Serve(l net.Listener, cfg ...ServerConfig) error {
+	server := a.newServer(l.Addr().String(), cfg...)
+	return a.serveOn(server, l, cfg...)
+}
+
+// ListenUnix starts the server on the Unix domain socket at path, with optional
+// configuration. A stale socket left at path by a previous run is removed first; if
+// a server is still accepting connections on it, ListenUnix fails with an "address
+// already in use" error instead of taking the socket over.
+//
+// Example:
+//
+//	app.ListenUnix("/var/run/app.sock")
+func (a *App) ListenUnix(path string, cfg ...ServerConfig) error {
+	l, err := a.listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return a.Serve(l, cfg...)
+}
+
+// ListenWithContext starts the server like Listen and shuts it down gracefully once
+// ctx is done, waiting up to ServerConfig.ShutdownTimeout for in-flight requests.
+// It returns nil after a graceful shutdown.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	err := app.ListenWithContext(ctx, 8080)
+func (a *App) ListenWithContext(ctx context.Context, port int, cfg ...ServerConfig) error {
+	server := a.newServer(":"+strconv.Itoa(port), cfg...)
+	l, err := a.listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- a.serveOn(server, l, cfg...)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if len(cfg) > 0 && cfg[0].ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, cfg[0].ShutdownTimeout)
+		defer cancel()
+	}
+	if err := a.shutdown(shutdownCtx, server); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ListenAndWait starts the server like Listen and shuts it down gracefully on SIGINT
+// or SIGTERM, waiting up to ServerConfig.ShutdownTimeout for in-flight requests.
+// On SIGHUP it hands the listener to a new instance of the binary with Restart and
+// then shuts down the same way; if Restart fails, the error is reported to the
+// OnError hooks and the server keeps running. It returns nil after a graceful shutdown.
+//
+// Example:
+//
+//	log.Fatal(app.ListenAndWait(8080, velocity.ServerConfig{
+//	    ShutdownTimeout: 15 * time.Second,
+//	}))
+func (a *App) ListenAndWait(port int, cfg ...ServerConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				if err := a.Restart(); err != nil {
+					a.Logger().Error("restart failed", "err", err)
+					a.serverError(err)
+					continue
+				}
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return a.ListenWithContext(ctx, port, cfg...)
+}
+
+// newServer builds the http.Server for addr and cfg and records it for Shutdown.
+func (a *App) newServer(addr string, cfg ...ServerConfig) *http.Server {
+	server := &http.Server{
+		Addr:     addr,
+		Handler:  a,
+		ErrorLog: slog.NewLogLogger(a.Logger().Handler(), slog.LevelError),
+	}
+	if len(cfg) > 0 {
+		if cfg[0].ReadTimeout > 0 {
+			server.ReadTimeout = cfg[0].ReadTimeout
+		}
+		if cfg[0].WriteTimeout > 0 {
+			server.WriteTimeout = cfg[0].WriteTimeout
+		}
+		if cfg[0].IdleTimeout > 0 {
+			server.IdleTimeout = cfg[0].IdleTimeout
+		}
+		if cfg[0].ReadHeaderTimeout > 0 {
+			server.ReadHeaderTimeout = cfg[0].ReadHeaderTimeout
+		}
+		if cfg[0].MaxHeaderBytes > 0 {
+			server.MaxHeaderBytes = cfg[0].MaxHeaderBytes
+		}
+		if cfg[0].ErrorLog != nil {
+			server.ErrorLog = cfg[0].ErrorLog
+		}
+		server.BaseContext = cfg[0].BaseContext
+		server.ConnContext = cfg[0].ConnContext
+		if cfg[0].TLSConfig != nil {
+			server.TLSConfig = cfg[0].TLSConfig
+		}
+		if cfg[0].H2C {
+			server.Handler = h2c.NewHandler(a, &http2.Server{IdleTimeout: server.IdleTimeout})
+		}
+	}
+	a.srvMu.Lock()
+	a.server = server
+	a.srvMu.Unlock()
+	return server
+}
+
+// serveOn accepts connections on l, using TLS when cfg provides certificates.
+func (a *App) serveOn(server *http.Server, l net.Listener, cfg ...ServerConfig) (err error) {
+	defer func() {
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.serverError(err)
+		}
+	}()
+
+	var useTLS bool
+	if len(cfg) > 0 {
+		// TLSConfig may already provide certificates, e.g. via autocert
+		tc := server.TLSConfig
+		useTLS = cfg[0].CertFile != "" && cfg[0].KeyFile != "" ||
+			tc != nil && (len(tc.Certificates) > 0 || tc.GetCertificate != nil)
+		if useTLS && cfg[0].HTTPRedirectAddr != "" {
+			redirect, err := a.startRedirect(cfg[0].HTTPRedirectAddr, l.Addr())
+			if err != nil {
+				l.Close()
+				return err
+			}
+			defer redirect.Close()
+		}
+
+		if cfg[0].CertFile != "" && cfg[0].KeyFile != "" {
+			cr, err := newCertReloader(cfg[0].CertFile, cfg[0].KeyFile)
+			if err != nil {
+				l.Close()
+				return err
+			}
+			cr.onError = func(err error) {
+				a.Logger().Error("reloading certificate", "err", err)
+				a.serverError(err)
+			}
+			if server.TLSConfig == nil {
+				server.TLSConfig = &tls.Config{
+					MinVersion: tls.VersionTLS12,
+					NextProtos: []string{"h2", "http/1.1"},
+				}
+			} else {
+				server.TLSConfig = server.TLSConfig.Clone()
+			}
+			// Serve the files through GetCertificate so rotated certificates are picked up
+			server.TLSConfig.GetCertificate = cr.GetCertificate
+		}
+	}
+
+	a.Logger().Info("server listening", "addr", l.Addr().String())
+	for _, fn := range a.onStart {
+		fn(l.Addr().String())
+	}
+	if useTLS {
+		return server.ServeTLS(l, "", "")
+	}
+	return server.Serve(l)
+}
+
+// acmeChallengePrefix is the path prefix of ACME HTTP-01 challenges, which must be
+// answered over plain HTTP.
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// startRedirect starts a plain HTTP server on addr that redirects to the HTTPS server
+// listening on tlsAddr. The caller closes it when the HTTPS server stops.
+func (a *App) startRedirect(addr string, tlsAddr net.Addr) (*http.Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	port := 443
+	if ta, ok := tlsAddr.(*net.TCPAddr); ok {
+		port = ta.Port
+	}
+	redirect := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+			a.ServeHTTP(w, r)
+			return
+		}
+		http.Redirect(w, r, "https://"+httpsHost(r.Host, port)+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})}
+	go func() {
+		if err := redirect.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.Logger().Error("redirect server stopped", "err", err)
+		}
+	}()
+	return redirect, nil
+}
+
+// httpsHost replaces the port of host with port, omitting the default HTTPS port.
+func httpsHost(host string, port int) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	} else {
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	if port != 443 {
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// Shutdown gracefully stops the server started by Listen: it stops accepting new
+// connections and waits for in-flight requests to finish, or for ctx to be done,
+// in which case ctx's error is returned. It is a no-op if the server is not running.
+// Readiness endpoints registered with Health start failing first, for the configured
+// drain delay.
+//
+// Example:
+//
+//	go app.Listen(8080)
+//
+//	stop := make(chan os.Signal, 1)
+//	signal.Notify(stop, syscall.SIGTERM)
+//	<-stop
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	app.Shutdown(ctx)
+func (a *App) Shutdown(ctx context.Context) error {
+	a.srvMu.Lock()
+	server := a.server
+	a.srvMu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return a.shutdown(ctx, server)
+}
+
+// shutdown fails readiness checks, waits for the drain delay, gracefully stops
+// server and then runs the OnShutdown hooks.
+func (a *App) shutdown(ctx context.Context, server *http.Server) error {
+	a.health.drain(ctx)
+	err := server.Shutdown(ctx)
+	for _, fn := range a.onShutdown {
+		fn()
+	}
+	return err
+}
+
+// listen announces on the network address, or takes over the listener passed by
+// Restart, and records it for the next Restart. Failures are reported to the OnError
+// hooks.
+func (a *App) listen(network, addr string) (net.Listener, error) {
+	l, err := inheritedListener(network, addr)
+	if l == nil && err == nil {
+		if network == "unix" {
+			err = removeStaleSocket(addr)
+		}
+		if err == nil {
+			l, err = net.Listen(network, addr)
+		}
+	}
+	if err != nil {
+		a.serverError(err)
+		return nil, err
+	}
+	a.srvMu.Lock()
+	a.listener = l
+	a.listenKey = network + "://" + addr
+	a.srvMu.Unlock()
+	return l, nil
+}
+
+// removeStaleSocket removes the Unix socket at path left by a previous run. Sockets
+// that still accept connections belong to a running server and are left in place.
+func removeStaleSocket(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return &net.OpError{
+			Op:   "listen",
+			Net:  "unix",
+			Addr: &net.UnixAddr{Name: path, Net: "unix"},
+			Err:  os.NewSyscallError("bind", syscall.EADDRINUSE),
+		}
+	}
+	return os.Remove(path)
+}
+
+// Logger returns the logger configured with AppConfig.Logger, or slog.Default().
+func (a *App) Logger() *slog.Logger {
+	if a.cfg.Logger != nil {
+		return a.cfg.Logger
+	}
+	return slog.Default()
+}
+
+// serverError reports err to the OnError hooks.
+func (a *App) serverError(err error) {
+	for _, fn := range a.onServerError {
+		fn(err)
+	}
+}
+
+// OnStart registers fn to be called with the listening address once the server's
+// listener is bound, before requests are served, e.g. to register the instance with
+// service discovery or warm caches. Hooks must be registered before the server starts.
+//
+// Example:
+//
+//	app.OnStart(func(addr string) {
+//	    registry.Register("api", addr)
+//	})
+func (a *App) OnStart(fn func(addr string)) {
+	a.onStart = append(a.onStart, fn)
+}
+
+// OnShutdown registers fn to be called when the server is stopped with Shutdown,
+// ListenWithContext or ListenAndWait, after in-flight requests have finished or the
+// shutdown timeout expired, e.g. to flush telemetry. Hooks must be registered before
+// the server starts.
+//
+// Example:
+//
+//	app.OnShutdown(func() {
+//	    tracerProvider.Shutdown(context.Background())
+//	})
+func (a *App) OnShutdown(fn func()) {
+	a.onShutdown = append(a.onShutdown, fn)
+}
+
+// OnError registers fn to be called when the server fails, e.g. because its address
+// is already in use or a rotated TLS certificate cannot be loaded. A graceful shutdown
+// is not reported. Handler errors go to the ErrorHandler instead. Hooks must be
+// registered before the server starts.
+//
+// Example:
+//
+//	app.OnError(func(err error) {
+//	    metrics.ServerErrors.Inc()
+//	})
+func (a *App) OnError(fn func(error)) {
+	a.onServerError = append(a.onServerError, fn)
+}
@@ -0,0 +1,588 @@
+package velocity_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"golang.org/x/net/http2"
+)
+
+// freePort returns a TCP port that is free at the time of the call.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitForServer polls url until the server responds.
+func waitForServer(t *testing.T, url string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s did not start", url)
+}
+
+func TestShutdown(t *testing.T) {
+	app := velocity.New()
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error shutting down a stopped app, got %v", err)
+	}
+
+	router := app.Router("/")
+	router.Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	started := make(chan struct{})
+	router.Get("/slow").Handle(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	port := freePort(t)
+	errc := make(chan error, 1)
+	go func() { errc <- app.Listen(port) }()
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	waitForServer(t, base+"/health")
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := app.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if got := <-body; got != "done" {
+		t.Errorf("expected in-flight request to complete, got %q", got)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected %v from Listen, got %v", http.ErrServerClosed, err)
+	}
+}
+
+func TestListenWithContext(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	port := freePort(t)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- app.ListenWithContext(ctx, port, velocity.ServerConfig{ShutdownTimeout: time.Second})
+	}()
+	waitForServer(t, fmt.Sprintf("http://127.0.0.1:%d/health", port))
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("expected nil error after graceful shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shut down after context cancellation")
+	}
+}
+
+func TestServe(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- app.Serve(l) }()
+	waitForServer(t, "http://"+l.Addr().String()+"/health")
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected %v from Serve, got %v", http.ErrServerClosed, err)
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	errc := make(chan error, 1)
+	go func() { errc <- app.ListenUnix(sock) }()
+	defer app.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 100; i++ {
+		if resp, err = client.Get("http://unix/health"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "ok" {
+		t.Errorf("expected body %q, got %q", "ok", b)
+	}
+}
+
+func TestListenUnixSocketInUse(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("running"))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	app := velocity.New()
+	if err := app.ListenUnix(sock); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("expected address in use error, got %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatalf("expected the running server to keep its socket: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "running" {
+		t.Errorf("expected body %q, got %q", "running", b)
+	}
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Leave the socket file behind, as a crashed process would
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	app := velocity.New()
+	app.OnStart(func(addr string) { app.Shutdown(context.Background()) })
+	if err := app.ListenUnix(sock); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected the stale socket to be replaced, got %v", err)
+	}
+}
+
+// writeCert writes a self-signed certificate for commonName to certFile and keyFile.
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "first")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go app.Serve(l, velocity.ServerConfig{CertFile: certFile, KeyFile: keyFile})
+	defer app.Shutdown(context.Background())
+
+	commonName := func() string {
+		t.Helper()
+		// A new transport per call forces a new handshake
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		var resp *http.Response
+		var err error
+		for i := 0; i < 100; i++ {
+			if resp, err = client.Get("https://" + l.Addr().String() + "/health"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := commonName(); got != "first" {
+		t.Fatalf("expected certificate %q, got %q", "first", got)
+	}
+
+	writeCert(t, certFile, keyFile, "second")
+	// Make sure the rotation is visible even on filesystems with coarse timestamps
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	// The files are checked at most once per second
+	got := commonName()
+	for deadline := time.Now().Add(3 * time.Second); got != "second" && time.Now().Before(deadline); {
+		time.Sleep(100 * time.Millisecond)
+		got = commonName()
+	}
+	if got != "second" {
+		t.Errorf("expected reloaded certificate %q, got %q", "second", got)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/proto").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go app.Serve(l, velocity.ServerConfig{H2C: true})
+	defer app.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + l.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %q", b)
+	}
+}
+
+func TestHTTPRedirectAddr(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	router.Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/.well-known/acme-challenge/:token").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(velocity.GetParams(r)["token"]))
+	})
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "localhost")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	redirectAddr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	go app.Serve(l, velocity.ServerConfig{CertFile: certFile, KeyFile: keyFile, HTTPRedirectAddr: redirectAddr})
+	defer app.Shutdown(context.Background())
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = client.Get("http://" + redirectAddr + "/users?page=2"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPermanentRedirect {
+		t.Errorf("expected status %d, got %d", http.StatusPermanentRedirect, resp.StatusCode)
+	}
+	want := fmt.Sprintf("https://127.0.0.1:%d/users?page=2", l.Addr().(*net.TCPAddr).Port)
+	if got := resp.Header.Get("Location"); got != want {
+		t.Errorf("expected Location %q, got %q", want, got)
+	}
+
+	resp, err = client.Get("http://" + redirectAddr + "/.well-known/acme-challenge/abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(b) != "abc" {
+		t.Errorf("expected challenge to be served, got %d %q", resp.StatusCode, b)
+	}
+}
+
+func TestServerConfigConnContext(t *testing.T) {
+	type ctxKey struct{}
+	app := velocity.New()
+	app.Router("/").Get("/conn").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value(ctxKey{}).(string)))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go app.Serve(l, velocity.ServerConfig{
+		ReadHeaderTimeout: time.Second,
+		MaxHeaderBytes:    1 << 10,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, ctxKey{}, "tagged")
+		},
+	})
+	defer app.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/conn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "tagged" {
+		t.Errorf("expected connection context value %q, got %q", "tagged", b)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+"/conn", nil)
+	req.Header.Set("X-Large", strings.Repeat("a", 16<<10))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/health").Handle(func(w http.ResponseWriter, r *http.Request) {})
+
+	started := make(chan string, 1)
+	var shutdown bool
+	app.OnStart(func(addr string) { started <- addr })
+	app.OnShutdown(func() { shutdown = true })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- app.Serve(l) }()
+	if addr := <-started; addr != l.Addr().String() {
+		t.Errorf("expected OnStart with %q, got %q", l.Addr().String(), addr)
+	}
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	<-errc
+	if !shutdown {
+		t.Error("expected OnShutdown to be called")
+	}
+}
+
+func TestOnError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	app := velocity.New()
+	var reported error
+	app.OnError(func(err error) { reported = err })
+
+	err = app.Listen(l.Addr().(*net.TCPAddr).Port)
+	if err == nil {
+		t.Fatal("expected an error listening on a port in use")
+	}
+	if reported != err {
+		t.Errorf("expected OnError with %v, got %v", err, reported)
+	}
+}
+
+func TestInheritedListener(t *testing.T) {
+	if port := os.Getenv("VELOCITY_TEST_CHILD_PORT"); port != "" {
+		// Runs in the child process started below
+		app := velocity.New()
+		app.Router("/").Get("/who").Handle(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("child"))
+		})
+		p, _ := strconv.Atoi(port)
+		app.Listen(p)
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("listener inheritance is not supported on Windows")
+	}
+
+	port := freePort(t)
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Keep the port bound so the child can only serve on the inherited socket
+	defer l.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestInheritedListener$")
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("VELOCITY_TEST_CHILD_PORT=%d", port),
+		fmt.Sprintf("VELOCITY_LISTENER=tcp://:%d", port),
+	)
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	var resp *http.Response
+	for i := 0; i < 200; i++ {
+		if resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/who", port)); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := io.ReadAll(resp.Body); string(b) != "child" {
+		t.Errorf("expected the child to serve on the inherited listener, got %q", b)
+	}
+}
+
+func TestRestartNotListening(t *testing.T) {
+	if err := velocity.New().Restart(); err == nil {
+		t.Error("expected an error restarting an app that is not listening")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	app := velocity.New(velocity.AppConfig{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+	app.Router("/").Get("/panic").Handle(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- app.Serve(l) }()
+	if resp, err := http.Get("http://" + l.Addr().String() + "/panic"); err == nil {
+		resp.Body.Close()
+	}
+	app.Shutdown(context.Background())
+	<-errc
+
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"server listening"`) {
+		t.Errorf("expected startup message in logger output, got %q", out)
+	}
+	if !strings.Contains(out, "boom") {
+		t.Errorf("expected panic in logger output, got %q", out)
+	}
+}
@@ -0,0 +1,374 @@
+/*
+Package session provides cookie-based sessions for velocity apps. The cookie only
+holds a random session ID; the data lives in a Store, such as the in-memory store,
+the SQL store in this package or the Redis store in package
+github.com/Juanfec4/velocity/middleware/redisstore.
+
+Sessions expire after IdleTimeout without requests and after AbsoluteTimeout since
+they were created, whichever comes first. Values are encoded with encoding/gob, so
+custom types stored in a session must be registered with gob.Register.
+
+Usage:
+
+	router := app.Router("/", session.Middleware())
+
+	router.Post("/login").Handle(func(w http.ResponseWriter, r *http.Request) {
+	    s := session.Get(r)
+	    s.Set("user_id", 42)
+	    if err := s.Regenerate(); err != nil {
+	        http.Error(w, err.Error(), http.StatusInternalServerError)
+	        return
+	    }
+	})
+
+	router.Get("/me").Handle(func(w http.ResponseWriter, r *http.Request) {
+	    id, ok := session.Value[int](session.Get(r), "user_id")
+	    // ...
+	})
+*/
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+)
+
+// Store persists encoded sessions. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the data saved under id, or false if there is none or it expired
+	Load(ctx context.Context, id string) (data []byte, found bool, err error)
+
+	// Save stores data under id until expiry, replacing any previous data
+	Save(ctx context.Context, id string, data []byte, expiry time.Time) error
+
+	// Delete removes the data saved under id, if any
+	Delete(ctx context.Context, id string) error
+}
+
+// Config configures the session Middleware.
+type Config struct {
+	// Store holds session data. Defaults to an in-memory store local to the process.
+	Store Store
+
+	// CookieName is the name of the cookie holding the session ID
+	CookieName *string
+
+	// IdleTimeout is how long a session lasts without requests
+	IdleTimeout *time.Duration
+
+	// AbsoluteTimeout is how long a session lasts after it was created, regardless of activity
+	AbsoluteTimeout *time.Duration
+
+	// CookiePath is the Path attribute of the session cookie
+	CookiePath *string
+
+	// CookieDomain is the Domain attribute of the session cookie
+	CookieDomain *string
+
+	// Secure sets the Secure attribute of the session cookie
+	Secure *bool
+
+	// HTTPOnly sets the HttpOnly attribute of the session cookie
+	HTTPOnly *bool
+
+	// SameSite is the SameSite attribute of the session cookie
+	SameSite *http.SameSite
+
+	// Skipper skips the middleware for requests it returns true for, e.g. health
+	// checks and static files, so no session is loaded. Get returns nil for them.
+	Skipper middleware.Skipper
+}
+
+var defaultCookieName = "session_id"
+var defaultIdleTimeout = 30 * time.Minute
+var defaultAbsoluteTimeout = 24 * time.Hour
+var defaultCookiePath = "/"
+var defaultCookieDomain = ""
+var defaultSecure = true
+var defaultHTTPOnly = true
+var defaultSameSite = http.SameSiteLaxMode
+var defaultConfig = Config{
+	CookieName:      &defaultCookieName,
+	IdleTimeout:     &defaultIdleTimeout,
+	AbsoluteTimeout: &defaultAbsoluteTimeout,
+	CookiePath:      &defaultCookiePath,
+	CookieDomain:    &defaultCookieDomain,
+	Secure:          &defaultSecure,
+	HTTPOnly:        &defaultHTTPOnly,
+	SameSite:        &defaultSameSite,
+}
+
+var sessionKey = struct {
+	name string
+}{name: "session"}
+
+// Session is the session of a request. It is safe for concurrent use, but only valid
+// until the request's handler returns.
+type Session struct {
+	mu     sync.Mutex
+	id     string
+	rec    record
+	stored bool
+	w      http.ResponseWriter
+	ctx    context.Context
+	config *Config
+}
+
+// record is the stored form of a session.
+type record struct {
+	Values   map[string]any
+	Created  time.Time
+	LastSeen time.Time
+}
+
+// Middleware returns a middleware that loads the session named by the request's
+// cookie, or starts an empty one, and makes it available through Get. New sessions
+// are only stored, and their cookie set, once Save or Regenerate is called, so
+// anonymous visitors do not create sessions. Stored sessions have their idle
+// expiry extended after every request.
+//
+// Example:
+//
+//	router := app.Router("/", session.Middleware())
+//	// or with config
+//	router := app.Router("/", session.Middleware(session.Config{
+//	    Store: redisstore.NewSessionStore(client, "session:"),
+//	    IdleTimeout: durationPtr(time.Hour),
+//	    Skipper: middleware.SkipPaths("/healthz", "/assets/*"),
+//	}))
+func Middleware(cfg ...Config) func(next http.HandlerFunc) http.HandlerFunc {
+	config := defaultConfig
+	if len(cfg) > 0 {
+		if cfg[0].Store != nil {
+			config.Store = cfg[0].Store
+		}
+		if cfg[0].CookieName != nil {
+			config.CookieName = cfg[0].CookieName
+		}
+		if cfg[0].IdleTimeout != nil {
+			config.IdleTimeout = cfg[0].IdleTimeout
+		}
+		if cfg[0].AbsoluteTimeout != nil {
+			config.AbsoluteTimeout = cfg[0].AbsoluteTimeout
+		}
+		if cfg[0].CookiePath != nil {
+			config.CookiePath = cfg[0].CookiePath
+		}
+		if cfg[0].CookieDomain != nil {
+			config.CookieDomain = cfg[0].CookieDomain
+		}
+		if cfg[0].Secure != nil {
+			config.Secure = cfg[0].Secure
+		}
+		if cfg[0].HTTPOnly != nil {
+			config.HTTPOnly = cfg[0].HTTPOnly
+		}
+		if cfg[0].SameSite != nil {
+			config.SameSite = cfg[0].SameSite
+		}
+		if cfg[0].Skipper != nil {
+			config.Skipper = cfg[0].Skipper
+		}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if config.Skipper != nil && config.Skipper(r) {
+				next(w, r)
+				return
+			}
+			s := &Session{w: w, ctx: r.Context(), config: &config}
+			if c, err := r.Cookie(*config.CookieName); err == nil && c.Value != "" {
+				s.load(c.Value)
+			}
+			if !s.stored {
+				s.rec = record{Values: make(map[string]any), Created: time.Now()}
+			}
+
+			ctx := context.WithValue(r.Context(), sessionKey, s)
+			next(w, r.WithContext(ctx))
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.stored {
+				s.rec.LastSeen = time.Now()
+				s.save()
+			}
+		}
+	}
+}
+
+// Get returns the session of the request, or nil if the session Middleware did not run.
+func Get(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey).(*Session)
+	return s
+}
+
+// Value returns the value stored under key in s if it has type T.
+//
+// Example:
+//
+//	userID, ok := session.Value[int](session.Get(r), "user_id")
+func Value[T any](s *Session, key string) (T, bool) {
+	v, ok := s.Get(key).(T)
+	return v, ok
+}
+
+// ID returns the session ID, or an empty string if the session was not stored yet.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get returns the value stored under key, or nil if there is none.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rec.Values[key]
+}
+
+// Set stores value under key. Changes to a stored session are persisted when the
+// request completes; a new session is only persisted once Save is called.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rec.Values[key] = value
+}
+
+// Delete removes the value stored under key. It is persisted like Set.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rec.Values, key)
+}
+
+// Save persists the session now, setting the session cookie if the session is new.
+// Like any header, the cookie must be set before the response is written.
+func (s *Session) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id == "" {
+		s.id = newID()
+		s.setCookie()
+	}
+	s.rec.LastSeen = time.Now()
+	s.stored = true
+	return s.save()
+}
+
+// Regenerate moves the session to a new ID and persists it, removing the old ID
+// from the store. Call it when the privilege level changes, e.g. after login, to
+// prevent session fixation.
+func (s *Session) Regenerate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id != "" {
+		if err := s.config.Store.Delete(s.ctx, s.id); err != nil {
+			return fmt.Errorf("session: delete: %w", err)
+		}
+	}
+	s.id = newID()
+	s.setCookie()
+	s.rec.LastSeen = time.Now()
+	s.stored = true
+	return s.save()
+}
+
+// Destroy removes the session from the store, clears its values and expires the
+// session cookie, e.g. on logout.
+func (s *Session) Destroy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.id
+	s.id = ""
+	s.stored = false
+	s.rec = record{Values: make(map[string]any), Created: time.Now()}
+	http.SetCookie(s.w, s.cookie("", -1))
+	if id == "" {
+		return nil
+	}
+	if err := s.config.Store.Delete(s.ctx, id); err != nil {
+		return fmt.Errorf("session: delete: %w", err)
+	}
+	return nil
+}
+
+// load reads the session saved under id, dropping it if it expired or is unreadable.
+func (s *Session) load(id string) {
+	data, found, err := s.config.Store.Load(s.ctx, id)
+	if err != nil || !found {
+		return
+	}
+	var rec record
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return
+	}
+	if s.expiry(rec).Before(time.Now()) {
+		s.config.Store.Delete(s.ctx, id)
+		return
+	}
+	if rec.Values == nil {
+		rec.Values = make(map[string]any)
+	}
+	s.id, s.rec, s.stored = id, rec, true
+}
+
+func (s *Session) save() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s.rec); err != nil {
+		return fmt.Errorf("session: encode: %w", err)
+	}
+	if err := s.config.Store.Save(s.ctx, s.id, buf.Bytes(), s.expiry(s.rec)); err != nil {
+		return fmt.Errorf("session: save: %w", err)
+	}
+	return nil
+}
+
+// expiry returns when rec expires: after the idle timeout, capped by the absolute timeout.
+func (s *Session) expiry(rec record) time.Time {
+	idle := rec.LastSeen.Add(*s.config.IdleTimeout)
+	absolute := rec.Created.Add(*s.config.AbsoluteTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+// setCookie sets the session cookie to expire with the absolute timeout; idle
+// expiry is enforced by the store.
+func (s *Session) setCookie() {
+	maxAge := int(time.Until(s.rec.Created.Add(*s.config.AbsoluteTimeout)).Seconds())
+	http.SetCookie(s.w, s.cookie(s.id, max(maxAge, 1)))
+}
+
+func (s *Session) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     *s.config.CookieName,
+		Value:    value,
+		Path:     *s.config.CookiePath,
+		Domain:   *s.config.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   *s.config.Secure,
+		HttpOnly: *s.config.HTTPOnly,
+		SameSite: *s.config.SameSite,
+	}
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
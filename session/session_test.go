@@ -0,0 +1,186 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/middleware"
+	"github.com/Juanfec4/velocity/session"
+)
+
+type client struct {
+	cookie *http.Cookie
+}
+
+// do sends a request through h with the client's session cookie and keeps the
+// cookie set by the response, if any.
+func (c *client) do(t *testing.T, h http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if c.cookie != nil {
+		req.AddCookie(c.cookie)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	for _, ck := range rec.Result().Cookies() {
+		if ck.Name == "session_id" {
+			c.cookie = ck
+		}
+	}
+	return rec
+}
+
+func TestSession(t *testing.T) {
+	mw := session.Middleware()
+	var c client
+
+	// Anonymous requests do not create a session
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		session.Get(r).Set("visited", true)
+	}))
+	if c.cookie != nil {
+		t.Fatal("expected no cookie for an unsaved session")
+	}
+
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		s := session.Get(r)
+		s.Set("user_id", 42)
+		if err := s.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	if c.cookie == nil || !c.cookie.HttpOnly || !c.cookie.Secure || c.cookie.MaxAge <= 0 {
+		t.Fatalf("expected secure session cookie, got %v", c.cookie)
+	}
+	firstID := c.cookie.Value
+
+	// Changes to a stored session persist without Save
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		s := session.Get(r)
+		if id, ok := session.Value[int](s, "user_id"); !ok || id != 42 {
+			t.Errorf("expected user_id 42, got %v", id)
+		}
+		s.Set("theme", "dark")
+	}))
+
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		s := session.Get(r)
+		if theme, _ := session.Value[string](s, "theme"); theme != "dark" {
+			t.Errorf("expected theme dark, got %q", theme)
+		}
+		if err := s.Regenerate(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	if c.cookie.Value == firstID {
+		t.Fatal("expected Regenerate to issue a new ID")
+	}
+
+	// The old ID no longer resolves
+	old := client{cookie: &http.Cookie{Name: "session_id", Value: firstID}}
+	old.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		if session.Get(r).Get("user_id") != nil {
+			t.Error("expected old session ID to be invalid after Regenerate")
+		}
+	}))
+
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Get(r).Destroy(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	if c.cookie.MaxAge >= 0 {
+		t.Errorf("expected Destroy to expire the cookie, got MaxAge %d", c.cookie.MaxAge)
+	}
+	c.cookie.Value = ""
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		if session.Get(r).Get("user_id") != nil {
+			t.Error("expected values to be gone after Destroy")
+		}
+	}))
+}
+
+func TestSessionExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		idle     time.Duration
+		absolute time.Duration
+	}{
+		{"idle timeout", 20 * time.Millisecond, time.Hour},
+		{"absolute timeout", time.Hour, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := session.Middleware(session.Config{IdleTimeout: &tt.idle, AbsoluteTimeout: &tt.absolute})
+			var c client
+			c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+				s := session.Get(r)
+				s.Set("user_id", 1)
+				s.Save()
+			}))
+
+			time.Sleep(30 * time.Millisecond)
+			c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+				if session.Get(r).Get("user_id") != nil {
+					t.Error("expected session to expire")
+				}
+			}))
+		})
+	}
+}
+
+func TestGetWithoutMiddleware(t *testing.T) {
+	if session.Get(httptest.NewRequest(http.MethodGet, "/", nil)) != nil {
+		t.Error("expected nil session without middleware")
+	}
+}
+
+// countingStore counts the sessions loaded from the wrapped store.
+type countingStore struct {
+	session.Store
+	loads int
+}
+
+func (cs *countingStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	cs.loads++
+	return cs.Store.Load(ctx, id)
+}
+
+func TestSessionSkipper(t *testing.T) {
+	store := &countingStore{Store: session.NewMemoryStore()}
+	mw := session.Middleware(session.Config{Store: store, Skipper: middleware.SkipPaths("/healthz")})
+	var c client
+
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		if err := session.Get(r).Save(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	if c.cookie == nil {
+		t.Fatal("expected session cookie")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.AddCookie(c.cookie)
+	mw(func(w http.ResponseWriter, r *http.Request) {
+		if session.Get(r) != nil {
+			t.Error("expected no session for a skipped request")
+		}
+	})(httptest.NewRecorder(), req)
+	if store.loads != 0 {
+		t.Errorf("expected no session loads for a skipped request, got %d", store.loads)
+	}
+
+	c.do(t, mw(func(w http.ResponseWriter, r *http.Request) {
+		if session.Get(r).ID() == "" {
+			t.Error("expected the stored session")
+		}
+	}))
+	if store.loads != 1 {
+		t.Errorf("expected 1 session load, got %d", store.loads)
+	}
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps sessions in memory. Sessions are lost on
+// restart and not shared between replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	nextSweep time.Time
+}
+
+type memoryEntry struct {
+	data   []byte
+	expiry time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(_ context.Context, id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || !time.Now().Before(e.expiry) {
+		return nil, false, nil
+	}
+	return e.data, true, nil
+}
+
+// Save implements Store. Expired sessions are removed at most once a minute.
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, expiry time.Time) error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, e := range s.sessions {
+			if !now.Before(e.expiry) {
+				delete(s.sessions, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	s.sessions[id] = memoryEntry{data: data, expiry: expiry}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// SQLStoreConfig configures an SQLStore.
+type SQLStoreConfig struct {
+	// Table is the name of the sessions table.
+	// Default: "sessions"
+	Table string
+
+	// Placeholder is the bind parameter style of the driver: "?" for MySQL and
+	// SQLite, or "$" for PostgreSQL, which numbers parameters as $1, $2, ...
+	// Default: "?"
+	Placeholder string
+}
+
+// SQLStore is a Store that keeps sessions in an SQL database through database/sql.
+// The table must exist with this schema, adjusting the data type to the database,
+// e.g. BYTEA on PostgreSQL:
+//
+//	CREATE TABLE sessions (
+//	    id     VARCHAR(64) PRIMARY KEY,
+//	    data   BLOB NOT NULL,
+//	    expiry BIGINT NOT NULL
+//	);
+//	CREATE INDEX sessions_expiry ON sessions (expiry);
+//
+// Expired rows are ignored but not removed; call DeleteExpired periodically.
+type SQLStore struct {
+	db          *sql.DB
+	load        string
+	update      string
+	insert      string
+	delete      string
+	deleteAfter string
+}
+
+// NewSQLStore returns a store using db.
+func NewSQLStore(db *sql.DB, cfg ...SQLStoreConfig) *SQLStore {
+	config := SQLStoreConfig{}
+	if len(cfg) > 0 {
+		config = cfg[0]
+	}
+	if config.Table == "" {
+		config.Table = "sessions"
+	}
+	p := func(n int) string {
+		if config.Placeholder == "$" {
+			return "$" + strconv.Itoa(n)
+		}
+		return "?"
+	}
+
+	t := config.Table
+	return &SQLStore{
+		db:          db,
+		load:        "SELECT data FROM " + t + " WHERE id = " + p(1) + " AND expiry > " + p(2),
+		update:      "UPDATE " + t + " SET data = " + p(1) + ", expiry = " + p(2) + " WHERE id = " + p(3),
+		insert:      "INSERT INTO " + t + " (id, data, expiry) VALUES (" + p(1) + ", " + p(2) + ", " + p(3) + ")",
+		delete:      "DELETE FROM " + t + " WHERE id = " + p(1),
+		deleteAfter: "DELETE FROM " + t + " WHERE expiry <= " + p(1),
+	}
+}
+
+// Load implements Store.
+func (s *SQLStore) Load(ctx context.Context, id string) ([]byte, bool, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, s.load, id, time.Now().UnixMilli()).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Save implements Store.
+func (s *SQLStore) Save(ctx context.Context, id string, data []byte, expiry time.Time) error {
+	res, err := s.db.ExecContext(ctx, s.update, data, expiry.UnixMilli(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, s.insert, id, data, expiry.UnixMilli())
+	return err
+}
+
+// Delete implements Store.
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.delete, id)
+	return err
+}
+
+// DeleteExpired removes expired sessions from the table.
+func (s *SQLStore) DeleteExpired(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, s.deleteAfter, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("session: delete expired: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package session_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity/session"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStores(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE web_sessions (id VARCHAR(64) PRIMARY KEY, data BLOB NOT NULL, expiry BIGINT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]session.Store{
+		"memory": session.NewMemoryStore(),
+		"sql":    session.NewSQLStore(db, session.SQLStoreConfig{Table: "web_sessions"}),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, ok, err := store.Load(ctx, "abc"); ok || err != nil {
+				t.Fatalf("expected missing session, got ok=%v err=%v", ok, err)
+			}
+
+			for _, data := range []string{"first", "second"} {
+				if err := store.Save(ctx, "abc", []byte(data), time.Now().Add(time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+				got, ok, err := store.Load(ctx, "abc")
+				if err != nil || !ok || string(got) != data {
+					t.Fatalf("expected %q, got %q ok=%v err=%v", data, got, ok, err)
+				}
+			}
+
+			store.Save(ctx, "expired", []byte("old"), time.Now().Add(-time.Second))
+			if _, ok, _ := store.Load(ctx, "expired"); ok {
+				t.Error("expected expired session to be ignored")
+			}
+
+			if err := store.Delete(ctx, "abc"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := store.Load(ctx, "abc"); ok {
+				t.Error("expected session to be deleted")
+			}
+		})
+	}
+
+	if err := stores["sql"].(*session.SQLStore).DeleteExpired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM web_sessions`).Scan(&n)
+	if n != 0 {
+		t.Errorf("expected DeleteExpired to remove expired rows, %d left", n)
+	}
+}
@@ -0,0 +1,406 @@
+package velocity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSSEClosed is returned when sending on an SSESender after Close.
+var ErrSSEClosed = errors.New("velocity: SSE stream closed")
+
+// SSEConfig configures SSE.
+type SSEConfig struct {
+	// HeartbeatInterval is how often a comment line is sent, so proxies do not close
+	// idle streams and disconnected clients are noticed. A negative value disables
+	// heartbeats.
+	// Default: 15s
+	HeartbeatInterval time.Duration
+
+	// Retry is the reconnection delay sent to the client, which EventSource waits
+	// before reconnecting after the stream drops.
+	// Default: 0 (the browser's default)
+	Retry time.Duration
+}
+
+var defaultSSEConfig = SSEConfig{
+	HeartbeatInterval: 15 * time.Second,
+}
+
+// SSESender sends Server-Sent Events to a client. It is safe for concurrent use,
+// but must be closed before the handler returns.
+type SSESender struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	ctx         context.Context
+	lastEventID string
+
+	mu     sync.Mutex
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// SSE starts a Server-Sent Events stream: it sets the text/event-stream headers,
+// lifts the server's WriteTimeout for the response, sends the status and starts the
+// heartbeat. Events are flushed as they are sent. Call Close before the handler
+// returns.
+//
+// Example:
+//
+//	router.Get("/events").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    s, err := velocity.SSE(w, r)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    defer s.Close()
+//	    for job := range jobs.Updates(r.Context(), s.LastEventID()) {
+//	        if err := s.Send("progress", job.ID, job); err != nil {
+//	            return nil // client disconnected
+//	        }
+//	    }
+//	    return nil
+//	})
+func SSE(w http.ResponseWriter, r *http.Request, cfg ...SSEConfig) (*SSESender, error) {
+	config := defaultSSEConfig
+	if len(cfg) > 0 {
+		if cfg[0].HeartbeatInterval != 0 {
+			config.HeartbeatInterval = cfg[0].HeartbeatInterval
+		}
+		config.Retry = cfg[0].Retry
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, err
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Disables response buffering in nginx
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	s := &SSESender{
+		w:           w,
+		rc:          rc,
+		ctx:         r.Context(),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	var prelude []byte
+	if config.Retry > 0 {
+		prelude = []byte("retry: " + strconv.FormatInt(config.Retry.Milliseconds(), 10) + "\n\n")
+	}
+	if err := s.write(prelude); err != nil {
+		return nil, err
+	}
+	if config.HeartbeatInterval > 0 {
+		go s.heartbeat(config.HeartbeatInterval)
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+func (s *SSESender) heartbeat(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.write([]byte(":\n\n")) != nil {
+				return
+			}
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Send sends an event. event and id may be empty; EventSource dispatches events
+// without a name as "message". Strings and byte slices are sent as is, split into
+// one data line per line, and other values as JSON. It fails once the client has
+// disconnected or the sender was closed.
+func (s *SSESender) Send(event, id string, data any) error {
+	payload, err := sseData(data)
+	if err != nil {
+		return err
+	}
+	frame, err := sseFrame(event, id, payload)
+	if err != nil {
+		return err
+	}
+	return s.write(frame)
+}
+
+// write writes p, if any, and flushes the response.
+func (s *SSESender) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrSSEClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if len(p) > 0 {
+		if _, err := s.w.Write(p); err != nil {
+			return err
+		}
+	}
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// LastEventID returns the Last-Event-ID header sent by a reconnecting client, the
+// ID of the last event it received, or "".
+func (s *SSESender) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed once the client has disconnected.
+func (s *SSESender) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close stops the heartbeat and makes later sends fail with ErrSSEClosed. It does
+// not end the response; the stream ends when the handler returns.
+func (s *SSESender) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+	return nil
+}
+
+// sseData encodes the data of an event.
+func sseData(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// sseFrame formats an event in the text/event-stream format.
+func sseFrame(event, id string, data []byte) ([]byte, error) {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n\x00") {
+		return nil, errors.New("velocity: SSE event and id must not contain newlines")
+	}
+	var b bytes.Buffer
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(bytes.ReplaceAll(line, []byte("\r"), nil))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// SSEBroadcasterConfig configures an SSEBroadcaster.
+type SSEBroadcasterConfig struct {
+	// SSE configures each subscriber's stream.
+	SSE SSEConfig
+
+	// HistorySize is the number of recent events kept to replay to clients that
+	// reconnect with a Last-Event-ID. A negative value disables replay.
+	// Default: 100
+	HistorySize int
+
+	// QueueSize is the number of events buffered per subscriber. Subscribers that
+	// fall further behind are disconnected and catch up from the history when they
+	// reconnect.
+	// Default: 64
+	QueueSize int
+}
+
+var defaultSSEBroadcasterConfig = SSEBroadcasterConfig{
+	HistorySize: 100,
+	QueueSize:   64,
+}
+
+// SSEBroadcaster fans events out to many SSE subscribers. Events get sequential
+// IDs, so clients that reconnect resume after the last event they received. It is
+// safe for concurrent use.
+type SSEBroadcaster struct {
+	cfg SSEBroadcasterConfig
+
+	mu      sync.Mutex
+	subs    map[chan sseEvent]struct{}
+	history []sseEvent
+	seq     uint64
+	closed  bool
+}
+
+type sseEvent struct {
+	id    uint64
+	frame []byte
+}
+
+// NewSSEBroadcaster returns a broadcaster without subscribers.
+//
+// Example:
+//
+//	events := velocity.NewSSEBroadcaster()
+//	router.Get("/events").Handle(events.ServeHTTP)
+//
+//	events.Publish("order.created", order)
+func NewSSEBroadcaster(cfg ...SSEBroadcasterConfig) *SSEBroadcaster {
+	config := defaultSSEBroadcasterConfig
+	if len(cfg) > 0 {
+		config.SSE = cfg[0].SSE
+		if cfg[0].HistorySize != 0 {
+			config.HistorySize = cfg[0].HistorySize
+		}
+		if cfg[0].QueueSize > 0 {
+			config.QueueSize = cfg[0].QueueSize
+		}
+	}
+	return &SSEBroadcaster{cfg: config, subs: make(map[chan sseEvent]struct{})}
+}
+
+// Publish sends an event to every subscriber and returns its ID. data is encoded
+// as by SSESender.Send.
+func (b *SSEBroadcaster) Publish(event string, data any) (string, error) {
+	payload, err := sseData(data)
+	if err != nil {
+		return "", err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return "", ErrSSEClosed
+	}
+	b.seq++
+	id := strconv.FormatUint(b.seq, 10)
+	frame, err := sseFrame(event, id, payload)
+	if err != nil {
+		b.seq--
+		return "", err
+	}
+	e := sseEvent{id: b.seq, frame: frame}
+	if b.cfg.HistorySize > 0 {
+		if len(b.history) == b.cfg.HistorySize {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, e)
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			// Too slow; the client reconnects and resumes from the history
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return id, nil
+}
+
+// ServeHTTP streams published events to the client until it disconnects or the
+// broadcaster is closed, first replaying the events it missed if it reconnected
+// with a Last-Event-ID.
+func (b *SSEBroadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s, err := SSE(w, r, b.cfg.SSE)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+
+	ch := make(chan sseEvent, b.cfg.QueueSize)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	// Collected under the lock, so no event is both replayed and delivered
+	var replay []sseEvent
+	if last, err := strconv.ParseUint(s.LastEventID(), 10, 64); err == nil {
+		for _, e := range b.history {
+			if e.id > last {
+				replay = append(replay, e)
+			}
+		}
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	defer b.unsubscribe(ch)
+
+	for _, e := range replay {
+		if s.write(e.frame) != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if s.write(e.frame) != nil {
+				return
+			}
+		case <-s.Done():
+			return
+		}
+	}
+}
+
+func (b *SSEBroadcaster) unsubscribe(ch chan sseEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// Len returns the number of subscribers.
+func (b *SSEBroadcaster) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close ends every subscriber's stream and rejects new subscribers and events.
+// Open streams keep the server's Shutdown waiting, so close the broadcaster before
+// shutting down.
+func (b *SSEBroadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
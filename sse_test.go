@@ -0,0 +1,153 @@
+package velocity_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+// readEvent reads lines up to the next blank line, skipping heartbeat comments
+// unless keepComments is set.
+func readEvent(t *testing.T, br *bufio.Reader, keepComments bool) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		if line == "\n" {
+			if len(lines) > 0 {
+				return strings.Join(lines, "")
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") && !keepComments {
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Get("/events").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		s, err := velocity.SSE(w, r, velocity.SSEConfig{HeartbeatInterval: 20 * time.Millisecond, Retry: 3 * time.Second})
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		if err := s.Send("", "", "resumed after "+s.LastEventID()); err != nil {
+			return err
+		}
+		if err := s.Send("update", "7", map[string]int{"progress": 50}); err != nil {
+			return err
+		}
+		if err := s.Send("", "", "line one\nline two"); err != nil {
+			return err
+		}
+		if err := s.Send("bad\nevent", "", nil); err == nil {
+			t.Error("expected an error for an event name with a newline")
+		}
+		<-s.Done()
+		return nil
+	})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "42")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %q", ct)
+	}
+	if cc := res.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected no-cache, got %q", cc)
+	}
+
+	br := bufio.NewReader(res.Body)
+	expected := []string{
+		"retry: 3000\n",
+		"data: resumed after 42\n",
+		"id: 7\nevent: update\ndata: {\"progress\":50}\n",
+		"data: line one\ndata: line two\n",
+	}
+	for _, e := range expected {
+		if got := readEvent(t, br, false); got != e {
+			t.Errorf("expected event %q, got %q", e, got)
+		}
+	}
+	if got := readEvent(t, br, true); got != ":\n" {
+		t.Errorf("expected heartbeat comment, got %q", got)
+	}
+}
+
+func TestSSEBroadcaster(t *testing.T) {
+	events := velocity.NewSSEBroadcaster(velocity.SSEBroadcasterConfig{HistorySize: 2})
+	app := velocity.New()
+	app.Router("/").Get("/events").Handle(events.ServeHTTP)
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	subscribe := func(lastEventID string) *bufio.Reader {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return bufio.NewReader(res.Body)
+	}
+	waitSubscribers := func(n int) {
+		for range 100 {
+			if events.Len() == n {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected %d subscribers, got %d", n, events.Len())
+	}
+
+	a := subscribe("")
+	waitSubscribers(1)
+	for _, msg := range []string{"one", "two", "three"} {
+		if _, err := events.Publish("msg", msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for i, msg := range []string{"one", "two", "three"} {
+		expected := "id: " + string(rune('1'+i)) + "\nevent: msg\ndata: " + msg + "\n"
+		if got := readEvent(t, a, false); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+
+	// A reconnecting client gets the events after its last ID that are still kept
+	b := subscribe("1")
+	if got := readEvent(t, b, false); got != "id: 2\nevent: msg\ndata: two\n" {
+		t.Errorf("expected replay of event 2, got %q", got)
+	}
+	if got := readEvent(t, b, false); got != "id: 3\nevent: msg\ndata: three\n" {
+		t.Errorf("expected replay of event 3, got %q", got)
+	}
+	waitSubscribers(2)
+
+	events.Close()
+	waitSubscribers(0)
+	if _, err := events.Publish("msg", "late"); err != velocity.ErrSSEClosed {
+		t.Errorf("expected ErrSSEClosed, got %v", err)
+	}
+}
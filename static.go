@@ -0,0 +1,145 @@
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Static serves files from the dir directory under the given path prefix, with
+// optional middleware. Content types are detected from the file extension or content,
+// directories serve their index.html, and missing files use the nearest NotFound
+// handler, as requests matching no route do.
+// Paths are cleaned before opening, so requests cannot escape dir with "..".
+// It returns an error if the prefix is invalid or, with AppConfig.StrictRoutes,
+// conflicts with an existing route.
+//
+// Example:
+//
+//	router.Static("/assets", "./public")
+func (r *Router) Static(p string, dir string, mws ...Middleware) error {
+	return r.static(p, http.Dir(dir), false, mws)
+}
+
+// StaticFS serves files from fsys under the given path prefix, with optional middleware.
+// It behaves like Static and is typically used with an embed.FS to ship assets
+// inside the binary.
+//
+// Example:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	assets, _ := fs.Sub(public, "public")
+//	router.StaticFS("/assets", assets)
+func (r *Router) StaticFS(p string, fsys fs.FS, mws ...Middleware) error {
+	return r.static(p, http.FS(fsys), false, mws)
+}
+
+// SPA serves a single-page application from fsys under the given path prefix, with
+// optional middleware. It behaves like StaticFS, except that requests for missing
+// paths without a file extension are answered with the root index.html so client-side
+// routing can handle them. Missing files with an extension still return 404.
+//
+// Example:
+//
+//	router.SPA("/", dist)
+func (r *Router) SPA(p string, fsys fs.FS, mws ...Middleware) error {
+	return r.static(p, http.FS(fsys), true, mws)
+}
+
+func (r *Router) static(p string, fsys http.FileSystem, spa bool, mws []Middleware) error {
+	prefix := cleanPath(r.path + p)
+	fn := func(w http.ResponseWriter, req *http.Request) {
+		name := path.Clean("/" + GetParams(req)["*"])
+		if serveFile(w, req, fsys, name) {
+			return
+		}
+		if spa && path.Ext(name) == "" && serveFile(w, req, fsys, "/index.html") {
+			return
+		}
+		// The router middleware already ran for the static route
+		r.notFoundHandler()(w, req)
+	}
+	errs := []error{}
+	for _, sp := range []string{prefix, cleanPath(prefix + "/*")} {
+		errs = append(errs, r.newRoute(sp, mws, mGET).Handle(fn))
+	}
+	return errors.Join(errs...)
+}
+
+// File serves the file at name, typically a path computed by the application rather
+// than taken from the request. Like Static, it detects the content type, supports
+// Range requests and answers conditional requests such as If-Modified-Since with
+// 304 Not Modified. It returns an error, without writing a response, if the file
+// cannot be opened or is a directory.
+//
+// Example:
+//
+//	router.Get("/reports/:id").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    return velocity.File(w, r, filepath.Join(reportDir, velocity.Param(r, "id")+".pdf"))
+//	})
+func File(w http.ResponseWriter, r *http.Request, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		return fmt.Errorf("velocity: %s is a directory", name)
+	}
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+	return nil
+}
+
+// Attachment serves the file at name like File, with a Content-Disposition header
+// that makes browsers download it as filename. Non-ASCII filenames are encoded as
+// described in RFC 6266.
+//
+// Example:
+//
+//	router.Get("/invoices/:id/download").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    return velocity.Attachment(w, r, invoicePath(r), "invoice.pdf")
+//	})
+func Attachment(w http.ResponseWriter, r *http.Request, name, filename string) error {
+	if filename == "" {
+		filename = filepath.Base(name)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	if err := File(w, r, name); err != nil {
+		w.Header().Del("Content-Disposition")
+		return err
+	}
+	return nil
+}
+
+// serveFile serves name from fsys, falling back to index.html for directories.
+// It reports false if no file could be served.
+func serveFile(w http.ResponseWriter, r *http.Request, fsys http.FileSystem, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if stat.IsDir() {
+		return serveFile(w, r, fsys, path.Join(name, "index.html"))
+	}
+
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+	return true
+}
@@ -0,0 +1,217 @@
+package velocity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestStatic(t *testing.T) {
+	root := t.TempDir()
+	public := filepath.Join(root, "public")
+	os.MkdirAll(filepath.Join(public, "docs"), 0o755)
+	os.WriteFile(filepath.Join(public, "app.css"), []byte("body{}"), 0o644)
+	os.WriteFile(filepath.Join(public, "index.html"), []byte("<h1>home</h1>"), 0o644)
+	os.WriteFile(filepath.Join(public, "docs", "guide.json"), []byte(`{"ok":true}`), 0o644)
+	os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644)
+
+	app := velocity.New()
+	router := app.Router("/")
+	router.Static("/assets", public)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{"css file", "/assets/app.css", http.StatusOK, "text/css", "body{}"},
+		{"nested json file", "/assets/docs/guide.json", http.StatusOK, "application/json", `{"ok":true}`},
+		{"directory index", "/assets", http.StatusOK, "text/html", "<h1>home</h1>"},
+		{"missing file", "/assets/missing.js", http.StatusNotFound, "", ""},
+		{"directory without index", "/assets/docs", http.StatusNotFound, "", ""},
+		{"path traversal", "/assets/../secret.txt", http.StatusNotFound, "", ""},
+		{"encoded path traversal", "/assets/..%2fsecret.txt", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.expectedType) {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, ct)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), "secret") {
+				t.Error("served a file outside the static directory")
+			}
+		})
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("<div id=app></div>")},
+		"js/app.js":      {Data: []byte("console.log(1)")},
+		"docs/help.html": {Data: []byte("<p>help</p>")},
+	}
+
+	app := velocity.New()
+	router := app.Router("/")
+	router.StaticFS("/static", fsys)
+	router.SPA("/app", fsys)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{"static file", "/static/js/app.js", http.StatusOK, "text/javascript", "console.log(1)"},
+		{"static missing route", "/static/dashboard", http.StatusNotFound, "", ""},
+		{"spa file", "/app/docs/help.html", http.StatusOK, "text/html", "<p>help</p>"},
+		{"spa fallback", "/app/dashboard/settings", http.StatusOK, "text/html", "<div id=app></div>"},
+		{"spa missing asset", "/app/js/missing.js", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.expectedType) {
+				t.Errorf("expected content type %q, got %q", tt.expectedType, ct)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticNotFound(t *testing.T) {
+	app := velocity.New()
+	docs := app.Router("/").Group("/docs")
+	docs.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such page", http.StatusNotFound)
+	})
+	docs.StaticFS("/", fstest.MapFS{"index.html": {Data: []byte("docs")}})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/missing.html", nil))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "no such page\n" {
+		t.Errorf("expected the group's 404 handler, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.json")
+	os.WriteFile(report, []byte(`{"total":42}`), 0o644)
+	modTime := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(report, modTime, modTime)
+
+	app := velocity.New()
+	router := app.Router("/")
+	router.Get("/file").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return velocity.File(w, r, report)
+	})
+	router.Get("/download").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return velocity.Attachment(w, r, report, "résumé 2024.json")
+	})
+	router.Get("/missing").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		return velocity.Attachment(w, r, filepath.Join(dir, "missing.json"), "")
+	})
+
+	tests := []struct {
+		name                string
+		path                string
+		header              map[string]string
+		expectedStatus      int
+		expectedBody        string
+		expectedDisposition string
+	}{
+		{name: "file", path: "/file", expectedStatus: http.StatusOK, expectedBody: `{"total":42}`},
+		{name: "range", path: "/file", header: map[string]string{"Range": "bytes=1-7"}, expectedStatus: http.StatusPartialContent, expectedBody: `"total"`},
+		{
+			name:           "not modified",
+			path:           "/file",
+			header:         map[string]string{"If-Modified-Since": modTime.Add(time.Hour).Format(http.TimeFormat)},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:                "attachment",
+			path:                "/download",
+			expectedStatus:      http.StatusOK,
+			expectedBody:        `{"total":42}`,
+			expectedDisposition: "attachment; filename*=utf-8''r%C3%A9sum%C3%A9%202024.json",
+		},
+		{name: "missing file", path: "/missing", expectedStatus: http.StatusInternalServerError, expectedBody: "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, got)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != tt.expectedDisposition {
+				t.Errorf("expected Content-Disposition %q, got %q", tt.expectedDisposition, got)
+			}
+			if tt.expectedStatus == http.StatusOK && !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+				t.Errorf("expected JSON content type, got %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestStaticErrors(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("home")}}
+
+	app := velocity.New()
+	router := app.Router("/")
+	if err := router.Static("/:1bad", t.TempDir()); err == nil {
+		t.Error("expected error for invalid Static prefix")
+	}
+	if err := router.StaticFS("/:1bad", fsys); err == nil {
+		t.Error("expected error for invalid StaticFS prefix")
+	}
+	if err := router.SPA("/:1bad", fsys); err == nil {
+		t.Error("expected error for invalid SPA prefix")
+	}
+
+	strict := velocity.New(velocity.AppConfig{StrictRoutes: true})
+	strict.Router("/").Get("/assets").Handle(func(w http.ResponseWriter, r *http.Request) {})
+	if err := strict.Router("/").StaticFS("/assets", fsys); err == nil {
+		t.Error("expected error for prefix conflicting with an existing route")
+	}
+}
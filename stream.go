@@ -0,0 +1,89 @@
+package velocity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StreamWriter writes a streamed response body. Writes fail once the client has
+// disconnected, so long-running producers stop as soon as nobody is listening.
+type StreamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+}
+
+// Stream starts a streamed response and calls fn to produce its body. The status
+// and headers, 200 OK unless already written, are sent to the client before fn runs,
+// and the server's WriteTimeout is lifted for the response so it can stream for as
+// long as needed. It returns fn's error, or the request context's error once the
+// client has disconnected.
+//
+// Example:
+//
+//	router.Get("/export").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    w.Header().Set("Content-Type", "application/x-ndjson")
+//	    return velocity.Stream(w, r, func(sw *velocity.StreamWriter) error {
+//	        for row := range rows(r.Context()) {
+//	            if err := sw.WriteJSON(row); err != nil {
+//	                return err
+//	            }
+//	            sw.Flush()
+//	        }
+//	        return nil
+//	    })
+//	})
+func Stream(w http.ResponseWriter, r *http.Request, fn func(sw *StreamWriter) error) error {
+	sw := &StreamWriter{w: w, rc: http.NewResponseController(w), ctx: r.Context()}
+	if err := sw.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	sw.Flush()
+	if err := fn(sw); err != nil {
+		return err
+	}
+	return sw.ctx.Err()
+}
+
+// Write writes p to the response. It fails with the request context's error once
+// the client has disconnected.
+func (sw *StreamWriter) Write(p []byte) (int, error) {
+	if err := sw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return sw.w.Write(p)
+}
+
+// WriteJSON writes v as a single line of JSON, as used by NDJSON streams.
+func (sw *StreamWriter) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = sw.Write(append(b, '\n'))
+	return err
+}
+
+// Flush sends buffered data to the client. It is a no-op if the response writer
+// does not support flushing.
+func (sw *StreamWriter) Flush() error {
+	if err := sw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Done returns a channel that is closed once the client has disconnected or the
+// request context is otherwise done.
+func (sw *StreamWriter) Done() <-chan struct{} {
+	return sw.ctx.Done()
+}
+
+// Context returns the request context.
+func (sw *StreamWriter) Context() context.Context {
+	return sw.ctx
+}
@@ -0,0 +1,72 @@
+package velocity_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestStream(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan error, 1)
+
+	app := velocity.New()
+	app.Router("/").Get("/export").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err := velocity.Stream(w, r, func(sw *velocity.StreamWriter) error {
+			if err := sw.WriteJSON(map[string]int{"row": 1}); err != nil {
+				return err
+			}
+			sw.Flush()
+			<-release
+			// Keep writing until the disconnect is noticed
+			for {
+				if err := sw.WriteJSON(map[string]int{"row": 2}); err != nil {
+					return err
+				}
+				sw.Flush()
+				time.Sleep(time.Millisecond)
+			}
+		})
+		done <- err
+		return nil
+	})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/export", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected content type %q, got %q", "application/x-ndjson", ct)
+	}
+
+	// The first row arrives while the handler is still running
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if line != `{"row":1}`+"\n" {
+		t.Errorf("expected first row, got %q", line)
+	}
+
+	cancel()
+	close(release)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error after the client disconnected")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not stop after the client disconnected")
+	}
+}
@@ -0,0 +1,72 @@
+package velocity
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// Toggle is a boolean switch that can be flipped at runtime, e.g. from an admin
+// endpoint or a signal, and read concurrently by middleware such as
+// middleware.Maintenance. The zero value is a disabled toggle.
+type Toggle struct {
+	on atomic.Bool
+}
+
+// Enabled reports whether t is on.
+func (t *Toggle) Enabled() bool {
+	return t.on.Load()
+}
+
+// Set turns t on or off.
+func (t *Toggle) Set(on bool) {
+	t.on.Store(on)
+}
+
+// Enable turns t on.
+func (t *Toggle) Enable() {
+	t.on.Store(true)
+}
+
+// Disable turns t off.
+func (t *Toggle) Disable() {
+	t.on.Store(false)
+}
+
+// Flip inverts t and returns its new state.
+func (t *Toggle) Flip() bool {
+	for {
+		old := t.on.Load()
+		if t.on.CompareAndSwap(old, !old) {
+			return !old
+		}
+	}
+}
+
+// FlipOnSignal flips t whenever the process receives one of sigs, until the
+// returned stop function is called.
+//
+// Example:
+//
+//	var maintenance velocity.Toggle
+//	stop := maintenance.FlipOnSignal(syscall.SIGUSR1)
+//	defer stop()
+func (t *Toggle) FlipOnSignal(sigs ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				t.Flip()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,43 @@
+package velocity_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestToggle(t *testing.T) {
+	var toggle velocity.Toggle
+	if toggle.Enabled() {
+		t.Fatal("expected zero Toggle to be disabled")
+	}
+	toggle.Enable()
+	if !toggle.Enabled() {
+		t.Error("expected Enable to turn the toggle on")
+	}
+	if toggle.Flip() || toggle.Enabled() {
+		t.Error("expected Flip to turn the toggle off")
+	}
+	toggle.Set(true)
+	toggle.Disable()
+	if toggle.Enabled() {
+		t.Error("expected Disable to turn the toggle off")
+	}
+}
+
+func TestToggleFlipOnSignal(t *testing.T) {
+	var toggle velocity.Toggle
+	stop := toggle.FlipOnSignal(syscall.SIGUSR1)
+	defer stop()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	deadline := time.Now().Add(time.Second)
+	for !toggle.Enabled() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected signal to flip the toggle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,6 +1,7 @@
 package velocity
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
@@ -10,16 +11,24 @@ type (
 	nType uint8
 	tree  = node
 	node  struct {
-		nType    nType
-		prefix   string
-		children map[byte]*node
-		special  [catchAll + 1]*node
-		endpoint *endpoint
+		nType       nType
+		prefix      string
+		children    map[byte]*node
+		special     [catchAll + 1]*node
+		constrained []*node
+		regex       *regexp.Regexp
+		key         string
+		endpoint    *endpoint
 	}
 	endpoint struct {
 		fn       http.HandlerFunc
 		fullPath string
 		pKeys    []string
+		pTypes   []string
+		typed    bool
+		format   bool
+		implicit bool
+		meta     *routeMeta
 	}
 )
 
@@ -31,6 +40,14 @@ const (
 
 var paramRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
+// formatSuffix marks an optional format extension on a trailing param segment,
+// e.g. "/users/:id.:format?" matches both "/users/42.json" and "/users/42".
+const formatSuffix = ".:format?"
+
+// optionalSuffix marks an optional trailing param segment, e.g.
+// "/posts/:year/:month?" matches both "/posts/2024" and "/posts/2024/05".
+const optionalSuffix = "?"
+
 func newNode(nType nType, prefix string) *node {
 	return &node{
 		nType:    nType,
@@ -59,6 +76,10 @@ func newEndpoint(path string, fn *http.HandlerFunc, pKeys []string) *endpoint {
 	}
 }
 
+func (e *endpoint) catchAll() bool {
+	return len(e.pKeys) > 0 && e.pKeys[len(e.pKeys)-1] == "*"
+}
+
 func (n *node) addChild(label byte, node *node) {
 	n.children[label] = node
 }
@@ -67,14 +88,20 @@ func (n *node) addSpecial(nType nType, node *node) {
 	n.special[nType] = node
 }
 
+func (n *node) addConstrained(node *node) {
+	n.constrained = append(n.constrained, node)
+}
+
 func (n *node) clearChildren() {
 	n.children = make(map[byte]*node)
 	n.special = [catchAll + 1]*node{}
+	n.constrained = nil
 }
 
 func (n *node) copyFrom(node *node) {
 	n.children = node.children
 	n.special = node.special
+	n.constrained = node.constrained
 	n.endpoint = node.endpoint
 }
 
@@ -82,14 +109,25 @@ func (n *node) setEndpoint(e *endpoint) {
 	n.endpoint = e
 }
 
-func (t *tree) insert(p string, fn http.HandlerFunc) {
+// insert registers fn under path p with the route's metadata. In strict mode, overriding
+// an existing route or reusing a param position under a different name is rejected.
+func (t *tree) insert(p string, fn http.HandlerFunc, meta *routeMeta, strict bool) error {
 	p = cleanPath(p)
-	if !isValidPath(p) {
-		return
+	fullPath := p
+	p, format := splitFormat(p)
+	optional := false
+	if !format {
+		p, optional = splitOptional(p)
+	}
+	if !isValidPath(p, optional) {
+		return fmt.Errorf("velocity: invalid route path %q", fullPath)
 	}
 	cur := t
+	parent := t
 	pKeys := []string{}
+	pTypes := []string{}
 	for _, seg := range splitPath(p) {
+		parent = cur
 		switch getSegmentType(seg) {
 		case static:
 			search := seg
@@ -127,17 +165,25 @@ func (t *tree) insert(p string, fn http.HandlerFunc) {
 				search = search[len(lcp):]
 			}
 		case param:
-			pKeys = append(pKeys, seg[1:])
-			n := cur.special[param]
-			if n == nil {
-				new := newNode(param, "")
-				cur.addSpecial(param, new)
-				cur = new
-				continue
+			name, typ, pattern := parseParam(seg)
+			pKeys = append(pKeys, name)
+			pTypes = append(pTypes, typ)
+			var n *node
+			if pattern != "" {
+				n = cur.constrainedChild(pattern)
+			} else if n = cur.special[param]; n == nil {
+				n = newNode(param, "")
+				cur.addSpecial(param, n)
+			}
+			if n.key == "" {
+				n.key = name
+			} else if strict && n.key != name {
+				return fmt.Errorf("velocity: route %q: param :%s conflicts with :%s of an existing route", fullPath, name, n.key)
 			}
 			cur = n
 		case catchAll:
 			pKeys = append(pKeys, "*")
+			pTypes = append(pTypes, "")
 			n := cur.special[catchAll]
 			if n == nil {
 				new := newNode(catchAll, "")
@@ -149,78 +195,234 @@ func (t *tree) insert(p string, fn http.HandlerFunc) {
 		}
 
 	}
-	e := newEndpoint(p, &fn, pKeys)
+	if strict && cur.endpoint != nil {
+		return fmt.Errorf("velocity: route %q conflicts with existing route %q", fullPath, cur.endpoint.fullPath)
+	}
+	if strict && optional && parent.endpoint != nil {
+		return fmt.Errorf("velocity: route %q conflicts with existing route %q", fullPath, parent.endpoint.fullPath)
+	}
+	if format {
+		pKeys = append(pKeys, "format")
+		pTypes = append(pTypes, "")
+	}
+	e := newEndpoint(fullPath, &fn, pKeys)
+	e.format = format
+	e.meta = meta
+	for _, typ := range pTypes {
+		if typ != "" {
+			e.pTypes = pTypes
+			e.typed = true
+			break
+		}
+	}
 	cur.setEndpoint(e)
+	if optional {
+		// Register the route without its optional param on the parent node
+		pe := *e
+		pe.pKeys = pKeys[:len(pKeys)-1]
+		if pe.typed {
+			pe.pTypes = pTypes[:len(pTypes)-1]
+		}
+		pe.implicit = true
+		parent.setEndpoint(&pe)
+	}
+	return nil
 }
 
-func (t *tree) find(p string) (*endpoint, map[string]string) {
-	params := []string{}
+// remove unregisters the route registered under path p and returns its endpoint, or
+// nil if no route is registered under p.
+func (t *tree) remove(p string) *endpoint {
+	p, format := splitFormat(cleanPath(p))
+	optional := false
+	if !format {
+		p, optional = splitOptional(p)
+	}
+	if !isValidPath(p, optional) {
+		return nil
+	}
 	cur := t
-	var prevLabel *byte
-	start := 0
-	for len(p) > 0 {
+	parent := t
+	for _, seg := range splitPath(p) {
+		parent = cur
+		switch getSegmentType(seg) {
+		case static:
+			for search := seg; len(search) > 0; {
+				next := cur.children[search[0]]
+				if next == nil || !strings.HasPrefix(search, next.prefix) {
+					return nil
+				}
+				search = search[len(next.prefix):]
+				cur = next
+			}
+		case param:
+			_, _, pattern := parseParam(seg)
+			next := cur.special[param]
+			if pattern != "" {
+				next = nil
+				for _, c := range cur.constrained {
+					if c.prefix == pattern {
+						next = c
+					}
+				}
+			}
+			if next == nil {
+				return nil
+			}
+			cur = next
+		case catchAll:
+			if cur = cur.special[catchAll]; cur == nil {
+				return nil
+			}
+		}
+	}
+	e := cur.endpoint
+	if e == nil {
+		return nil
+	}
+	cur.setEndpoint(nil)
+	if optional && parent.endpoint != nil && parent.endpoint.implicit {
+		parent.setEndpoint(nil)
+	}
+	return e
+}
 
-		if p[0] == '/' {
-			p = p[1:]
-			continue
+// clone returns a deep copy of the node and its children. Endpoints are shared.
+func (n *node) clone() *node {
+	c := *n
+	c.children = make(map[byte]*node, len(n.children))
+	for label, child := range n.children {
+		c.children[label] = child.clone()
+	}
+	for i, s := range n.special {
+		if s != nil {
+			c.special[i] = s.clone()
 		}
+	}
+	c.constrained = nil
+	for _, child := range n.constrained {
+		c.constrained = append(c.constrained, child.clone())
+	}
+	return &c
+}
 
-		label := p[0]
-		if prevLabel != nil {
-			label = *prevLabel
+func (n *node) constrainedChild(pattern string) *node {
+	for _, c := range n.constrained {
+		if c.prefix == pattern {
+			return c
 		}
+	}
+	new := newNode(param, pattern)
+	new.regex = compileConstraint(pattern)
+	n.addConstrained(new)
+	return new
+}
 
-		if static := cur.children[label]; static != nil {
-			lcp := longestPrefix(p, static.prefix[start:])
-			if len(lcp) == 0 {
-				break
-			}
-			if len(lcp)+start == len(static.prefix) {
-				cur = static
-				p = p[len(lcp):]
-				prevLabel = nil
-				start = 0
-			} else {
-				prevLabel = &label
-				start += len(lcp)
-				p = p[len(lcp):]
+func compileConstraint(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("^(?:" + pattern + ")$")
+}
+
+// find returns the endpoint matching path p, capturing its params into rp unless
+// rp is nil. A branch that dead-ends is abandoned for the next candidate in priority
+// order: static, then constrained params, then plain params, then catch-all.
+func (t *tree) find(p string, rp *routeParams) *endpoint {
+	var values []string
+	if rp != nil {
+		values = rp.values[:0]
+	}
+	n, values := t.match(p, values)
+	if n == nil {
+		return nil
+	}
+	if rp != nil {
+		n.endpoint.capture(rp, values)
+	}
+	return n.endpoint
+}
+
+// match walks the tree with backtracking and returns the node whose endpoint
+// matches p, along with the captured param values.
+func (n *node) match(p string, params []string) (*node, []string) {
+	p = strings.TrimLeft(p, "/")
+	if p == "" {
+		if n.endpoint != nil {
+			return n, params
+		}
+		return nil, nil
+	}
+
+	if static := n.children[p[0]]; static != nil {
+		if rest, ok := consumePrefix(p, static.prefix); ok {
+			if m, ps := static.match(rest, params); m != nil {
+				return m, ps
 			}
-			continue
 		}
+	}
 
-		if param := cur.special[param]; param != nil {
-			j := strings.IndexByte(p, '/')
-			if j == -1 {
-				params = append(params, p)
-				cur = param
-				p = ""
-			} else {
-				params = append(params, p[:j])
-				cur = param
-				p = p[j+1:]
+	if len(n.constrained) > 0 || n.special[param] != nil {
+		seg, rest := p, ""
+		if j := strings.IndexByte(p, '/'); j != -1 {
+			seg, rest = p[:j], p[j+1:]
+		}
+		for _, c := range n.constrained {
+			if !c.regex.MatchString(seg) {
+				continue
+			}
+			if m, ps := c.match(rest, append(params, seg)); m != nil {
+				return m, ps
 			}
-			continue
 		}
+		if c := n.special[param]; c != nil {
+			if m, ps := c.match(rest, append(params, seg)); m != nil {
+				return m, ps
+			}
+		}
+	}
 
-		if catchAll := cur.special[catchAll]; catchAll != nil {
-			params = append(params, p)
-			cur = catchAll
-			p = ""
-			continue
+	if c := n.special[catchAll]; c != nil && c.endpoint != nil {
+		return c, append(params, p)
+	}
+	return nil, nil
+}
+
+// consumePrefix matches the static prefix against p, skipping slashes in p since
+// static segments are stored without them, and returns the remainder of p.
+func consumePrefix(p, prefix string) (string, bool) {
+	j := 0
+	for i := 0; i < len(prefix); i++ {
+		for j < len(p) && p[j] == '/' {
+			j++
 		}
-		return nil, map[string]string{}
+		if j == len(p) || p[j] != prefix[i] {
+			return "", false
+		}
+		j++
 	}
+	return p[j:], true
+}
 
-	if cur.endpoint == nil {
-		return nil, map[string]string{}
+// capture maps the captured param values to the endpoint's keys, reusing the
+// buffers of rp.
+func (e *endpoint) capture(rp *routeParams, values []string) {
+	rp.pairs = rp.pairs[:0]
+	// Fast path for static routes: no params to map
+	if len(e.pKeys) == 0 {
+		return
 	}
 
-	pMap := map[string]string{}
-	for i, k := range cur.endpoint.pKeys {
-		pMap[k] = params[i]
+	if e.format {
+		last := values[len(values)-1]
+		ext := ""
+		if i := strings.LastIndexByte(last, '.'); i > 0 {
+			last, ext = last[:i], last[i+1:]
+		}
+		values = append(values[:len(values)-1], last, ext)
 	}
+	// Keep the grown buffer for the next request
+	rp.values = values[:0]
 
-	return cur.endpoint, pMap
+	for i, k := range e.pKeys {
+		rp.pairs = append(rp.pairs, pathParam{key: k, value: values[i]})
+	}
 }
 
 func splitPath(p string) []string {
@@ -245,6 +447,30 @@ func splitPath(p string) []string {
 	return segments
 }
 
+func splitFormat(p string) (string, bool) {
+	base, ok := strings.CutSuffix(p, formatSuffix)
+	if !ok {
+		return p, false
+	}
+	segments := strings.Split(base, "/")
+	if last := segments[len(segments)-1]; last == "" || getSegmentType(last) != param {
+		return p, false
+	}
+	return base, true
+}
+
+func splitOptional(p string) (string, bool) {
+	base, ok := strings.CutSuffix(p, optionalSuffix)
+	if !ok {
+		return p, false
+	}
+	segments := strings.Split(base, "/")
+	if last := segments[len(segments)-1]; last == "" || getSegmentType(last) != param {
+		return p, false
+	}
+	return base, true
+}
+
 func cleanPath(p string) string {
 	p = strings.TrimPrefix(p, "/")
 	p = strings.TrimSuffix(p, "/")
@@ -258,8 +484,23 @@ func cleanPath(p string) string {
 	return "/" + strings.Join(final, "/")
 }
 
+// parseParam splits a param segment such as ":id<int>([0-9]+)" into its name,
+// optional type and optional regex constraint.
+func parseParam(seg string) (name, typ, pattern string) {
+	name = seg[1:]
+	if i := strings.IndexByte(name, '('); i != -1 && strings.HasSuffix(name, ")") {
+		name, pattern = name[:i], name[i+1:len(name)-1]
+	}
+	if i := strings.IndexByte(name, '<'); i != -1 && strings.HasSuffix(name, ">") {
+		name, typ = name[:i], name[i+1:len(name)-1]
+	}
+	return name, typ, pattern
+}
+
 func getSegmentType(s string) nType {
 	switch {
+	case s == "":
+		return static
 	case s[0] == ':':
 		return param
 	case s[0] == '*':
@@ -282,7 +523,9 @@ func longestPrefix(s1, s2 string) string {
 	return s1[:min]
 }
 
-func isValidPath(p string) bool {
+// isValidPath reports whether p is a valid route path. When optional is set, the
+// final param segment may directly follow another param.
+func isValidPath(p string, optional bool) bool {
 	var prevTyp *nType
 	segments := splitPath(p)
 	keys := map[string]struct{}{}
@@ -291,25 +534,35 @@ func isValidPath(p string) bool {
 			continue
 		}
 		typ := getSegmentType(seg)
-		// Cannot have two variadic segments together
-		if prevTyp != nil && *prevTyp != static && typ != static {
+		// Cannot have two variadic segments together, except an optional final param
+		if prevTyp != nil && *prevTyp != static && typ != static && !(optional && i == len(segments)-1) {
 			return false
 		}
 		// Catch-all must be last
 		if typ == catchAll && i != len(segments)-1 {
 			return false
 		}
-		// Cannot have repeat param keys
 		if typ == param {
-			_, ok := keys[seg]
-			if ok {
+			name, typ, pattern := parseParam(seg)
+			// Cannot have repeat param keys
+			if _, ok := keys[name]; ok {
 				return false
 			}
-			keys[seg] = struct{}{}
-		}
-		// Is invalid param name
-		if typ == param && !paramRegex.MatchString(seg[1:]) {
-			return false
+			keys[name] = struct{}{}
+			// Is invalid param name
+			if !paramRegex.MatchString(name) {
+				return false
+			}
+			// Is unknown param type
+			if _, ok := paramConverters[typ]; typ != "" && !ok {
+				return false
+			}
+			// Is invalid regex constraint
+			if strings.ContainsRune(seg, '(') {
+				if _, err := regexp.Compile(pattern); pattern == "" || err != nil {
+					return false
+				}
+			}
 		}
 		// Catch all may only contain "*"
 		if typ == catchAll && seg != "*" {
@@ -321,24 +574,33 @@ func isValidPath(p string) bool {
 }
 
 func (t *tree) captureRoutes(m string) []string {
-	return recurseCapture(m, t, []string{})
+	r := []string{}
+	for _, e := range t.captureEndpoints() {
+		r = append(r, m+" "+e.fullPath)
+	}
+	return r
 }
 
-func recurseCapture(m string, n *node, r []string) []string {
+// captureEndpoints returns the endpoints registered in the tree, skipping the implicit
+// endpoints of omitted optional params and internal routes.
+func (t *tree) captureEndpoints() []*endpoint {
+	return recurseCapture(t, []*endpoint{})
+}
+
+func recurseCapture(n *node, r []*endpoint) []*endpoint {
+	if n.endpoint != nil && !n.endpoint.implicit && (n.endpoint.meta == nil || !n.endpoint.meta.internal) {
+		r = append(r, n.endpoint)
+	}
 	for _, c := range n.special {
-		if c == nil {
-			continue
+		if c != nil {
+			r = recurseCapture(c, r)
 		}
-		if c.endpoint != nil {
-			r = append(r, m+" "+c.endpoint.fullPath)
-		}
-		r = recurseCapture(m, c, r)
+	}
+	for _, c := range n.constrained {
+		r = recurseCapture(c, r)
 	}
 	for _, c := range n.children {
-		if c.endpoint != nil {
-			r = append(r, m+" "+c.endpoint.fullPath)
-		}
-		r = recurseCapture(m, c, r)
+		r = recurseCapture(c, r)
 	}
 	return r
 }
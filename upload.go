@@ -0,0 +1,168 @@
+package velocity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// UploadOpts configures FormFile.
+type UploadOpts struct {
+	// MaxSize is the maximum file size in bytes. Larger files fail with a 413
+	// HTTPError. Zero means no limit.
+	MaxSize int64
+
+	// AllowedTypes lists the accepted media types, detected by sniffing the file
+	// content rather than trusting the client. Wildcards such as "image/*" are
+	// supported. Other files fail with a 415 HTTPError. Empty allows any type.
+	AllowedTypes []string
+
+	// Dest receives the file content. If nil, the file is stored in a temporary
+	// file that is removed once the request is done. When the file exceeds
+	// MaxSize, up to MaxSize+1 bytes have already been written to Dest.
+	Dest io.Writer
+
+	// TempDir is the directory for temporary files. Default: os.TempDir()
+	TempDir string
+}
+
+// Upload describes a file received by FormFile.
+type Upload struct {
+	// Filename is the client-supplied file name, which must not be trusted as a path.
+	Filename string
+	// ContentType is the media type detected from the file content.
+	ContentType string
+	// Size is the file size in bytes.
+	Size int64
+	// Path is the temporary file holding the content, empty if UploadOpts.Dest was set.
+	Path string
+}
+
+// FormFile streams the file in the multipart form field name to a temporary file or
+// opts.Dest without buffering the whole request in memory. The content type is
+// detected from the first 512 bytes of the file. Temporary files are removed when the
+// request is done, so they must be moved or copied by the handler to be kept.
+//
+// FormFile reads the request body up to the field, so form fields after it are not
+// available and it cannot be combined with r.ParseMultipartForm. Failures caused by
+// the request, including truncated or malformed file content, are returned as
+// *HTTPError with a suitable status; errors writing to Dest are returned unchanged.
+//
+// Example:
+//
+//	router.Post("/avatar").HandleE(func(w http.ResponseWriter, r *http.Request) error {
+//	    up, err := velocity.FormFile(r, "avatar", velocity.UploadOpts{
+//	        MaxSize:      5 << 20,
+//	        AllowedTypes: []string{"image/png", "image/jpeg"},
+//	    })
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return storeAvatar(up.Path)
+//	})
+func FormFile(r *http.Request, name string, opts ...UploadOpts) (*Upload, error) {
+	var o UploadOpts
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Message: "expected a multipart form"}
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Message: fmt.Sprintf("missing file %q", name)}
+		}
+		if err != nil {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Message: "malformed multipart form"}
+		}
+		if part.FormName() != name || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		// Sniff the content type before writing anything
+		head := make([]byte, 512)
+		n, err := io.ReadFull(part, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Message: "malformed multipart form"}
+		}
+		head = head[:n]
+		ct := http.DetectContentType(head)
+		if !allowedType(ct, o.AllowedTypes) {
+			return nil, &HTTPError{Code: http.StatusUnsupportedMediaType, Message: fmt.Sprintf("file type %s is not allowed", ct)}
+		}
+
+		up := &Upload{Filename: part.FileName(), ContentType: ct}
+		dest := o.Dest
+		if dest == nil {
+			f, err := os.CreateTemp(o.TempDir, "velocity-upload-*")
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			up.Path = f.Name()
+			context.AfterFunc(r.Context(), func() { os.Remove(up.Path) })
+			dest = f
+		}
+
+		src := &readErrReader{r: io.MultiReader(bytes.NewReader(head), part)}
+		if o.MaxSize > 0 {
+			// Read one byte past the limit to detect oversized files
+			src.r = io.LimitReader(src.r, o.MaxSize+1)
+		}
+		up.Size, err = io.Copy(dest, src)
+		if src.err != nil {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Message: "malformed multipart form"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if o.MaxSize > 0 && up.Size > o.MaxSize {
+			return nil, &HTTPError{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("file exceeds %d bytes", o.MaxSize)}
+		}
+		return up, nil
+	}
+}
+
+// readErrReader records the error of r other than io.EOF, so failures reading the
+// request can be told apart from failures writing the destination.
+type readErrReader struct {
+	r   io.Reader
+	err error
+}
+
+func (rr *readErrReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		rr.err = err
+	}
+	return n, err
+}
+
+// allowedType reports whether the detected content type ct matches one of allowed.
+func allowedType(ct string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType || strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
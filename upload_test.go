@@ -0,0 +1,132 @@
+package velocity_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func multipartRequest(t *testing.T, field, filename string, content []byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "profile picture")
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fw.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormFile(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 100)...)
+
+	tests := []struct {
+		name           string
+		field          string
+		content        []byte
+		opts           velocity.UploadOpts
+		expectedStatus int
+	}{
+		{name: "allowed type", field: "avatar", content: png, opts: velocity.UploadOpts{AllowedTypes: []string{"image/png"}}},
+		{name: "wildcard type", field: "avatar", content: png, opts: velocity.UploadOpts{AllowedTypes: []string{"image/*"}}},
+		{name: "sniffed type not allowed", field: "avatar", content: []byte("<html>not an image</html>"), opts: velocity.UploadOpts{AllowedTypes: []string{"image/png"}}, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "too large", field: "avatar", content: png, opts: velocity.UploadOpts{MaxSize: 64}, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "missing field", field: "document", content: png, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			req := multipartRequest(t, tt.field, "me.png", tt.content).WithContext(ctx)
+
+			up, err := velocity.FormFile(req, "avatar", tt.opts)
+			if tt.expectedStatus != 0 {
+				cancel()
+				var he *velocity.HTTPError
+				if !errors.As(err, &he) || he.Code != tt.expectedStatus {
+					t.Fatalf("expected HTTPError with status %d, got %v", tt.expectedStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if up.Filename != "me.png" || up.ContentType != "image/png" || up.Size != int64(len(png)) {
+				t.Errorf("unexpected upload %+v", up)
+			}
+			got, err := os.ReadFile(up.Path)
+			if err != nil || !bytes.Equal(got, png) {
+				t.Errorf("expected temp file with the uploaded content, got %v", err)
+			}
+
+			// The temp file is removed once the request is done
+			cancel()
+			for i := 0; i < 100; i++ {
+				if _, err := os.Stat(up.Path); errors.Is(err, os.ErrNotExist) {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+			t.Error("expected temp file to be removed after the request")
+		})
+	}
+}
+
+func TestFormFileDest(t *testing.T) {
+	var dest bytes.Buffer
+	req := multipartRequest(t, "report", "report.csv", []byte("a,b\n1,2\n"))
+	up, err := velocity.FormFile(req, "report", velocity.UploadOpts{Dest: &dest, AllowedTypes: []string{"text/plain"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if up.Path != "" || dest.String() != "a,b\n1,2\n" || !strings.HasPrefix(up.ContentType, "text/plain") {
+		t.Errorf("unexpected upload %+v with content %q", up, dest.String())
+	}
+}
+
+// failingWriter fails every write with errWrite.
+type failingWriter struct{}
+
+var errWrite = errors.New("disk full")
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestFormFileErrors(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 2048)
+
+	// Cut the body in the middle of the file content
+	full := multipartRequest(t, "report", "report.txt", content)
+	body, _ := io.ReadAll(full.Body)
+	truncated := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body[:bytes.Index(body, content)+1024]))
+	truncated.Header.Set("Content-Type", full.Header.Get("Content-Type"))
+
+	_, err := velocity.FormFile(truncated, "report", velocity.UploadOpts{Dest: &bytes.Buffer{}})
+	var he *velocity.HTTPError
+	if !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+		t.Errorf("expected HTTPError with status %d for a truncated body, got %v", http.StatusBadRequest, err)
+	}
+
+	_, err = velocity.FormFile(multipartRequest(t, "report", "report.txt", content), "report", velocity.UploadOpts{Dest: failingWriter{}})
+	if !errors.Is(err, errWrite) || errors.As(err, &he) {
+		t.Errorf("expected the destination error unchanged, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package velocity
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL builds the path of the route registered under name, substituting params.
+// Param values are escaped, and values for constrained params must satisfy the
+// route's regex. The catch-all value is given under the "*" key and may contain slashes.
+// An optional trailing param is omitted from the URL when it has no value.
+//
+// Example:
+//
+//	router.Get("/users/:id/files/*").Name("user.files").Handle(handler)
+//	url, err := app.URL("user.files", map[string]string{"id": "42", "*": "docs/a.pdf"})
+//	// "/users/42/files/docs/a.pdf"
+func (a *App) URL(name string, params map[string]string) (string, error) {
+	a.mu.Lock()
+	p, ok := a.names[name]
+	a.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("velocity: no route named %q", name)
+	}
+
+	p, format := splitFormat(p)
+	optional := false
+	if !format {
+		p, optional = splitOptional(p)
+	}
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		switch getSegmentType(seg) {
+		case param:
+			key, _, pattern := parseParam(seg)
+			v, ok := params[key]
+			if !ok && optional && i == len(segments)-1 {
+				segments = segments[:i]
+				break
+			}
+			if !ok {
+				return "", fmt.Errorf("velocity: route %q: missing param %q", name, key)
+			}
+			if pattern != "" {
+				if !compileConstraint(pattern).MatchString(v) {
+					return "", fmt.Errorf("velocity: route %q: param %q value %q does not match %q", name, key, v, pattern)
+				}
+			}
+			segments[i] = url.PathEscape(v)
+		case catchAll:
+			v, ok := params["*"]
+			if !ok {
+				return "", fmt.Errorf("velocity: route %q: missing param %q", name, "*")
+			}
+			parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		}
+	}
+
+	u := "/" + strings.Join(segments, "/")
+	if format {
+		if ext := params["format"]; ext != "" {
+			u += "." + url.PathEscape(ext)
+		}
+	}
+	return u, nil
+}
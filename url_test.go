@@ -0,0 +1,86 @@
+package velocity_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+)
+
+func TestURL(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/api")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	router.Get("/users/:id").Name("user.show").Handle(handler)
+	router.Get("/orders/:id<int>([0-9]+)").Name("order.show").Handle(handler)
+	router.Get("/files/*").Name("files").Handle(handler)
+	router.Get("/reports/:id.:format?").Name("report").Handle(handler)
+	router.Get("/posts/:year/:month?").Name("posts").Handle(handler)
+
+	tests := []struct {
+		name        string
+		route       string
+		params      map[string]string
+		expected    string
+		expectError bool
+	}{
+		{"simple param", "user.show", map[string]string{"id": "42"}, "/api/users/42", false},
+		{"escaped param", "user.show", map[string]string{"id": "john doe"}, "/api/users/john%20doe", false},
+		{"typed constrained param", "order.show", map[string]string{"id": "7"}, "/api/orders/7", false},
+		{"constraint mismatch", "order.show", map[string]string{"id": "abc"}, "", true},
+		{"catch all", "files", map[string]string{"*": "docs/a b.pdf"}, "/api/files/docs/a%20b.pdf", false},
+		{"format", "report", map[string]string{"id": "1", "format": "json"}, "/api/reports/1.json", false},
+		{"format omitted", "report", map[string]string{"id": "1"}, "/api/reports/1", false},
+		{"optional param", "posts", map[string]string{"year": "2024", "month": "05"}, "/api/posts/2024/05", false},
+		{"optional param omitted", "posts", map[string]string{"year": "2024"}, "/api/posts/2024", false},
+		{"missing param", "user.show", map[string]string{}, "", true},
+		{"unknown route", "nope", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := app.URL(tt.route, tt.params)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestURLNameLifecycle(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	// Invalid routes are not registered, so neither is their name
+	if err := router.Get("/users/:").Name("broken").Handle(handler); err == nil {
+		t.Fatal("expected an error for an invalid path")
+	}
+	if _, err := app.URL("broken", nil); err == nil {
+		t.Error("expected no URL for a route that failed to register")
+	}
+
+	router.Match([]string{http.MethodGet, http.MethodPost}, "/users/:id").Name("user").Handle(handler)
+	if err := app.RemoveRoute(http.MethodGet, "/users/:id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := app.URL("user", map[string]string{"id": "1"}); err != nil {
+		t.Errorf("expected the name to remain while POST is registered, got %v", err)
+	}
+	if err := app.RemoveRoute(http.MethodPost, "/users/:id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := app.URL("user", map[string]string{"id": "1"}); err == nil {
+		t.Error("expected no URL for a removed route")
+	}
+}
@@ -0,0 +1,780 @@
+package velocity
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types, as passed to WSConn.WriteMessage and returned by
+// WSConn.ReadMessage (RFC 6455, section 5.2).
+const (
+	WSText   = 1
+	WSBinary = 2
+	WSPing   = 9
+	WSPong   = 10
+)
+
+// WebSocket close codes (RFC 6455, section 7.4.1).
+const (
+	WSCloseNormal          = 1000
+	WSCloseGoingAway       = 1001
+	WSCloseProtocolError   = 1002
+	WSCloseUnsupportedData = 1003
+	WSCloseNoStatus        = 1005
+	WSCloseAbnormal        = 1006
+	WSCloseInvalidPayload  = 1007
+	WSClosePolicyViolation = 1008
+	WSCloseMessageTooBig   = 1009
+	WSCloseInternalError   = 1011
+)
+
+const (
+	wsContinuation = 0
+	wsClose        = 8
+	wsRSV1         = 0x40
+	wsGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsDeflate      = "permessage-deflate"
+
+	defaultMaxMessageSize = 1 << 20
+)
+
+// WSConfig configures the WebSocket handshake and the upgraded connection.
+type WSConfig struct {
+	// CheckOrigin reports whether the request's Origin is allowed; other requests are
+	// answered with 403 Forbidden. Browsers do not apply CORS to WebSockets, so without
+	// it any page could connect with the user's cookies.
+	// Default: nil (allow requests without an Origin header or whose Origin host
+	// matches the request Host)
+	CheckOrigin func(r *http.Request) bool
+
+	// Subprotocols are the application protocols the server supports, in order of
+	// preference. The first one the client also offers is selected and returned by
+	// WSConn.Subprotocol.
+	// Default: nil (no subprotocol)
+	Subprotocols []string
+
+	// MaxMessageSize is the maximum size in bytes of a message read. Compressed
+	// messages are limited both before and after decompression. Larger messages close
+	// the connection with WSCloseMessageTooBig. A negative value disables the limit.
+	// Default: 1 MB
+	MaxMessageSize int64
+
+	// ReadDeadline is how long ReadMessage waits for each frame before the connection
+	// is closed, so pings or pongs from the peer keep an idle connection alive.
+	// Default: 0 (no timeout)
+	ReadDeadline time.Duration
+
+	// EnableCompression negotiates the permessage-deflate extension (RFC 7692) when the
+	// client offers it, compressing text and binary messages.
+	// Default: false
+	EnableCompression bool
+
+	// PingInterval is how often a ping is sent to keep the connection alive through
+	// proxies that close idle connections. Pongs are only processed while
+	// ReadMessage is being called.
+	// Default: 0 (no pings)
+	PingInterval time.Duration
+
+	// PongTimeout is how long to wait for the peer to answer a ping, or send any
+	// other frame, before the connection is considered dead and closed with
+	// WSCloseAbnormal.
+	// Default: 0 (PingInterval)
+	PongTimeout time.Duration
+
+	// OnClose is called once when the connection is closed, by the peer, a protocol
+	// error, a failed keepalive or the server, with the close status. It runs on the
+	// goroutine that closed the connection.
+	// Default: nil
+	OnClose func(conn *WSConn, err *WSCloseError)
+}
+
+var defaultWSConfig = WSConfig{
+	MaxMessageSize: defaultMaxMessageSize,
+}
+
+// ErrWSClosed is returned when writing to a WSConn after a close frame was sent.
+var ErrWSClosed = errors.New("velocity: websocket closed")
+
+// WSCloseError is returned by WSConn.ReadMessage once the connection is closed, by
+// the peer with a close frame or by the server after a protocol violation. Code is
+// WSCloseAbnormal if the connection dropped without a close frame.
+type WSCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *WSCloseError) Error() string {
+	if e.Text == "" {
+		return "websocket: close " + strconv.Itoa(e.Code)
+	}
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// WSConn is a WebSocket connection upgraded by Upgrade or a HandleWS route.
+// ReadMessage must be called from a single goroutine, while the write methods and
+// Close may be called concurrently. Pings are answered automatically while reading,
+// and sent periodically when WSConfig.PingInterval is set.
+type WSConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+	req  *http.Request
+
+	subprotocol    string
+	compress       bool
+	maxMessageSize int64
+	readTimeout    time.Duration
+
+	readErr  error
+	lastRead atomic.Int64 // Unix nanoseconds, tracked when keepalive is enabled
+	pinging  bool
+
+	wmu       sync.Mutex
+	closeSent bool
+
+	onClose    func(conn *WSConn, err *WSCloseError)
+	closed     chan struct{}
+	closeErr   atomic.Pointer[WSCloseError]
+	finishOnce sync.Once
+}
+
+// Upgrade performs the WebSocket opening handshake (RFC 6455) and takes over the
+// connection. Headers already set on w, e.g. by middleware, are sent with the 101
+// response. If the request is not a valid upgrade or its origin is not allowed,
+// Upgrade answers with an error status and returns an error. Prefer route.HandleWS
+// for routes.
+//
+// Example:
+//
+//	router.Get("/ws").Handle(func(w http.ResponseWriter, r *http.Request) {
+//	    conn, err := velocity.Upgrade(w, r, velocity.WSConfig{Subprotocols: []string{"graphql-ws"}})
+//	    if err != nil {
+//	        return
+//	    }
+//	    defer conn.Close()
+//	})
+func Upgrade(w http.ResponseWriter, r *http.Request, cfg ...WSConfig) (*WSConn, error) {
+	config := defaultWSConfig
+	if len(cfg) > 0 {
+		config.CheckOrigin = cfg[0].CheckOrigin
+		config.Subprotocols = cfg[0].Subprotocols
+		if cfg[0].MaxMessageSize != 0 {
+			config.MaxMessageSize = cfg[0].MaxMessageSize
+		}
+		config.ReadDeadline = cfg[0].ReadDeadline
+		config.EnableCompression = cfg[0].EnableCompression
+		config.PingInterval = cfg[0].PingInterval
+		config.PongTimeout = cfg[0].PongTimeout
+		config.OnClose = cfg[0].OnClose
+	}
+
+	if r.Method != http.MethodGet {
+		return nil, wsHandshakeError(w, http.StatusMethodNotAllowed, "websocket: upgrade requires GET")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, wsHandshakeError(w, http.StatusBadRequest, "websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, wsHandshakeError(w, http.StatusUpgradeRequired, "websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, wsHandshakeError(w, http.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := config.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, wsHandshakeError(w, http.StatusForbidden, "websocket: origin not allowed")
+	}
+	ws := &WSConn{
+		req:            r,
+		subprotocol:    selectSubprotocol(r.Header, config.Subprotocols),
+		compress:       config.EnableCompression && deflateOffered(r.Header),
+		maxMessageSize: config.MaxMessageSize,
+		readTimeout:    config.ReadDeadline,
+		pinging:        config.PingInterval > 0,
+		onClose:        config.OnClose,
+		closed:         make(chan struct{}),
+	}
+
+	header := w.Header().Clone()
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, wsHandshakeError(w, http.StatusInternalServerError, "websocket: "+err.Error())
+	}
+	// Deadlines set by the server for the request no longer apply
+	conn.SetDeadline(time.Time{})
+
+	bw := brw.Writer
+	bw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	bw.WriteString(wsAccept(key))
+	bw.WriteString("\r\n")
+	if ws.subprotocol != "" {
+		bw.WriteString("Sec-WebSocket-Protocol: " + ws.subprotocol + "\r\n")
+	}
+	if ws.compress {
+		// Without context takeover every message is compressed independently, so no
+		// compression state is kept per connection
+		bw.WriteString("Sec-WebSocket-Extensions: " + wsDeflate + "; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for _, h := range []string{"Upgrade", "Connection", "Sec-WebSocket-Accept", "Sec-WebSocket-Protocol", "Sec-WebSocket-Extensions", "Content-Length", "Content-Type"} {
+		header.Del(h)
+	}
+	header.Write(bw)
+	bw.WriteString("\r\n")
+	if err := bw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws.conn, ws.br, ws.bw = conn, brw.Reader, bw
+	if ws.pinging {
+		timeout := config.PongTimeout
+		if timeout <= 0 {
+			timeout = config.PingInterval
+		}
+		ws.lastRead.Store(time.Now().UnixNano())
+		go ws.keepalive(config.PingInterval, timeout)
+	}
+	return ws, nil
+}
+
+// keepalive pings the peer every interval until the connection is closed, closing
+// it if no frame arrives within timeout of a ping.
+func (c *WSConn) keepalive(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+		sent := time.Now().UnixNano()
+		// Checked from a timer, since the ping itself may block on a dead connection
+		// until finish closes it
+		check := time.AfterFunc(timeout, func() {
+			if c.lastRead.Load() < sent {
+				c.finish(&WSCloseError{Code: WSCloseAbnormal, Text: "pong timeout"})
+			}
+		})
+		if err := c.writeFrame(WSPing, nil); err != nil {
+			check.Stop()
+			c.finish(&WSCloseError{Code: WSCloseAbnormal, Text: err.Error()})
+			return
+		}
+	}
+}
+
+func wsHandshakeError(w http.ResponseWriter, status int, msg string) error {
+	http.Error(w, http.StatusText(status), status)
+	return errors.New(msg)
+}
+
+// wsAccept returns the Sec-WebSocket-Accept value for key.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// sameOrigin reports whether the request has no Origin header or its host matches
+// the request Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// selectSubprotocol returns the first of supported offered by the client, or "".
+func selectSubprotocol(h http.Header, supported []string) string {
+	for _, p := range supported {
+		if headerHasToken(h, "Sec-WebSocket-Protocol", p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// deflateOffered reports whether the client offers permessage-deflate with
+// parameters the server can accept. The compressor always uses a 32 KB window, so
+// offers limiting server_max_window_bits are declined.
+func deflateOffered(h http.Header) bool {
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+	offers:
+		for _, offer := range strings.Split(v, ",") {
+			params := strings.Split(offer, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), wsDeflate) {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					if strings.Trim(strings.TrimSpace(value), `"`) != "15" {
+						continue offers
+					}
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// IsWebSocketUpgrade reports whether r is a GET request asking for a WebSocket
+// upgrade, e.g. for middleware that treats upgrades differently from other requests.
+//
+// Example:
+//
+//	middleware.Timeout(5*time.Second, middleware.TimeoutConfig{Skipper: velocity.IsWebSocketUpgrade})
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		headerHasToken(r.Header, "Connection", "upgrade") &&
+		headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerHasToken reports whether the comma-separated header contains token,
+// compared case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HandleWS registers a WebSocket handler for the route. Requests are upgraded after
+// the route's middleware ran, so authentication middleware can reject them before
+// the handshake; failed handshakes are answered with an error status. The
+// connection is closed when h returns. An optional WSConfig is applied to every
+// upgrade.
+//
+// Example:
+//
+//	router.Websocket("/echo").HandleWS(func(conn *velocity.WSConn) {
+//	    for {
+//	        typ, msg, err := conn.ReadMessage()
+//	        if err != nil {
+//	            return
+//	        }
+//	        if err := conn.WriteMessage(typ, msg); err != nil {
+//	            return
+//	        }
+//	    }
+//	}, velocity.WSConfig{
+//	    CheckOrigin: func(r *http.Request) bool {
+//	        return r.Header.Get("Origin") == "https://example.com"
+//	    },
+//	    EnableCompression: true,
+//	})
+func (r route) HandleWS(h func(conn *WSConn), cfg ...WSConfig) error {
+	return r.Handle(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := Upgrade(w, req, cfg...)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		h(conn)
+	})
+}
+
+// Request returns the request the connection was upgraded from.
+func (c *WSConn) Request() *http.Request {
+	return c.req
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake, or "" if
+// none was selected.
+func (c *WSConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying network connection, e.g. for its addresses.
+func (c *WSConn) NetConn() net.Conn {
+	return c.conn
+}
+
+// SetReadDeadline sets the deadline for reading the next message. A zero value
+// means no deadline. It is overridden before each frame when WSConfig.ReadDeadline
+// is set.
+func (c *WSConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writes. A zero value means no deadline.
+func (c *WSConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+type wsFrame struct {
+	fin    bool
+	rsv    byte
+	opcode byte
+	length uint64
+	mask   [4]byte
+}
+
+// ReadMessage returns the next text or binary message, reassembling fragmented
+// messages. Ping frames are answered and pong frames skipped while reading. Once
+// the connection is closed it returns a *WSCloseError, and the same error on later
+// calls.
+func (c *WSConn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	var compressed bool
+	for {
+		if c.readTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.readFailed(err)
+		}
+		rsv := f.rsv
+		// RSV1 marks the first frame of a compressed message (RFC 7692, section 6)
+		if rsv == wsRSV1 && c.compress && (f.opcode == WSText || f.opcode == WSBinary) {
+			compressed, rsv = true, 0
+		}
+		if rsv != 0 {
+			return 0, nil, c.fail(WSCloseProtocolError, "reserved bits set")
+		}
+		control := f.opcode >= wsClose
+		if control && (!f.fin || f.length > 125) {
+			return 0, nil, c.fail(WSCloseProtocolError, "invalid control frame")
+		}
+		if !control && c.maxMessageSize > 0 && uint64(len(p))+f.length > uint64(c.maxMessageSize) {
+			return 0, nil, c.fail(WSCloseMessageTooBig, "message too big")
+		}
+		payload, err := c.readPayload(f)
+		if err != nil {
+			return 0, nil, c.readFailed(err)
+		}
+
+		switch f.opcode {
+		case WSPing:
+			if err := c.writeFrame(WSPong, payload); err != nil && !errors.Is(err, ErrWSClosed) {
+				return 0, nil, c.readFailed(err)
+			}
+			continue
+		case WSPong:
+			continue
+		case wsClose:
+			return 0, nil, c.closeReceived(payload)
+		case WSText, WSBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(WSCloseProtocolError, "unexpected data frame")
+			}
+			messageType, p = int(f.opcode), payload
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(WSCloseProtocolError, "unexpected continuation frame")
+			}
+			p = append(p, payload...)
+		default:
+			return 0, nil, c.fail(WSCloseProtocolError, "unknown opcode")
+		}
+
+		if f.fin {
+			if compressed {
+				if p, err = c.inflate(p); err != nil {
+					return 0, nil, c.readFailed(err)
+				}
+			}
+			if messageType == WSText && !utf8.Valid(p) {
+				return 0, nil, c.fail(WSCloseInvalidPayload, "invalid UTF-8")
+			}
+			return messageType, p, nil
+		}
+	}
+}
+
+func (c *WSConn) readFrame() (wsFrame, error) {
+	var f wsFrame
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.br, hdr[:2]); err != nil {
+		return f, err
+	}
+	if c.pinging {
+		c.lastRead.Store(time.Now().UnixNano())
+	}
+	f.fin = hdr[0]&0x80 != 0
+	f.rsv = hdr[0] & 0x70
+	f.opcode = hdr[0] & 0x0f
+	masked := hdr[1]&0x80 != 0
+	f.length = uint64(hdr[1] & 0x7f)
+	switch f.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, hdr[:2]); err != nil {
+			return f, err
+		}
+		f.length = uint64(binary.BigEndian.Uint16(hdr[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, hdr[:8]); err != nil {
+			return f, err
+		}
+		f.length = binary.BigEndian.Uint64(hdr[:8])
+		if f.length > math.MaxInt64 {
+			return f, &WSCloseError{Code: WSCloseProtocolError, Text: "invalid frame length"}
+		}
+	}
+	// Clients must mask every frame (RFC 6455, section 5.1)
+	if !masked {
+		return f, &WSCloseError{Code: WSCloseProtocolError, Text: "unmasked frame"}
+	}
+	if _, err := io.ReadFull(c.br, f.mask[:]); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func (c *WSConn) readPayload(f wsFrame) ([]byte, error) {
+	// The buffer grows as data arrives, so a forged length cannot exhaust memory
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(c.br, int64(f.length)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) != f.length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	p := buf.Bytes()
+	for i := range p {
+		p[i] ^= f.mask[i&3]
+	}
+	return p, nil
+}
+
+var (
+	flateReaderPool sync.Pool
+	flateWriterPool sync.Pool
+)
+
+// deflateTail ends the compressed data of a message: the sync marker stripped by
+// the sender, followed by a final empty block so the reader sees a clean EOF.
+const deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+// inflate decompresses a permessage-deflate message, enforcing the size limit.
+func (c *WSConn) inflate(p []byte) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(p), strings.NewReader(deflateTail))
+	fr, _ := flateReaderPool.Get().(io.ReadCloser)
+	if fr == nil {
+		fr = flate.NewReader(src)
+	} else {
+		fr.(flate.Resetter).Reset(src, nil)
+	}
+	defer flateReaderPool.Put(fr)
+
+	var r io.Reader = fr
+	if c.maxMessageSize > 0 {
+		r = io.LimitReader(fr, c.maxMessageSize+1)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, &WSCloseError{Code: WSCloseInvalidPayload, Text: "invalid compressed data"}
+	}
+	if c.maxMessageSize > 0 && int64(buf.Len()) > c.maxMessageSize {
+		return nil, &WSCloseError{Code: WSCloseMessageTooBig, Text: "message too big"}
+	}
+	return buf.Bytes(), nil
+}
+
+// deflate compresses a message for permessage-deflate.
+func deflate(p []byte) []byte {
+	var buf bytes.Buffer
+	fw, _ := flateWriterPool.Get().(*flate.Writer)
+	if fw == nil {
+		fw, _ = flate.NewWriter(&buf, flate.BestSpeed)
+	} else {
+		fw.Reset(&buf)
+	}
+	defer flateWriterPool.Put(fw)
+	fw.Write(p)
+	fw.Flush()
+	// The sync marker ending the flushed data is implied (RFC 7692, section 7.2.1)
+	return bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff})
+}
+
+// readFailed records err as the terminal read error, failing the connection with
+// the close code of a *WSCloseError or treating other errors as an abnormal closure.
+func (c *WSConn) readFailed(err error) error {
+	// Reads fail once another goroutine closed the connection; report its status
+	if closed := c.closeErr.Load(); closed != nil {
+		c.readErr = closed
+		return closed
+	}
+	var ce *WSCloseError
+	if errors.As(err, &ce) {
+		return c.fail(ce.Code, ce.Text)
+	}
+	ce = &WSCloseError{Code: WSCloseAbnormal, Text: err.Error()}
+	c.readErr = ce
+	c.finish(ce)
+	return ce
+}
+
+// fail sends a close frame with code and closes the connection.
+func (c *WSConn) fail(code int, text string) error {
+	ce := &WSCloseError{Code: code, Text: text}
+	c.readErr = ce
+	c.writeClose(code, text)
+	c.finish(ce)
+	return ce
+}
+
+// closeReceived answers a close frame from the peer, echoing its status code.
+func (c *WSConn) closeReceived(payload []byte) error {
+	code, text := WSCloseNoStatus, ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(WSCloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) || !utf8.ValidString(text) {
+			return c.fail(WSCloseProtocolError, "invalid close frame")
+		}
+	}
+	ce := &WSCloseError{Code: code, Text: text}
+	c.readErr = ce
+	if code == WSCloseNoStatus {
+		c.writeFrame(wsClose, nil)
+	} else {
+		c.writeClose(code, "")
+	}
+	c.finish(ce)
+	return ce
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1011:
+		return false
+	}
+	return code != 1004 && code != WSCloseNoStatus && code != WSCloseAbnormal
+}
+
+// WriteMessage sends data as a single frame of messageType: WSText, WSBinary,
+// WSPing or WSPong. Text messages must be valid UTF-8, and control messages carry
+// at most 125 bytes. Text and binary messages are compressed when permessage-deflate
+// was negotiated.
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case WSText, WSBinary:
+	case WSPing, WSPong:
+		if len(data) > 125 {
+			return errors.New("velocity: websocket control message exceeds 125 bytes")
+		}
+	default:
+		return fmt.Errorf("velocity: invalid websocket message type %d", messageType)
+	}
+	if c.compress && messageType <= WSBinary {
+		return c.writeFrame(byte(messageType)|wsRSV1, deflate(data))
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+func (c *WSConn) writeClose(code int, text string) error {
+	p := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(p, uint16(code))
+	return c.writeFrame(wsClose, append(p, text...))
+}
+
+// writeFrame sends p as a final frame. opcode may carry reserved bits.
+func (c *WSConn) writeFrame(opcode byte, p []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrWSClosed
+	}
+	if opcode == wsClose {
+		c.closeSent = true
+	}
+	var hdr [10]byte
+	hdr[0] = 0x80 | opcode
+	n := 2
+	switch {
+	case len(p) <= 125:
+		hdr[1] = byte(len(p))
+	case len(p) <= 0xffff:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(len(p)))
+		n = 4
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(len(p)))
+		n = 10
+	}
+	c.bw.Write(hdr[:n])
+	c.bw.Write(p)
+	return c.bw.Flush()
+}
+
+// CloseWithStatus sends a close frame with code and reason, then closes the
+// connection. Reasons longer than 123 bytes are truncated.
+func (c *WSConn) CloseWithStatus(code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	if c.closeErr.Load() != nil {
+		return nil
+	}
+	werr := c.writeClose(code, reason)
+	err := c.finish(&WSCloseError{Code: code, Text: reason})
+	if werr != nil && !errors.Is(werr, ErrWSClosed) {
+		return werr
+	}
+	return err
+}
+
+// finish closes the network connection and reports err to OnClose. Only the first
+// call has an effect.
+func (c *WSConn) finish(err *WSCloseError) error {
+	var cerr error
+	c.finishOnce.Do(func() {
+		c.closeErr.Store(err)
+		close(c.closed)
+		cerr = c.conn.Close()
+		if c.onClose != nil {
+			c.onClose(c, err)
+		}
+	})
+	return cerr
+}
+
+// Close sends a normal closure frame, unless a close frame was already sent, and
+// closes the connection.
+func (c *WSConn) Close() error {
+	return c.CloseWithStatus(WSCloseNormal, "")
+}
@@ -0,0 +1,457 @@
+package velocity_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+// wsClient is a minimal WebSocket client for exercising the server side.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, url string, header http.Header) (*wsClient, *http.Response) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	conn, err := net.Dial("tcp", req.URL.Host)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	return &wsClient{conn: conn, br: br}, res
+}
+
+func (c *wsClient) writeFrame(fin bool, opcode byte, payload []byte) {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	frame := []byte{b}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, v := range payload {
+		frame = append(frame, v^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *wsClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, hdr); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if hdr[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(c.br, ext)
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(c.br, ext)
+		n = binary.BigEndian.Uint64(ext)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return hdr[0] & 0x0f, payload
+}
+
+func closePayload(code uint16, text string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, code), text...)
+}
+
+func echoServer(t *testing.T, closed chan<- error) string {
+	t.Helper()
+	app := velocity.New()
+	router := app.Router("/", func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("token") == "bad" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Request-ID", "abc")
+			next(w, r)
+		}
+	})
+	router.Websocket("/echo").HandleWS(func(conn *velocity.WSConn) {
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				if closed != nil {
+					closed <- err
+				}
+				return
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/echo"
+}
+
+func TestWebsocketHandshake(t *testing.T) {
+	url := echoServer(t, nil)
+
+	c, res := dialWS(t, url, nil)
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, res.StatusCode)
+	}
+	// RFC 6455, section 1.3
+	if got := res.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected Sec-WebSocket-Accept %q", got)
+	}
+	if got := res.Header.Get("X-Request-ID"); got != "abc" {
+		t.Errorf("expected middleware headers in the handshake, got %q", got)
+	}
+	c.writeFrame(true, 1, []byte("hello"))
+	if op, msg := c.readFrame(t); op != 1 || string(msg) != "hello" {
+		t.Errorf("expected text echo, got %d %q", op, msg)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{"rejected by middleware", "?token=bad", nil, http.StatusUnauthorized},
+		{"unsupported version", "", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{"invalid key", "", http.Header{"Sec-Websocket-Key": {"short"}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, res := dialWS(t, url+tt.path, tt.header)
+			if res.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestWebsocketMessages(t *testing.T) {
+	closed := make(chan error, 1)
+	c, _ := dialWS(t, echoServer(t, closed), nil)
+
+	// Fragmented binary message with an interleaved ping
+	c.writeFrame(false, 2, []byte{1, 2})
+	c.writeFrame(true, 9, []byte("ping"))
+	c.writeFrame(true, 0, []byte{3})
+	if op, msg := c.readFrame(t); op != 10 || string(msg) != "ping" {
+		t.Errorf("expected pong, got %d %q", op, msg)
+	}
+	if op, msg := c.readFrame(t); op != 2 || !bytes.Equal(msg, []byte{1, 2, 3}) {
+		t.Errorf("expected reassembled binary echo, got %d %v", op, msg)
+	}
+
+	large := bytes.Repeat([]byte("x"), 70000)
+	c.writeFrame(true, 1, large)
+	if op, msg := c.readFrame(t); op != 1 || len(msg) != len(large) {
+		t.Errorf("expected large echo, got %d with %d bytes", op, len(msg))
+	}
+
+	c.writeFrame(true, 8, closePayload(1001, "bye"))
+	if op, msg := c.readFrame(t); op != 8 || binary.BigEndian.Uint16(msg) != 1001 {
+		t.Errorf("expected close echo, got %d %v", op, msg)
+	}
+	var ce *velocity.WSCloseError
+	if err := <-closed; !errors.As(err, &ce) || ce.Code != 1001 || ce.Text != "bye" {
+		t.Errorf("expected close error 1001, got %v", err)
+	}
+}
+
+func TestWebsocketProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		send         func(c *wsClient)
+		expectedCode int
+	}{
+		{"invalid UTF-8", func(c *wsClient) { c.writeFrame(true, 1, []byte{0xff, 0xfe}) }, velocity.WSCloseInvalidPayload},
+		{"orphan continuation", func(c *wsClient) { c.writeFrame(true, 0, []byte("x")) }, velocity.WSCloseProtocolError},
+		{"fragmented ping", func(c *wsClient) { c.writeFrame(false, 9, nil) }, velocity.WSCloseProtocolError},
+		{"unknown opcode", func(c *wsClient) { c.writeFrame(true, 3, nil) }, velocity.WSCloseProtocolError},
+		{"unmasked frame", func(c *wsClient) { c.conn.Write([]byte{0x81, 0x01, 'x'}) }, velocity.WSCloseProtocolError},
+		{"invalid close code", func(c *wsClient) { c.writeFrame(true, 8, closePayload(1005, "")) }, velocity.WSCloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan error, 1)
+			c, _ := dialWS(t, echoServer(t, closed), nil)
+			tt.send(c)
+
+			op, msg := c.readFrame(t)
+			if op != 8 || len(msg) < 2 || int(binary.BigEndian.Uint16(msg)) != tt.expectedCode {
+				t.Errorf("expected close %d, got %d %v", tt.expectedCode, op, msg)
+			}
+			var ce *velocity.WSCloseError
+			if err := <-closed; !errors.As(err, &ce) || ce.Code != tt.expectedCode {
+				t.Errorf("expected close error %d, got %v", tt.expectedCode, err)
+			}
+		})
+	}
+}
+
+func TestWebsocketConfig(t *testing.T) {
+	app := velocity.New()
+	router := app.Router("/")
+	closed := make(chan error, 1)
+	router.Websocket("/ws").HandleWS(func(conn *velocity.WSConn) {
+		conn.WriteMessage(velocity.WSText, []byte(conn.Subprotocol()))
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			conn.WriteMessage(typ, msg)
+		}
+	}, velocity.WSConfig{
+		CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") != "https://evil.example"
+		},
+		Subprotocols:      []string{"v2.chat", "v1.chat"},
+		MaxMessageSize:    16,
+		ReadDeadline:      200 * time.Millisecond,
+		EnableCompression: true,
+	})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+	url := srv.URL + "/ws"
+
+	t.Run("origin", func(t *testing.T) {
+		_, res := dialWS(t, url, http.Header{"Origin": {"https://evil.example"}})
+		if res.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, res.StatusCode)
+		}
+	})
+
+	t.Run("subprotocol and size limit", func(t *testing.T) {
+		c, res := dialWS(t, url, http.Header{"Sec-Websocket-Protocol": {"v1.chat, v2.chat"}})
+		if got := res.Header.Get("Sec-WebSocket-Protocol"); got != "v2.chat" {
+			t.Errorf("expected subprotocol v2.chat, got %q", got)
+		}
+		if res.Header.Get("Sec-WebSocket-Extensions") != "" {
+			t.Error("expected no extensions when compression was not offered")
+		}
+		if _, msg := c.readFrame(t); string(msg) != "v2.chat" {
+			t.Errorf("expected handler to see v2.chat, got %q", msg)
+		}
+		c.writeFrame(true, 1, bytes.Repeat([]byte("x"), 17))
+		if op, msg := c.readFrame(t); op != 8 || binary.BigEndian.Uint16(msg) != velocity.WSCloseMessageTooBig {
+			t.Errorf("expected close %d, got %d %v", velocity.WSCloseMessageTooBig, op, msg)
+		}
+		<-closed
+	})
+
+	t.Run("compression", func(t *testing.T) {
+		c, res := dialWS(t, url, http.Header{"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"}})
+		if ext := res.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+			t.Fatalf("expected permessage-deflate, got %q", ext)
+		}
+		if op, msg := c.readFrame(t); op != 1 || len(msg) == 0 {
+			t.Fatalf("expected subprotocol message, got %d %v", op, msg)
+		}
+
+		var buf bytes.Buffer
+		fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+		fw.Write([]byte("hello hello"))
+		fw.Flush()
+		c.writeFrame(true, 1|0x40, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+		op, msg := c.readFrame(t)
+		if op != 1 {
+			t.Fatalf("expected text frame, got %d", op)
+		}
+		fr := flate.NewReader(io.MultiReader(bytes.NewReader(msg), strings.NewReader("\x00\x00\xff\xff\x01\x00\x00\xff\xff")))
+		got, err := io.ReadAll(fr)
+		if err != nil || string(got) != "hello hello" {
+			t.Errorf("expected compressed echo, got %q (%v)", got, err)
+		}
+
+		// Decompressed size is limited too
+		buf.Reset()
+		fw.Reset(&buf)
+		fw.Write(bytes.Repeat([]byte("x"), 100))
+		fw.Flush()
+		c.writeFrame(true, 1|0x40, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}))
+		if op, msg := c.readFrame(t); op != 8 || binary.BigEndian.Uint16(msg) != velocity.WSCloseMessageTooBig {
+			t.Errorf("expected close %d, got %d %v", velocity.WSCloseMessageTooBig, op, msg)
+		}
+		<-closed
+	})
+
+	t.Run("read deadline", func(t *testing.T) {
+		c, _ := dialWS(t, url, nil)
+		c.readFrame(t)
+		select {
+		case err := <-closed:
+			var ce *velocity.WSCloseError
+			if !errors.As(err, &ce) || ce.Code != velocity.WSCloseAbnormal {
+				t.Errorf("expected abnormal closure, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the idle connection to be closed")
+		}
+	})
+}
+
+func TestWebsocketKeepalive(t *testing.T) {
+	closed := make(chan *velocity.WSCloseError, 1)
+	app := velocity.New()
+	app.Router("/").Websocket("/ws").HandleWS(func(conn *velocity.WSConn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}, velocity.WSConfig{
+		PingInterval: 50 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+		OnClose: func(conn *velocity.WSConn, err *velocity.WSCloseError) {
+			closed <- err
+		},
+	})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	t.Run("responsive peer", func(t *testing.T) {
+		c, _ := dialWS(t, srv.URL+"/ws", nil)
+		for range 5 {
+			if op, _ := c.readFrame(t); op != velocity.WSPing {
+				t.Fatalf("expected ping, got %d", op)
+			}
+			c.writeFrame(true, velocity.WSPong, nil)
+		}
+		c.writeFrame(true, 8, closePayload(velocity.WSCloseNormal, ""))
+		if err := <-closed; err.Code != velocity.WSCloseNormal {
+			t.Errorf("expected normal closure, got %v", err)
+		}
+	})
+
+	t.Run("dead peer", func(t *testing.T) {
+		c, _ := dialWS(t, srv.URL+"/ws", nil)
+		if op, _ := c.readFrame(t); op != velocity.WSPing {
+			t.Fatalf("expected ping, got %d", op)
+		}
+		select {
+		case err := <-closed:
+			if err.Code != velocity.WSCloseAbnormal || err.Text != "pong timeout" {
+				t.Errorf("expected pong timeout, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the connection to be closed")
+		}
+	})
+}
+
+func TestWebsocketRouting(t *testing.T) {
+	app := velocity.New()
+	var seen []string
+	logMethod := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			seen = append(seen, r.Method)
+			next(w, r)
+		}
+	}
+	router := app.Router("/", logMethod)
+	router.Websocket("/live").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("websocket"))
+	})
+	router.Get("/live").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	})
+	router.Get("/page").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	})
+
+	upgrade := http.Header{"Connection": {"keep-alive, Upgrade"}, "Upgrade": {"websocket"}}
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		header          http.Header
+		expectedStatus  int
+		expectedBody    string
+		expectedUpgrade bool
+	}{
+		{"upgrade", http.MethodGet, "/live", upgrade, http.StatusOK, "websocket", true},
+		{"plain GET", http.MethodGet, "/live", nil, http.StatusOK, "page", false},
+		{"upgrade falls back to GET", http.MethodGet, "/page", upgrade, http.StatusOK, "page", true},
+		{"upgrade needs GET", http.MethodPost, "/live", upgrade, http.StatusMethodNotAllowed, "", false},
+		{"WS is not a request method", "WS", "/live", nil, http.StatusMethodNotAllowed, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			if got := velocity.IsWebSocketUpgrade(req); got != tt.expectedUpgrade {
+				t.Errorf("expected IsWebSocketUpgrade %v, got %v", tt.expectedUpgrade, got)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && (len(seen) != 1 || seen[0] != tt.method) {
+				t.Errorf("expected middleware to see %s, got %v", tt.method, seen)
+			}
+		})
+	}
+}
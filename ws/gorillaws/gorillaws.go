@@ -0,0 +1,60 @@
+/*
+Package gorillaws adapts github.com/gorilla/websocket to velocity WebSocket routes,
+so existing code built on *websocket.Conn can run behind velocity routing and
+middleware. The route's middleware runs before the upgrade, so it can reject the
+request, and headers it sets are sent with the handshake response.
+
+Usage:
+
+	upgrader := &websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+	router.Websocket("/chat", authMiddleware).Handle(gorillaws.Handler(upgrader, func(conn *websocket.Conn, r *http.Request) {
+	    for {
+	        typ, msg, err := conn.ReadMessage()
+	        if err != nil {
+	            return
+	        }
+	        conn.WriteMessage(typ, msg)
+	    }
+	}))
+*/
+package gorillaws
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Handler returns a handler that upgrades the request with u, or a zero Upgrader
+// if u is nil, and passes the connection to h. Failed upgrades are answered by the
+// Upgrader. The connection is closed when h returns.
+func Handler(u *websocket.Upgrader, h func(conn *websocket.Conn, r *http.Request)) http.HandlerFunc {
+	if u == nil {
+		u = &websocket.Upgrader{}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The Upgrader writes the handshake itself and only sends the headers passed
+		// to it; it rejects a Sec-WebSocket-Extensions header
+		header := w.Header().Clone()
+		header.Del("Sec-Websocket-Extensions")
+		conn, err := u.Upgrade(hijacker{w}, r, header)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		h(conn, r)
+	}
+}
+
+// hijacker lets the Upgrader hijack connections through response writers wrapped
+// by middleware, which it only finds with a type assertion.
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
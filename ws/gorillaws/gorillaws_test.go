@@ -0,0 +1,68 @@
+package gorillaws_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/ws/gorillaws"
+	"github.com/gorilla/websocket"
+)
+
+// wrapped hides the writer's Hijack method, like many middleware writers do.
+type wrapped struct {
+	http.ResponseWriter
+}
+
+func (w wrapped) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Request-ID", "abc")
+		next(wrapped{w}, r)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Websocket("/echo", auth).Handle(gorillaws.Handler(nil, func(conn *websocket.Conn, r *http.Request) {
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(typ, msg)
+		}
+	}))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/echo"
+
+	_, res, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || res == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected middleware to reject the upgrade, got %v", err)
+	}
+
+	conn, res, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if got := res.Header.Get("X-Request-ID"); got != "abc" {
+		t.Errorf("expected middleware headers in the handshake, got %q", got)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typ, msg, err := conn.ReadMessage(); err != nil || typ != websocket.TextMessage || string(msg) != "hello" {
+		t.Errorf("expected echo, got %d %q %v", typ, msg, err)
+	}
+}
@@ -0,0 +1,407 @@
+/*
+Package ws provides a Hub for fanning messages out to many velocity WebSocket
+connections. Clients are registered with the hub, can join named rooms, and
+receive messages through a bounded per-client send queue written by its own
+goroutine, so a slow client never blocks broadcasts to the others.
+
+Usage:
+
+	hub := ws.NewHub()
+	app.OnShutdown(hub.Close)
+
+	router.Websocket("/chat/:room").HandleWS(func(conn *velocity.WSConn) {
+	    room := velocity.Param(conn.Request(), "room")
+	    hub.Serve(conn, userID(conn.Request()), func(c *ws.Client, messageType int, data []byte) {
+	        hub.BroadcastRoom(room, messageType, data, c)
+	    }, room)
+	})
+
+	// Elsewhere, e.g. from a background job
+	hub.SendTo("user-42", velocity.WSText, []byte(`{"type":"notification"}`))
+*/
+package ws
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+)
+
+var (
+	// ErrQueueFull is returned when a message cannot be queued because the
+	// client's send queue is full.
+	ErrQueueFull = errors.New("ws: send queue full")
+
+	// ErrClientClosed is returned when sending to a client that was closed.
+	ErrClientClosed = errors.New("ws: client closed")
+)
+
+// Config configures a Hub.
+type Config struct {
+	// QueueSize is the number of outgoing messages buffered per client
+	QueueSize *int
+
+	// WriteTimeout is how long writing a single message may take before the client
+	// is closed
+	WriteTimeout *time.Duration
+
+	// DropWhenFull drops messages for clients whose send queue is full instead of
+	// closing them with velocity.WSClosePolicyViolation
+	DropWhenFull *bool
+}
+
+var defaultQueueSize = 64
+var defaultWriteTimeout = 10 * time.Second
+var defaultDropWhenFull = false
+var defaultConfig = Config{
+	QueueSize:    &defaultQueueSize,
+	WriteTimeout: &defaultWriteTimeout,
+	DropWhenFull: &defaultDropWhenFull,
+}
+
+// Hub tracks registered clients by ID and room. It is safe for concurrent use.
+type Hub struct {
+	config Config
+
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	ids     map[string]map[*Client]struct{}
+	rooms   map[string]map[*Client]struct{}
+	closed  bool
+}
+
+// Client is a connection registered with a Hub.
+type Client struct {
+	hub   *Hub
+	conn  *velocity.WSConn
+	id    string
+	rooms map[string]struct{} // guarded by hub.mu
+
+	send chan message
+	done chan struct{}
+	once sync.Once
+
+	// Close status sent by the writer once done is closed
+	closeCode   int
+	closeReason string
+}
+
+type message struct {
+	messageType int
+	data        []byte
+}
+
+// NewHub returns an empty Hub.
+//
+// Example:
+//
+//	hub := ws.NewHub()
+//	// or with config
+//	hub := ws.NewHub(ws.Config{
+//	    QueueSize: intPtr(256),
+//	    DropWhenFull: boolPtr(true),
+//	})
+func NewHub(cfg ...Config) *Hub {
+	config := defaultConfig
+	if len(cfg) > 0 {
+		if cfg[0].QueueSize != nil {
+			config.QueueSize = cfg[0].QueueSize
+		}
+		if cfg[0].WriteTimeout != nil {
+			config.WriteTimeout = cfg[0].WriteTimeout
+		}
+		if cfg[0].DropWhenFull != nil {
+			config.DropWhenFull = cfg[0].DropWhenFull
+		}
+	}
+	return &Hub{
+		config:  config,
+		clients: make(map[*Client]struct{}),
+		ids:     make(map[string]map[*Client]struct{}),
+		rooms:   make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Register adds conn to the hub under id, which need not be unique, e.g. a user ID
+// shared by several tabs, and joins it to rooms. The client's writer runs until
+// the client is closed. Use Serve to also read from the connection.
+func (h *Hub) Register(conn *velocity.WSConn, id string, rooms ...string) *Client {
+	c := &Client{
+		hub:   h,
+		conn:  conn,
+		id:    id,
+		rooms: make(map[string]struct{}),
+		send:  make(chan message, *h.config.QueueSize),
+		done:  make(chan struct{}),
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		c.stop(velocity.WSCloseGoingAway, "server shutting down")
+		conn.CloseWithStatus(velocity.WSCloseGoingAway, "server shutting down")
+		return c
+	}
+	h.clients[c] = struct{}{}
+	addMember(h.ids, id, c)
+	for _, room := range rooms {
+		addMember(h.rooms, room, c)
+		c.rooms[room] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	go c.writeLoop()
+	return c
+}
+
+// Serve registers conn like Register and reads messages from it, passing each to
+// onMessage, until the connection fails or is closed. The client is closed when
+// Serve returns the read error, a *velocity.WSCloseError once the connection closed.
+//
+// Example:
+//
+//	router.Websocket("/ws").HandleWS(func(conn *velocity.WSConn) {
+//	    hub.Serve(conn, "", func(c *ws.Client, messageType int, data []byte) {
+//	        hub.Broadcast(messageType, data)
+//	    })
+//	})
+func (h *Hub) Serve(conn *velocity.WSConn, id string, onMessage func(c *Client, messageType int, data []byte), rooms ...string) error {
+	c := h.Register(conn, id, rooms...)
+	defer c.Close()
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if onMessage != nil {
+			onMessage(c, messageType, data)
+		}
+	}
+}
+
+// Broadcast queues a message for every client except the ones in except, returning
+// the number of clients it was queued for. data must not be modified afterwards.
+func (h *Hub) Broadcast(messageType int, data []byte, except ...*Client) int {
+	h.mu.RLock()
+	recipients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		recipients = append(recipients, c)
+	}
+	h.mu.RUnlock()
+	return deliver(recipients, message{messageType, data}, except)
+}
+
+// BroadcastRoom queues a message for every client in room except the ones in
+// except, returning the number of clients it was queued for.
+func (h *Hub) BroadcastRoom(room string, messageType int, data []byte, except ...*Client) int {
+	return h.sendMembers(h.rooms, room, message{messageType, data}, except)
+}
+
+// SendTo queues a message for every client registered under id, returning the
+// number of clients it was queued for.
+func (h *Hub) SendTo(id string, messageType int, data []byte) int {
+	return h.sendMembers(h.ids, id, message{messageType, data}, nil)
+}
+
+func (h *Hub) sendMembers(index map[string]map[*Client]struct{}, key string, m message, except []*Client) int {
+	h.mu.RLock()
+	members := index[key]
+	recipients := make([]*Client, 0, len(members))
+	for c := range members {
+		recipients = append(recipients, c)
+	}
+	h.mu.RUnlock()
+	return deliver(recipients, m, except)
+}
+
+// deliver queues m for recipients outside the hub lock, since full queues may
+// close clients, which unregisters them.
+func deliver(recipients []*Client, m message, except []*Client) int {
+	n := 0
+	for _, c := range recipients {
+		if slices.Contains(except, c) {
+			continue
+		}
+		if c.enqueue(m) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of registered clients.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// RoomLen returns the number of clients in room.
+func (h *Hub) RoomLen(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Close closes every client with velocity.WSCloseGoingAway. Clients registered
+// afterwards are closed immediately. Hijacked connections are not closed by the
+// server's Shutdown, so register Close with App.OnShutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+	for _, c := range clients {
+		c.stop(velocity.WSCloseGoingAway, "server shutting down")
+	}
+}
+
+func addMember(index map[string]map[*Client]struct{}, key string, c *Client) {
+	members := index[key]
+	if members == nil {
+		members = make(map[*Client]struct{})
+		index[key] = members
+	}
+	members[c] = struct{}{}
+}
+
+func removeMember(index map[string]map[*Client]struct{}, key string, c *Client) {
+	delete(index[key], c)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// ID returns the ID the client was registered under.
+func (c *Client) ID() string {
+	return c.id
+}
+
+// Conn returns the client's connection. Write through Send rather than the
+// connection, so messages are not interleaved with queued ones.
+func (c *Client) Conn() *velocity.WSConn {
+	return c.conn
+}
+
+// Join adds the client to room. It has no effect once the client is closed.
+func (c *Client) Join(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	addMember(h.rooms, room, c)
+	c.rooms[room] = struct{}{}
+}
+
+// Leave removes the client from room.
+func (c *Client) Leave(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := c.rooms[room]; !ok {
+		return
+	}
+	removeMember(h.rooms, room, c)
+	delete(c.rooms, room)
+}
+
+// Rooms returns the rooms the client is in, sorted.
+func (c *Client) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// Send queues a message for the client. If the queue is full it returns
+// ErrQueueFull and, unless the hub drops messages when full, closes the client.
+func (c *Client) Send(messageType int, data []byte) error {
+	return c.enqueue(message{messageType, data})
+}
+
+func (c *Client) enqueue(m message) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+	select {
+	case c.send <- m:
+		return nil
+	default:
+	}
+	// The client cannot keep up; dropping or disconnecting keeps memory bounded
+	if !*c.hub.config.DropWhenFull {
+		c.stop(velocity.WSClosePolicyViolation, "send queue full")
+	}
+	return ErrQueueFull
+}
+
+// writeLoop writes queued messages until the client is stopped, then closes the
+// connection. Only this goroutine writes, so stopping a client never waits for a
+// blocked write.
+func (c *Client) writeLoop() {
+	timeout := *c.hub.config.WriteTimeout
+	for {
+		// Stopping takes priority over queued messages
+		select {
+		case <-c.done:
+			c.conn.CloseWithStatus(c.closeCode, c.closeReason)
+			return
+		default:
+		}
+		select {
+		case m := <-c.send:
+			if timeout > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(timeout))
+			}
+			if err := c.conn.WriteMessage(m.messageType, m.data); err != nil {
+				// The connection failed, so no close frame can be sent
+				c.stop(velocity.WSCloseAbnormal, "")
+				c.conn.NetConn().Close()
+				return
+			}
+		case <-c.done:
+			c.conn.CloseWithStatus(c.closeCode, c.closeReason)
+			return
+		}
+	}
+}
+
+// Close unregisters the client and closes its connection with a normal closure.
+// Queued messages that were not written yet are discarded.
+func (c *Client) Close() {
+	c.stop(velocity.WSCloseNormal, "")
+}
+
+// stop unregisters the client and signals its writer to close the connection with
+// code and reason.
+func (c *Client) stop(code int, reason string) {
+	c.once.Do(func() {
+		c.closeCode, c.closeReason = code, reason
+		close(c.done)
+		h := c.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.clients[c]; !ok {
+			return
+		}
+		delete(h.clients, c)
+		removeMember(h.ids, c.id, c)
+		for room := range c.rooms {
+			removeMember(h.rooms, room, c)
+		}
+		clear(c.rooms)
+	})
+}
@@ -0,0 +1,203 @@
+package ws_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/ws"
+)
+
+type client struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dial connects a WebSocket client to path, e.g. "/chat?id=a".
+func dial(t *testing.T, srv *httptest.Server, path string) *client {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	conn, err := net.Dial("tcp", req.URL.Host)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Write(conn)
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil || res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake failed: %v %v", res, err)
+	}
+	return &client{conn: conn, br: br}
+}
+
+func (c *client) write(msg string) {
+	frame := []byte{0x81, 0x80 | byte(len(msg)), 0, 0, 0, 0}
+	c.conn.Write(append(frame, msg...))
+}
+
+// read returns the opcode and payload of the next frame, or -1 if none arrives
+// within timeout.
+func (c *client) read(timeout time.Duration) (int, string) {
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, hdr); err != nil {
+		return -1, ""
+	}
+	n := int(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(c.br, ext)
+		n = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(c.br, ext)
+		n = int(binary.BigEndian.Uint64(ext))
+	}
+	payload := make([]byte, n)
+	io.ReadFull(c.br, payload)
+	return int(hdr[0] & 0x0f), string(payload)
+}
+
+func newServer(t *testing.T, hub *ws.Hub) *httptest.Server {
+	t.Helper()
+	app := velocity.New()
+	router := app.Router("/")
+	router.Websocket("/chat").HandleWS(func(conn *velocity.WSConn) {
+		q := conn.Request().URL.Query()
+		hub.Serve(conn, q.Get("id"), func(c *ws.Client, messageType int, data []byte) {
+			if string(data) == "leave" {
+				c.Leave(q.Get("room"))
+				return
+			}
+			hub.BroadcastRoom(q.Get("room"), messageType, data, c)
+		}, q["room"]...)
+	})
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for range 100 {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestHub(t *testing.T) {
+	hub := ws.NewHub()
+	srv := newServer(t, hub)
+
+	alice := dial(t, srv, "/chat?id=alice&room=general")
+	bob := dial(t, srv, "/chat?id=bob&room=general")
+	bobTab := dial(t, srv, "/chat?id=bob&room=random")
+	waitFor(t, func() bool { return hub.Len() == 3 })
+
+	if n := hub.RoomLen("general"); n != 2 {
+		t.Errorf("expected 2 clients in general, got %d", n)
+	}
+
+	// Room broadcasts skip the sender and other rooms
+	alice.write("hi")
+	if op, msg := bob.read(time.Second); op != velocity.WSText || msg != "hi" {
+		t.Errorf("expected bob to receive hi, got %d %q", op, msg)
+	}
+	if op, _ := alice.read(50 * time.Millisecond); op != -1 {
+		t.Error("expected the sender to be excluded")
+	}
+	if op, _ := bobTab.read(50 * time.Millisecond); op != -1 {
+		t.Error("expected clients in other rooms not to receive the message")
+	}
+
+	// Targeted sends reach every connection of the ID
+	if n := hub.SendTo("bob", velocity.WSText, []byte("dm")); n != 2 {
+		t.Errorf("expected 2 recipients, got %d", n)
+	}
+	for _, c := range []*client{bob, bobTab} {
+		if _, msg := c.read(time.Second); msg != "dm" {
+			t.Errorf("expected dm, got %q", msg)
+		}
+	}
+
+	if n := hub.Broadcast(velocity.WSText, []byte("all")); n != 3 {
+		t.Errorf("expected 3 recipients, got %d", n)
+	}
+	for _, c := range []*client{alice, bob, bobTab} {
+		if _, msg := c.read(time.Second); msg != "all" {
+			t.Errorf("expected all, got %q", msg)
+		}
+	}
+
+	bob.write("leave")
+	waitFor(t, func() bool { return hub.RoomLen("general") == 1 })
+
+	// Disconnected clients are unregistered
+	alice.conn.Close()
+	waitFor(t, func() bool { return hub.Len() == 2 && hub.RoomLen("general") == 0 })
+
+	hub.Close()
+	for _, c := range []*client{bob, bobTab} {
+		if op, msg := c.read(time.Second); op != 8 || binary.BigEndian.Uint16([]byte(msg)) != velocity.WSCloseGoingAway {
+			t.Errorf("expected going away close, got %d %q", op, msg)
+		}
+	}
+	waitFor(t, func() bool { return hub.Len() == 0 })
+}
+
+func TestHubBackpressure(t *testing.T) {
+	queueSize := 1
+	tests := []struct {
+		name         string
+		dropWhenFull bool
+	}{
+		{"close slow client", false},
+		{"drop messages", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := ws.NewHub(ws.Config{QueueSize: &queueSize, DropWhenFull: &tt.dropWhenFull})
+			srv := newServer(t, hub)
+			dial(t, srv, "/chat?id=slow")
+			waitFor(t, func() bool { return hub.Len() == 1 })
+
+			// The client never reads, so the writer eventually blocks and the queue fills
+			payload := bytes.Repeat([]byte("x"), 1<<20)
+			full := false
+			for range 1000 {
+				if hub.SendTo("slow", velocity.WSBinary, payload) == 0 {
+					full = true
+					break
+				}
+			}
+			if !full {
+				t.Fatal("expected the queue to fill up")
+			}
+
+			if tt.dropWhenFull {
+				if hub.Len() != 1 {
+					t.Error("expected the client to stay registered")
+				}
+				return
+			}
+			waitFor(t, func() bool { return hub.Len() == 0 })
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Package nhooyrws adapts nhooyr.io/websocket to velocity WebSocket routes, so
+existing code built on *websocket.Conn can run behind velocity routing and
+middleware. The route's middleware runs before the upgrade, so it can reject the
+request, and headers it sets are sent with the handshake response.
+
+Usage:
+
+	router.Websocket("/chat", authMiddleware).Handle(nhooyrws.Handler(&websocket.AcceptOptions{
+	    OriginPatterns: []string{"app.example.com"},
+	}, func(conn *websocket.Conn, r *http.Request) {
+	    for {
+	        typ, msg, err := conn.Read(r.Context())
+	        if err != nil {
+	            return
+	        }
+	        conn.Write(r.Context(), typ, msg)
+	    }
+	}))
+*/
+package nhooyrws
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"nhooyr.io/websocket"
+)
+
+// Handler returns a handler that accepts the WebSocket handshake with opts, which
+// may be nil, and passes the connection to h. Failed handshakes are answered by
+// websocket.Accept. The connection is closed with a normal closure when h returns,
+// unless h closed it already.
+func Handler(opts *websocket.AcceptOptions, h func(conn *websocket.Conn, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(hijacker{w}, r, opts)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusNormalClosure, "")
+		h(conn, r)
+	}
+}
+
+// hijacker lets websocket.Accept hijack connections through response writers
+// wrapped by middleware, which it only finds with a type assertion.
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
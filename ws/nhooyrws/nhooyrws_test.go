@@ -0,0 +1,71 @@
+package nhooyrws_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Juanfec4/velocity"
+	"github.com/Juanfec4/velocity/ws/nhooyrws"
+	"nhooyr.io/websocket"
+)
+
+// wrapped hides the writer's Hijack method, like many middleware writers do.
+type wrapped struct {
+	http.ResponseWriter
+}
+
+func (w wrapped) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Request-ID", "abc")
+		next(wrapped{w}, r)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	app := velocity.New()
+	app.Router("/").Websocket("/echo", auth).Handle(nhooyrws.Handler(nil, func(conn *websocket.Conn, r *http.Request) {
+		for {
+			typ, msg, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			conn.Write(r.Context(), typ, msg)
+		}
+	}))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, res, err := websocket.Dial(ctx, srv.URL+"/echo", nil)
+	if err == nil || res == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected middleware to reject the upgrade, got %v", err)
+	}
+
+	conn, res, err := websocket.Dial(ctx, srv.URL+"/echo?token=secret", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	if got := res.Header.Get("X-Request-ID"); got != "abc" {
+		t.Errorf("expected middleware headers in the handshake, got %q", got)
+	}
+	if err := conn.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typ, msg, err := conn.Read(ctx); err != nil || typ != websocket.MessageText || string(msg) != "hello" {
+		t.Errorf("expected echo, got %v %q %v", typ, msg, err)
+	}
+}